@@ -29,8 +29,15 @@ func Index(nodes []*yaml.RNode, apps map[yaml.NameMeta]*Node) ([]*yaml.RNode, er
 			return nil, err
 		}
 
-		// Leave non-Application nodes alone
-		if md.APIVersion != "app.k8s.io/v1beta1" || md.Kind != "Application" {
+		// Convert application definitions from other tools, leave other non-Application nodes alone
+		if origin := originOf(md.TypeMeta); origin != "" {
+			if node, err = fromOrigin(origin, node); err != nil {
+				return nil, err
+			}
+			if md, err = node.GetMeta(); err != nil {
+				return nil, err
+			}
+		} else if md.APIVersion != "app.k8s.io/v1beta1" || md.Kind != "Application" {
 			nodes[i] = node
 			i++
 			continue
@@ -42,6 +49,10 @@ func Index(nodes []*yaml.RNode, apps map[yaml.NameMeta]*Node) ([]*yaml.RNode, er
 			app = &Node{namespace: md.Namespace}
 			apps[md.NameMeta] = app
 		}
+		if origin := md.Annotations[AnnotationOrigin]; origin != "" {
+			app.origin = origin
+			app.originName = parseNameMeta(md.Annotations[AnnotationOriginName])
+		}
 
 		// Update the application with the new node information
 		app.Node, err = node.Pipe(
@@ -80,6 +91,8 @@ type Node struct {
 	namespace      string
 	componentKinds []GroupKind
 	selector       string
+	origin         string
+	originName     yaml.NameMeta
 }
 
 // Filter removes all the application resources from the supplied collection.
@@ -98,12 +111,21 @@ func (app *Node) Filter(nodes []*yaml.RNode) ([]*yaml.RNode, error) {
 
 		if !owns {
 			result = append(result, node)
+		} else if app.origin != "" {
+			if err := app.addComponentKind(md.TypeMeta); err != nil {
+				return nil, err
+			}
 		}
 	}
 	return result, nil
 }
 
 func (app *Node) owns(md yaml.ResourceMeta, node *yaml.RNode) (bool, error) {
+	// Applications created from other tools use their tracking labels and annotations
+	if app.origin != "" {
+		return app.tracks(md), nil
+	}
+
 	// The node must be in the same namespace as the application
 	if md.Namespace != app.namespace {
 		return false, nil
@@ -128,3 +150,21 @@ func (app *Node) owns(md yaml.ResourceMeta, node *yaml.RNode) (bool, error) {
 
 	return true, nil
 }
+
+// addComponentKind adds the type to the application component kinds if it is not already present.
+func (app *Node) addComponentKind(t yaml.TypeMeta) error {
+	for i := range app.componentKinds {
+		if app.componentKinds[i].Matches(t) {
+			return nil
+		}
+	}
+
+	gk := GroupKind{Group: StripVersion(t.APIVersion), Kind: t.Kind}
+	app.componentKinds = append(app.componentKinds, gk)
+	return app.Node.PipeE(
+		yaml.LookupCreate(yaml.SequenceNode, "spec", "componentKinds"),
+		yaml.Append(&yaml.Node{Kind: yaml.MappingNode}),
+		yaml.Tee(yaml.SetField("group", yaml.NewStringRNode(gk.Group))),
+		yaml.Tee(yaml.SetField("kind", yaml.NewStringRNode(gk.Kind))),
+	)
+}
@@ -0,0 +1,236 @@
+package application
+
+import (
+	"cmp"
+	"strings"
+
+	"sigs.k8s.io/kustomize/kyaml/yaml"
+)
+
+const (
+	// AnnotationOrigin is the annotation recording the type of the application
+	// definition an application was created from. For example, `Application.argoproj.io`.
+	AnnotationOrigin = "konjure.stormforge.io/origin"
+	// AnnotationOriginName is the annotation recording the namespace and name of
+	// the application definition an application was created from. For example, `argocd/guestbook`.
+	AnnotationOriginName = "konjure.stormforge.io/origin-name"
+)
+
+// The types of application definitions that can be converted to applications.
+const (
+	OriginArgoCDApplication = "Application.argoproj.io"
+	OriginFluxKustomization = "Kustomization.kustomize.toolkit.fluxcd.io"
+	OriginFluxHelmRelease   = "HelmRelease.helm.toolkit.fluxcd.io"
+	OriginHelmRelease       = "Release.helm.sh"
+)
+
+// See: https://argo-cd.readthedocs.io/en/stable/user-guide/resource_tracking/
+// See: https://fluxcd.io/flux/faq/#how-do-i-find-which-flux-object-manages-a-resource
+// See: https://helm.sh/docs/topics/kubernetes_apis/
+const (
+	// AnnotationArgoCDTrackingID is the annotation used by Argo CD to track
+	// resources. For example, `guestbook:apps/Deployment:default/guestbook-ui`.
+	AnnotationArgoCDTrackingID = "argocd.argoproj.io/tracking-id"
+	// LabelFluxKustomizeName is the label used by Flux to track the name of
+	// the Kustomization which applied a resource.
+	LabelFluxKustomizeName = "kustomize.toolkit.fluxcd.io/name"
+	// LabelFluxKustomizeNamespace is the label used by Flux to track the
+	// namespace of the Kustomization which applied a resource.
+	LabelFluxKustomizeNamespace = "kustomize.toolkit.fluxcd.io/namespace"
+	// LabelFluxHelmName is the label used by Flux to track the name of the
+	// HelmRelease which installed a resource.
+	LabelFluxHelmName = "helm.toolkit.fluxcd.io/name"
+	// LabelFluxHelmNamespace is the label used by Flux to track the namespace
+	// of the HelmRelease which installed a resource.
+	LabelFluxHelmNamespace = "helm.toolkit.fluxcd.io/namespace"
+	// AnnotationHelmReleaseName is the annotation used by Helm to track the
+	// name of the release which installed a resource.
+	AnnotationHelmReleaseName = "meta.helm.sh/release-name"
+	// AnnotationHelmReleaseNamespace is the annotation used by Helm to track
+	// the namespace of the release which installed a resource.
+	AnnotationHelmReleaseNamespace = "meta.helm.sh/release-namespace"
+)
+
+// originOf returns the origin of an application definition, or an empty string
+// if the resource is not an application definition we recognize. Argo CD
+// ApplicationSets are not converted: the Applications they generate are, and
+// those are labeled as part of the ApplicationSet.
+func originOf(t yaml.TypeMeta) string {
+	gk := GroupKind{Group: StripVersion(t.APIVersion), Kind: t.Kind}
+	switch origin := gk.String(); origin {
+	case OriginArgoCDApplication, OriginFluxKustomization, OriginFluxHelmRelease:
+		return origin
+	default:
+		return ""
+	}
+}
+
+// fromOrigin converts an Argo CD or Flux application definition into an application.
+func fromOrigin(origin string, node *yaml.RNode) (*yaml.RNode, error) {
+	md, err := node.GetMeta()
+	if err != nil {
+		return nil, err
+	}
+
+	// Applications are placed in the namespace their resources are deployed to
+	namespace := md.Namespace
+	var appType, version, partOf string
+	switch origin {
+	case OriginArgoCDApplication:
+		namespace = cmp.Or(lookupString(node, "spec", "destination", "namespace"), namespace)
+		appType = lookupString(node, "spec", "source", "chart")
+		if appType != "" {
+			version = lookupString(node, "spec", "source", "targetRevision")
+		}
+
+		// Applications generated by an ApplicationSet are part of it
+		if err := node.PipeE(
+			yaml.Lookup(yaml.MetadataField, "ownerReferences", "[kind=ApplicationSet]"),
+			yaml.FilterFunc(func(object *yaml.RNode) (*yaml.RNode, error) {
+				partOf = lookupString(object, "name")
+				return object, nil
+			}),
+		); err != nil {
+			return nil, err
+		}
+
+	case OriginFluxKustomization:
+		namespace = cmp.Or(lookupString(node, "spec", "targetNamespace"), namespace)
+
+	case OriginFluxHelmRelease:
+		namespace = cmp.Or(lookupString(node, "spec", "targetNamespace"), namespace)
+		appType = lookupString(node, "spec", "chart", "spec", "chart")
+		version = lookupString(node, "spec", "chart", "spec", "version")
+	}
+
+	pp := newApplication(md.Name, namespace, origin, md.NameMeta)
+	if partOf != "" {
+		pp = append(pp, yaml.Tee(yaml.SetLabel(LabelPartOf, partOf)))
+	}
+
+	// Flux tracking labels can also be expressed as a selector
+	switch origin {
+	case OriginFluxKustomization:
+		pp = append(pp, setMatchLabels(LabelFluxKustomizeName, md.Name, LabelFluxKustomizeNamespace, md.Namespace))
+	case OriginFluxHelmRelease:
+		pp = append(pp, setMatchLabels(LabelFluxHelmName, md.Name, LabelFluxHelmNamespace, md.Namespace))
+	}
+
+	if appType != "" {
+		pp = append(pp, yaml.Tee(
+			yaml.LookupCreate(yaml.ScalarNode, "spec", "descriptor", "type"),
+			yaml.Tee(yaml.Set(yaml.NewStringRNode(appType))),
+		))
+	}
+	if version != "" {
+		pp = append(pp, yaml.Tee(
+			yaml.LookupCreate(yaml.ScalarNode, "spec", "descriptor", "version"),
+			yaml.Tee(yaml.Set(yaml.NewStringRNode(version))),
+		))
+	}
+
+	return newDocument().Pipe(pp...)
+}
+
+// FromHelmRelease creates an application for the Helm release which installed
+// the supplied resource. If the resource was not installed by Helm (or the
+// release is managed by a Flux HelmRelease), this function will just return nil.
+func FromHelmRelease(node *yaml.RNode) (*yaml.RNode, error) {
+	md, err := node.GetMeta()
+	if err != nil {
+		return nil, err
+	}
+
+	name := md.Annotations[AnnotationHelmReleaseName]
+	if name == "" || md.Labels[LabelFluxHelmName] != "" {
+		return nil, nil
+	}
+
+	namespace := cmp.Or(md.Annotations[AnnotationHelmReleaseNamespace], md.Namespace)
+	pp := newApplication(name, namespace, OriginHelmRelease, yaml.NameMeta{Name: name, Namespace: namespace})
+	pp = append(pp, yaml.Tee(yaml.SetLabel(LabelManagedBy, "Helm")))
+	return newDocument().Pipe(pp...)
+}
+
+// tracks checks to see if the resource is tracked by the application definition
+// the application was created from.
+func (app *Node) tracks(md yaml.ResourceMeta) bool {
+	name, namespace := app.originName.Name, app.originName.Namespace
+	switch app.origin {
+	case OriginArgoCDApplication:
+		if id, ok := md.Annotations[AnnotationArgoCDTrackingID]; ok {
+			// Applications outside the control plane namespace are tracked as "namespace_name"
+			appName, _, _ := strings.Cut(id, ":")
+			return appName == name || appName == namespace+"_"+name
+		}
+		return md.Labels[LabelInstance] == name
+	case OriginFluxKustomization:
+		return md.Labels[LabelFluxKustomizeName] == name && md.Labels[LabelFluxKustomizeNamespace] == namespace
+	case OriginFluxHelmRelease:
+		return md.Labels[LabelFluxHelmName] == name && md.Labels[LabelFluxHelmNamespace] == namespace
+	case OriginHelmRelease:
+		return md.Annotations[AnnotationHelmReleaseName] == name &&
+			cmp.Or(md.Annotations[AnnotationHelmReleaseNamespace], md.Namespace) == namespace
+	default:
+		return false
+	}
+}
+
+// newApplication returns the filters for creating an application with the recorded origin.
+func newApplication(name, namespace, origin string, originName yaml.NameMeta) []yaml.Filter {
+	pp := []yaml.Filter{
+		yaml.Tee(yaml.SetField(yaml.APIVersionField, yaml.NewStringRNode("app.k8s.io/v1beta1"))),
+		yaml.Tee(yaml.SetField(yaml.KindField, yaml.NewStringRNode("Application"))),
+		yaml.Tee(yaml.SetK8sName(name)),
+	}
+	if namespace != "" {
+		pp = append(pp, yaml.Tee(yaml.SetK8sNamespace(namespace)))
+	}
+	return append(pp,
+		yaml.Tee(yaml.SetLabel(LabelName, name)),
+		yaml.Tee(yaml.SetAnnotation(AnnotationOrigin, origin)),
+		yaml.Tee(yaml.SetAnnotation(AnnotationOriginName, formatNameMeta(originName))),
+	)
+}
+
+// setMatchLabels returns a filter which adds the supplied key/value pairs to the application selector.
+func setMatchLabels(kv ...string) yaml.Filter {
+	pp := []yaml.Filter{yaml.LookupCreate(yaml.MappingNode, "spec", "selector", "matchLabels")}
+	for i := 0; i+1 < len(kv); i += 2 {
+		pp = append(pp, yaml.Tee(yaml.SetField(kv[i], yaml.NewStringRNode(kv[i+1]))))
+	}
+	return yaml.Tee(pp...)
+}
+
+// newDocument returns an empty document to build an application in.
+func newDocument() *yaml.RNode {
+	return yaml.NewRNode(&yaml.Node{
+		Kind:    yaml.DocumentNode,
+		Content: []*yaml.Node{{Kind: yaml.MappingNode}},
+	})
+}
+
+// lookupString returns the string value at the supplied path, or an empty string.
+func lookupString(node *yaml.RNode, path ...string) string {
+	value, err := node.Pipe(yaml.Lookup(path...))
+	if err != nil {
+		return ""
+	}
+	return yaml.GetValue(value)
+}
+
+// formatNameMeta returns the "namespace/name" representation of the name.
+func formatNameMeta(nm yaml.NameMeta) string {
+	if nm.Namespace == "" {
+		return nm.Name
+	}
+	return nm.Namespace + "/" + nm.Name
+}
+
+// parseNameMeta parses the "namespace/name" representation of a name.
+func parseNameMeta(s string) yaml.NameMeta {
+	if namespace, name, ok := strings.Cut(s, "/"); ok {
+		return yaml.NameMeta{Namespace: namespace, Name: name}
+	}
+	return yaml.NameMeta{Name: s}
+}
@@ -17,19 +17,25 @@ limitations under the License.
 package command
 
 import (
+	"fmt"
 	"os"
+	"strings"
 
 	"github.com/spf13/cobra"
+	"github.com/thestormforge/konjure/pkg/filters"
 	"github.com/thestormforge/konjure/pkg/konjure"
 	"sigs.k8s.io/kustomize/kyaml/kio"
-	"sigs.k8s.io/kustomize/kyaml/kio/filters"
+	kiofilters "sigs.k8s.io/kustomize/kyaml/kio/filters"
 	"sigs.k8s.io/kustomize/kyaml/kio/kioutil"
+	"sigs.k8s.io/kustomize/kyaml/yaml"
 )
 
 func NewRootCommand(version, refspec, date string) *cobra.Command {
 	r := konjure.Resources{}
 	f := &konjure.Filter{}
 	w := &konjure.Writer{}
+	m := matchFlags{}
+	var images, patches, podSpecPaths []string
 
 	cmd := &cobra.Command{
 		Use:              "konjure INPUT...",
@@ -47,6 +53,7 @@ func NewRootCommand(version, refspec, date string) *cobra.Command {
 		PreRunE: func(cmd *cobra.Command, args []string) (err error) {
 			w.Writer = cmd.OutOrStdout()
 			f.DefaultReader = cmd.InOrStdin()
+			f.ImageFilter.Warnings = cmd.ErrOrStderr()
 
 			if len(args) > 0 {
 				r = append(r, konjure.NewResource(args...))
@@ -54,13 +61,54 @@ func NewRootCommand(version, refspec, date string) *cobra.Command {
 				r = append(r, konjure.NewResource("-"))
 			}
 
+			if f.MatchFilter, err = m.matchFilter(); err != nil {
+				return err
+			}
+
+			for _, p := range podSpecPaths {
+				path, err := filters.FieldPath(p, nil)
+				if err != nil {
+					return fmt.Errorf("invalid pod spec path %q: %w", p, err)
+				}
+				f.WorkloadFilter.PodSpecPaths = append(f.WorkloadFilter.PodSpecPaths, path)
+			}
+
+			// Use the match filter to select workloads so their dependencies are not filtered out
+			if f.WorkloadFilter.IncludeRelated {
+				f.WorkloadFilter.Selector = &filters.MatchFilter{}
+				*f.WorkloadFilter.Selector, f.MatchFilter = f.MatchFilter, filters.MatchFilter{}
+			}
+
+			for _, spec := range images {
+				img, err := filters.ParseImageOverride(spec)
+				if err != nil {
+					return err
+				}
+				f.ImageFilter.Images = append(f.ImageFilter.Images, img)
+			}
+
+			for _, patch := range patches {
+				data := []byte(patch)
+				if name, ok := strings.CutPrefix(patch, "@"); ok {
+					if data, err = os.ReadFile(name); err != nil {
+						return err
+					}
+				}
+				p, err := filters.ParsePatch(data)
+				if err != nil {
+					return fmt.Errorf("invalid patch %q: %w", patch, err)
+				}
+				f.Patches = append(f.Patches, *p)
+			}
+
 			f.WorkingDirectory, err = os.Getwd()
 
 			if !w.KeepReaderAnnotations {
 				w.ClearAnnotations = append(w.ClearAnnotations,
 					kioutil.PathAnnotation,
 					kioutil.LegacyPathAnnotation,
-					filters.FmtAnnotation,
+					kiofilters.FmtAnnotation,
+					filters.LiveAnnotation,
 				)
 			}
 
@@ -77,8 +125,26 @@ func NewRootCommand(version, refspec, date string) *cobra.Command {
 	}
 
 	cmd.Flags().IntVarP(&f.Depth, "depth", "d", 100, "limit the number of times expansion can happen")
-	cmd.Flags().StringVarP(&f.LabelSelector, "selector", "l", "", "label query to filter on")
-	cmd.Flags().StringVar(&f.Kind, "kind", "", "keep only resource matching the specified kind")
+	cmd.Flags().StringVarP(&m.LabelSelector, "selector", "l", "", "label query to filter on")
+	cmd.Flags().StringVar(&m.AnnotationSelector, "annotation-selector", "", "annotation query to filter on")
+	cmd.Flags().StringArrayVar(&m.kinds, "kind", nil, "keep only resources matching the specified `kind` (may be repeated)")
+	cmd.Flags().StringVar(&m.Group, "group", "", "keep only resources matching the specified API `group`")
+	cmd.Flags().StringVar(&m.Name, "name", "", "keep only resources matching the specified `name`")
+	cmd.Flags().StringVar(&m.Namespace, "namespace", "", "keep only resources matching the specified `namespace`")
+	cmd.Flags().StringVar(&m.filterFile, "filter-file", "", "keep only resources matching the filter in `file`")
+	cmd.Flags().StringVar(&m.Expression, "where", "", "keep only resources matching the CEL `expression` (e.g. 'object.spec.replicas > 1')")
+	cmd.Flags().StringVar(&f.NamespaceFilter.Namespace, "set-namespace", "", "set the `namespace` of namespace scoped resources")
+	cmd.Flags().BoolVar(&f.NamespaceFilter.CreateNamespaces, "create-namespaces", false, "create namespace resources for every namespace used")
+	cmd.Flags().StringToStringVar(&f.CommonMetadataFilter.Labels, "label", nil, "add the `key=value` label to all resources")
+	cmd.Flags().StringToStringVar(&f.CommonMetadataFilter.Annotations, "annotation", nil, "add the `key=value` annotation to all resources")
+	cmd.Flags().BoolVar(&f.CommonMetadataFilter.IncludeTemplates, "label-templates", false, "also add labels and annotations to pod and volume claim templates")
+	cmd.Flags().BoolVar(&f.CommonMetadataFilter.IncludeSelectors, "label-selectors", false, "also add labels to templates and selectors")
+	cmd.Flags().BoolVar(&f.CommonMetadataFilter.ProtectImmutableSelectors, "protect-immutable-selectors", false, "do not alter immutable selectors of resources read from a cluster")
+	cmd.Flags().StringArrayVar(&patches, "patch", nil, "apply the strategic merge or JSON `patch` (or @file) to matching resources")
+	cmd.Flags().StringArrayVar(&images, "image", nil, "override container images using `name=registry/repo:tag@digest`")
+	cmd.Flags().StringVar(&f.NameFilter.Prefix, "name-prefix", "", "add the `prefix` to all resource names")
+	cmd.Flags().StringVar(&f.NameFilter.Suffix, "name-suffix", "", "add the `suffix` to all resource names")
+	cmd.Flags().BoolVar(&f.HashSuffixFilter.Enabled, "hash-suffix", true, "append content hashes to the names of generated secrets and config maps")
 	cmd.Flags().BoolVar(&f.KeepStatus, "keep-status", false, "retain status fields, if present")
 	cmd.Flags().BoolVar(&f.KeepComments, "keep-comments", true, "retain YAML comments")
 	cmd.Flags().BoolVar(&f.ResetStyle, "reset-style", false, "reset YAML style")
@@ -88,18 +154,25 @@ func NewRootCommand(version, refspec, date string) *cobra.Command {
 	cmd.Flags().StringVar(&f.Kubeconfig, "kubeconfig", "", "path to the kubeconfig file")
 	cmd.Flags().StringVarP(&w.Format, "output", "o", "yaml", "set the output format (yaml, json, ndjson, env, name, columns=, csv=, template=)")
 	cmd.Flags().BoolVar(&w.KeepReaderAnnotations, "keep-annotations", false, "retain annotations used for processing")
+	cmd.Flags().StringSliceVar(&w.EncryptSecrets, "encrypt-secrets", nil, "encrypt secret data using SOPS for the age `recipient`")
+	cmd.Flags().StringVar(&w.SealSecretsCertificate, "seal-secrets", "", "convert secrets to sealed secrets using the controller certificate `file`")
+	cmd.Flags().StringVar(&w.SealSecretsScope, "seal-scope", "", "`scope` of sealed secrets (strict, namespace-wide, cluster-wide)")
 	cmd.Flags().BoolVar(&f.Sort, "sort", false, "sort output prior to writing")
 	cmd.Flags().BoolVar(&f.Reverse, "reverse", false, "reverse sort output prior to writing")
-	cmd.Flags().StringSliceVar(&f.DoNotExpand, "do-not-expand", nil, "do not expand Konjure kinds (Resource, Helm, Jsonnet, Kubernetes, Kustomize, Secret, Git, HTTP, File)")
+	cmd.Flags().StringSliceVar(&f.DoNotExpand, "do-not-expand", nil, "do not expand Konjure kinds (Resource, Helm, Jsonnet, Kubernetes, Kustomize, Secret, ConfigMap, Git, HTTP, File, Overlay, Replacement)")
 	cmd.Flags().BoolVar(&f.ApplicationFilter.Enabled, "apps", false, "transform output to application definitions")
 	cmd.Flags().StringSliceVar(&f.ApplicationFilter.ApplicationNameLabels, "application-name-label", nil, "label to use for application names")
 	cmd.Flags().BoolVar(&f.WorkloadFilter.Enabled, "workloads", false, "keep only workload resources")
+	cmd.Flags().BoolVar(&f.WorkloadFilter.IncludeRelated, "workload-dependencies", false, "also keep the resources workloads depend on (implies --workloads)")
+	cmd.Flags().StringArrayVar(&podSpecPaths, "workload-pod-spec-path", nil, "recognize custom workloads with a pod spec at the slash separated `path` (e.g. spec/workers/podSpec)")
 
 	_ = cmd.Flags().MarkHidden("do-not-expand")
 
 	_ = cmd.Flags().MarkHidden("apps")                   // TODO This is "early access"
 	_ = cmd.Flags().MarkHidden("application-name-label") // TODO This is "early access"
 	_ = cmd.Flags().MarkHidden("workloads")              // TODO This is "early access"
+	_ = cmd.Flags().MarkHidden("workload-dependencies")  // TODO This is "early access"
+	_ = cmd.Flags().MarkHidden("workload-pod-spec-path") // TODO This is "early access"
 	_ = cmd.Flags().MarkHidden("vws")                    // TODO This is "early access" / "somewhat unstable"
 
 	cmd.AddCommand(
@@ -107,7 +180,44 @@ func NewRootCommand(version, refspec, date string) *cobra.Command {
 		NewHelmValuesCommand(),
 		NewJsonnetCommand(),
 		NewSecretCommand(),
+		NewConfigMapCommand(),
 	)
 
 	return cmd
 }
+
+type matchFlags struct {
+	filters.ResourceMetaFilter
+	kinds      []string
+	filterFile string
+}
+
+// matchFilter builds a validated match filter from the flag values.
+func (m *matchFlags) matchFilter() (filters.MatchFilter, error) {
+	mf := filters.MatchFilter{ResourceMetaFilter: m.ResourceMetaFilter}
+
+	switch len(m.kinds) {
+	case 0:
+	case 1:
+		mf.Kind = m.kinds[0]
+	default:
+		for _, kind := range m.kinds {
+			mf.AnyOf = append(mf.AnyOf, filters.MatchFilter{ResourceMetaFilter: filters.ResourceMetaFilter{Kind: kind}})
+		}
+	}
+
+	if m.filterFile != "" {
+		data, err := os.ReadFile(m.filterFile)
+		if err != nil {
+			return mf, err
+		}
+
+		ff := filters.MatchFilter{}
+		if err := yaml.Unmarshal(data, &ff); err != nil {
+			return mf, fmt.Errorf("invalid filter file %s: %w", m.filterFile, err)
+		}
+		mf.AllOf = append(mf.AllOf, ff)
+	}
+
+	return mf, mf.Validate()
+}
@@ -18,12 +18,15 @@ package command
 
 import (
 	"fmt"
+	"maps"
+	"slices"
 	"strconv"
 	"strings"
 
 	"github.com/spf13/cobra"
 	"github.com/thestormforge/konjure/internal/readers"
 	konjurev1beta2 "github.com/thestormforge/konjure/pkg/api/core/v1beta2"
+	"github.com/thestormforge/konjure/pkg/filters"
 	"github.com/thestormforge/konjure/pkg/konjure"
 	"sigs.k8s.io/kustomize/kyaml/kio"
 )
@@ -32,66 +35,169 @@ func NewSecretCommand() *cobra.Command {
 	f := secretFlags{}
 
 	cmd := &cobra.Command{
-		Use:    "secret",
-		Short:  "Generate secrets",
-		PreRun: f.preRun,
+		Use:     "secret",
+		Short:   "Generate secrets",
+		PreRunE: f.preRun,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return kio.Pipeline{
 				Inputs:  []kio.Reader{&readers.SecretReader{Secret: f.Secret}},
-				Outputs: []kio.Writer{&konjure.Writer{Writer: cmd.OutOrStdout()}},
+				Filters: []kio.Filter{&filters.HashSuffixFilter{Enabled: true}},
+				Outputs: []kio.Writer{&konjure.Writer{
+					Writer:                 cmd.OutOrStdout(),
+					EncryptSecrets:         f.encryptSecrets,
+					SealSecretsCertificate: f.sealSecretsCertificate,
+					SealSecretsScope:       f.sealSecretsScope,
+				}},
 			}.Execute()
 		},
 	}
 
 	cmd.Flags().StringVar(&f.SecretName, "name", "", "`name` of the secret to generate")
+	cmd.Flags().StringVarP(&f.Namespace, "namespace", "n", "", "`namespace` of the secret to generate")
+	cmd.Flags().BoolVar(&f.AppendHash, "append-hash", false, "append a hash of the secret contents to its name")
+	cmd.Flags().StringVar(&f.SeedFile, "seed-file", "", "derive random values from the seed in `file`")
+	cmd.Flags().StringVar(&f.SeedEnv, "seed-env", "", "derive random values from the seed in the environment `variable`")
+	cmd.Flags().BoolVar(&f.PreserveExisting, "preserve-existing", false, "preserve generated values from the existing secret in the cluster")
 	cmd.Flags().StringToStringVar(&f.literals, "literal", nil, "literal `name=value` pair")
 	cmd.Flags().StringArrayVar(&f.FileSources, "file", nil, "file `path` to include")
 	cmd.Flags().StringArrayVar(&f.EnvSources, "env", nil, "env `file` to read")
 	cmd.Flags().StringArrayVar(&f.UUIDSources, "uuid", nil, "UUID `key` to generate")
 	cmd.Flags().StringArrayVar(&f.ULIDSources, "ulid", nil, "ULID `key` to generate")
-	cmd.Flags().StringToStringVar(&f.passwords, "password", nil, "password `spec` to generate, e.g. 'mypassword=length:5,numDigits:2'")
+	cmd.Flags().StringToStringVar(&f.passwords, "password", nil, "password `spec` to generate, e.g. 'mypassword=length:5,numDigits:2', 'phrase=words:6' or 'pwd=excludeAmbiguous:true,bcrypt:pwd-hash'")
+	cmd.Flags().StringVar(&f.tls.CommonName, "tls", "", "generate a TLS certificate and private key for the `common-name`")
+	cmd.Flags().StringArrayVar(&f.tls.Hosts, "tls-host", nil, "additional DNS name or IP `address` for the TLS certificate")
+	cmd.Flags().StringVar(&f.tls.ValidFor, "tls-valid-for", "", "`duration` the TLS certificate is valid for")
+	cmd.Flags().StringVar(&f.tls.KeyAlgorithm, "tls-key-algorithm", "", "TLS private key `algorithm` (ecdsa, rsa, ed25519)")
+	cmd.Flags().StringVar(&f.sshKey.KeyAlgorithm, "ssh-key", "", "generate an SSH key pair using the `algorithm` (ed25519, rsa, ecdsa)")
+	cmd.Flags().StringVar(&f.sshKey.Comment, "ssh-key-comment", "", "`comment` for the SSH key pair")
+	cmd.Flags().StringVar(&f.docker.Server, "docker-server", "", "container registry `server` location")
+	cmd.Flags().StringVar(&f.docker.Username, "docker-username", "", "container registry `username`")
+	cmd.Flags().StringVar(&f.docker.Password, "docker-password", "", "container registry `password`")
+	cmd.Flags().StringVar(&f.docker.Email, "docker-email", "", "container registry `email` address")
+	cmd.Flags().StringSliceVar(&f.encryptSecrets, "encrypt-secrets", nil, "encrypt secret data using SOPS for the age `recipient`")
+	cmd.Flags().StringVar(&f.sealSecretsCertificate, "seal-secrets", "", "convert secrets to sealed secrets using the controller certificate `file`")
+	cmd.Flags().StringVar(&f.sealSecretsScope, "seal-scope", "", "`scope` of sealed secrets (strict, namespace-wide, cluster-wide)")
+	cmd.Flags().StringToStringVar(&f.htpasswd, "htpasswd", nil, "htpasswd `username=password` entry to include under the 'auth' key")
+
+	cmd.Flags().Lookup("ssh-key").NoOptDefVal = "ed25519"
 
 	return cmd
 }
 
 type secretFlags struct {
 	konjurev1beta2.Secret
-	literals  map[string]string
-	passwords map[string]string
+	encryptSecrets         []string
+	sealSecretsCertificate string
+	sealSecretsScope       string
+	literals               map[string]string
+	passwords              map[string]string
+	tls                    konjurev1beta2.TLSRecipe
+	sshKey                 konjurev1beta2.SSHKeyRecipe
+	docker                 konjurev1beta2.DockerRegistryRecipe
+	htpasswd               map[string]string
 }
 
-func (f *secretFlags) preRun(*cobra.Command, []string) {
+func (f *secretFlags) preRun(cmd *cobra.Command, _ []string) error {
+	if f.tls.CommonName != "" {
+		f.TLSSource = &f.tls
+	}
+
+	if cmd.Flags().Changed("ssh-key") {
+		f.SSHKeySource = &f.sshKey
+	}
+
+	if f.docker.Username != "" {
+		f.DockerRegistrySources = append(f.DockerRegistrySources, f.docker)
+	} else {
+		for _, name := range []string{"docker-server", "docker-password", "docker-email"} {
+			if cmd.Flags().Changed(name) {
+				return fmt.Errorf("--%s requires --docker-username", name)
+			}
+		}
+	}
+
+	for _, k := range slices.Sorted(maps.Keys(f.htpasswd)) {
+		f.HTPasswdSources = append(f.HTPasswdSources, konjurev1beta2.HTPasswdRecipe{Username: k, Password: f.htpasswd[k]})
+	}
+
 	for k, v := range f.literals {
 		f.LiteralSources = append(f.LiteralSources, fmt.Sprintf("%s=%s", k, v))
 	}
 
-	for k, v := range f.passwords {
-		r := konjurev1beta2.PasswordRecipe{Key: k}
-		for _, s := range strings.Split(v, ",") {
-			p := strings.SplitN(s, ":", 2)
-			if len(p) != 2 {
-				continue
-			}
+	for _, k := range slices.Sorted(maps.Keys(f.passwords)) {
+		f.PasswordSources = append(f.PasswordSources, passwordRecipe(k, f.passwords[k]))
+	}
 
-			switch p[0] {
-			case "length":
-				l, _ := strconv.Atoi(p[1])
-				r.Length = &l
-			case "numDigits":
-				nd, _ := strconv.Atoi(p[1])
-				r.NumDigits = &nd
-			case "numSymbols":
-				ns, _ := strconv.Atoi(p[1])
-				r.NumSymbols = &ns
-			case "noUpper":
-				nu, _ := strconv.ParseBool(p[1])
-				r.NoUpper = &nu
-			case "allowRepeat":
-				ar, _ := strconv.ParseBool(p[1])
-				r.AllowRepeat = &ar
-			}
+	return nil
+}
+
+// passwordRecipe parses a comma separated list of `name:value` pairs into a recipe. Commas
+// can be included in values (e.g. the list of symbols) by escaping them with a backslash.
+func passwordRecipe(key, spec string) konjurev1beta2.PasswordRecipe {
+	r := konjurev1beta2.PasswordRecipe{Key: key}
+	for _, s := range splitUnescaped(spec, ',') {
+		p := strings.SplitN(s, ":", 2)
+		if len(p) != 2 {
+			continue
 		}
 
-		f.PasswordSources = append(f.PasswordSources, r)
+		switch p[0] {
+		case "length":
+			l, _ := strconv.Atoi(p[1])
+			r.Length = &l
+		case "numDigits":
+			nd, _ := strconv.Atoi(p[1])
+			r.NumDigits = &nd
+		case "numSymbols":
+			ns, _ := strconv.Atoi(p[1])
+			r.NumSymbols = &ns
+		case "noUpper":
+			nu, _ := strconv.ParseBool(p[1])
+			r.NoUpper = &nu
+		case "allowRepeat":
+			ar, _ := strconv.ParseBool(p[1])
+			r.AllowRepeat = &ar
+		case "lowerLetters":
+			r.LowerLetters = p[1]
+		case "upperLetters":
+			r.UpperLetters = p[1]
+		case "digits":
+			r.Digits = p[1]
+		case "symbols":
+			r.Symbols = p[1]
+		case "excludeAmbiguous":
+			ea, _ := strconv.ParseBool(p[1])
+			r.ExcludeAmbiguous = &ea
+		case "words":
+			w, _ := strconv.Atoi(p[1])
+			r.Words = &w
+		case "wordSeparator":
+			ws := p[1]
+			r.WordSeparator = &ws
+		case "wordList":
+			r.WordList = p[1]
+		case "bcrypt", "sha512-crypt":
+			r.Derived = append(r.Derived, konjurev1beta2.DerivedKeyRecipe{Key: p[1], Algorithm: p[0]})
+		}
+	}
+	return r
+}
+
+// splitUnescaped splits the string on each separator not preceded by a backslash.
+func splitUnescaped(s string, sep byte) []string {
+	var result []string
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '\\' && i+1 < len(s) && s[i+1] == sep:
+			i++
+			sb.WriteByte(sep)
+		case s[i] == sep:
+			result = append(result, sb.String())
+			sb.Reset()
+		default:
+			sb.WriteByte(s[i])
+		}
 	}
+	return append(result, sb.String())
 }
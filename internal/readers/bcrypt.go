@@ -0,0 +1,66 @@
+/*
+Copyright 2022 GramLabs, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package readers
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io"
+
+	"golang.org/x/crypto/bcrypt"
+	"golang.org/x/crypto/blowfish"
+)
+
+// bcryptEncoding is the non-standard base64 alphabet used by bcrypt.
+var bcryptEncoding = base64.NewEncoding("./ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789").WithPadding(base64.NoPadding)
+
+// bcryptHash returns the "$2a$" bcrypt hash of the password. Unlike `bcrypt.GenerateFromPassword`,
+// the salt is read from the supplied source of randomness so seeded hashes are reproducible.
+func bcryptHash(pwd []byte, cost int, rnd io.Reader) (string, error) {
+	if len(pwd) > 72 {
+		return "", bcrypt.ErrPasswordTooLong
+	}
+	if cost < bcrypt.MinCost || cost > bcrypt.MaxCost {
+		return "", bcrypt.InvalidCostError(cost)
+	}
+
+	salt := make([]byte, 16)
+	if _, err := io.ReadFull(rnd, salt); err != nil {
+		return "", err
+	}
+
+	// Include the trailing NUL for compatibility with the C implementations
+	key := append(append([]byte{}, pwd...), 0)
+	c, err := blowfish.NewSaltedCipher(key, salt)
+	if err != nil {
+		return "", err
+	}
+	for i := uint64(0); i < 1<<cost; i++ {
+		blowfish.ExpandKey(key, c)
+		blowfish.ExpandKey(salt, c)
+	}
+
+	data := []byte("OrpheanBeholderScryDoubt")
+	for i := 0; i < len(data); i += 8 {
+		for j := 0; j < 64; j++ {
+			c.Encrypt(data[i:i+8], data[i:i+8])
+		}
+	}
+
+	// Only 23 of the 24 bytes are encoded, again for compatibility
+	return fmt.Sprintf("$2a$%02d$%s%s", cost, bcryptEncoding.EncodeToString(salt), bcryptEncoding.EncodeToString(data[:23])), nil
+}
@@ -0,0 +1,104 @@
+/*
+Copyright 2022 GramLabs, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package readers
+
+import (
+	konjurev1beta2 "github.com/thestormforge/konjure/pkg/api/core/v1beta2"
+	"github.com/thestormforge/konjure/pkg/filters"
+	"sigs.k8s.io/kustomize/kyaml/yaml"
+)
+
+type ConfigMapReader struct {
+	konjurev1beta2.ConfigMap
+}
+
+func (r *ConfigMapReader) Read() ([]*yaml.RNode, error) {
+	// Build the basic config map node
+	n, err := yaml.FromMap(map[string]any{"apiVersion": "v1", "kind": "ConfigMap"})
+	if err != nil {
+		return nil, err
+	}
+	if err := n.PipeE(yaml.SetK8sName(r.ConfigMapName)); err != nil {
+		return nil, err
+	}
+	if r.AppendHash {
+		if err := n.PipeE(yaml.SetAnnotation(filters.HashSuffixAnnotation, "true")); err != nil {
+			return nil, err
+		}
+	}
+	if r.Namespace != "" {
+		if err := n.PipeE(yaml.SetK8sNamespace(r.Namespace)); err != nil {
+			return nil, err
+		}
+	}
+	if r.Immutable {
+		if err := n.PipeE(yaml.SetField("immutable", yaml.NewRNode(&yaml.Node{Kind: yaml.ScalarNode, Tag: yaml.NodeTagBool, Value: "true"}))); err != nil {
+			return nil, err
+		}
+	}
+
+	// Add all the config map data
+	if err := n.PipeE(yaml.Tee(
+		yaml.FilterFunc(r.literals),
+		yaml.FilterFunc(r.files),
+		yaml.FilterFunc(r.envs),
+	)); err != nil {
+		return nil, err
+	}
+
+	return []*yaml.RNode{n}, nil
+}
+
+func (r *ConfigMapReader) literals(n *yaml.RNode) (*yaml.RNode, error) {
+	if len(r.LiteralSources) == 0 {
+		return n, nil
+	}
+
+	m, err := literalSources(r.LiteralSources)
+	if err != nil {
+		return nil, err
+	}
+
+	return n, n.LoadMapIntoConfigMapData(m)
+}
+
+func (r *ConfigMapReader) files(n *yaml.RNode) (*yaml.RNode, error) {
+	if len(r.FileSources) == 0 {
+		return n, nil
+	}
+
+	m, err := fileSources(r.FileSources)
+	if err != nil {
+		return nil, err
+	}
+
+	// NOTE: non-UTF-8 values are automatically moved to the `binaryData` field
+	return n, n.LoadMapIntoConfigMapData(m)
+}
+
+func (r *ConfigMapReader) envs(n *yaml.RNode) (*yaml.RNode, error) {
+	if len(r.EnvSources) == 0 {
+		return n, nil
+	}
+
+	m, err := envSources(r.EnvSources)
+	if err != nil {
+		return nil, err
+	}
+
+	return n, n.LoadMapIntoConfigMapData(m)
+}
@@ -0,0 +1,66 @@
+/*
+Copyright 2022 GramLabs, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package readers
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	konjurev1beta2 "github.com/thestormforge/konjure/pkg/api/core/v1beta2"
+)
+
+func TestConfigMapReader_Read(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "conf"), 0700))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "conf", "app.properties"), []byte("color=blue\n"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "conf", "logo.bin"), []byte{0xFF, 0xFE, 0x00}, 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "readme.txt"), []byte("hello"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "app.env"), []byte("# Comment\nLOG_LEVEL=debug\n"), 0600))
+
+	nodes, err := (&ConfigMapReader{ConfigMap: konjurev1beta2.ConfigMap{
+		ConfigMapName:  "test",
+		Namespace:      "default",
+		Immutable:      true,
+		LiteralSources: []string{"greeting=hello world"},
+		FileSources:    []string{filepath.Join(dir, "conf"), "custom=" + filepath.Join(dir, "readme.txt")},
+		EnvSources:     []string{filepath.Join(dir, "app.env")},
+	}}).Read()
+	require.NoError(t, err)
+	require.Len(t, nodes, 1)
+
+	assert.Equal(t, "ConfigMap", nodes[0].GetKind())
+	assert.Equal(t, "test", nodes[0].GetName())
+	assert.Equal(t, "default", nodes[0].GetNamespace())
+
+	immutable, err := nodes[0].GetFieldValue("immutable")
+	if assert.NoError(t, err) {
+		assert.Equal(t, true, immutable)
+	}
+
+	assert.Equal(t, map[string]string{
+		"greeting":       "hello world",
+		"app.properties": "color=blue\n",
+		"custom":         "hello",
+		"LOG_LEVEL":      "debug",
+	}, nodes[0].GetDataMap())
+	assert.Equal(t, map[string]string{
+		"logo.bin": "//4A",
+	}, nodes[0].GetBinaryDataMap())
+}
@@ -0,0 +1,343 @@
+/*
+Copyright 2022 GramLabs, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package readers
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// parseEnv parses the contents of a ".env" file into the supplied map. The
+// syntax follows the conventions of the popular "dotenv" libraries:
+//
+//   - blank lines and lines starting with `#` are ignored
+//   - keys may be prefixed with `export`
+//   - unquoted values end at an inline comment (a `#` preceded by white space)
+//   - unquoted, single quoted (and back quoted) values are taken literally
+//   - double quoted values support `\n`, `\r`, `\t`, `\\`, `\"` and `\$` escapes
+//   - quoted values may span multiple lines
+//   - double quoted values expand `$VAR`, `${VAR}`, `${VAR:-default}` and
+//     `${VAR-default}` references using earlier keys or the lookup function
+//   - keys without a value are resolved using the lookup function
+func parseEnv(data []byte, vars map[string]string, lookup func(string) (string, bool)) error {
+	if !utf8.Valid(data) {
+		return fmt.Errorf("env file contains invalid UTF-8 bytes")
+	}
+
+	p := &envParser{src: strings.TrimPrefix(string(data), "\uFEFF"), line: 1, vars: vars, lookup: lookup}
+	for {
+		p.skip(" \t\r\n")
+		if p.eof() {
+			return nil
+		}
+
+		if p.peek() == '#' {
+			p.skipLine()
+			continue
+		}
+
+		line := p.line
+		if err := p.parseEntry(); err != nil {
+			return fmt.Errorf("line %d: %w", line, err)
+		}
+	}
+}
+
+// envParser holds the state of the env file parser.
+type envParser struct {
+	src    string
+	pos    int
+	line   int
+	vars   map[string]string
+	lookup func(string) (string, bool)
+}
+
+// parseEntry parses a single `key=value` entry.
+func (p *envParser) parseEntry() error {
+	key := p.parseKey()
+	if key == "export" && strings.ContainsRune(" \t", rune(p.peek())) {
+		p.skip(" \t")
+		key = p.parseKey()
+	}
+	if !isEnvKey(key) {
+		return fmt.Errorf("invalid key %q", key)
+	}
+
+	p.skip(" \t")
+	if p.eof() || p.peek() == '\n' || p.peek() == '\r' || p.peek() == '#' {
+		// A key without a value is taken from the environment
+		p.skipLine()
+		p.vars[key] = p.resolve(key)
+		return nil
+	}
+	if p.peek() != '=' {
+		return fmt.Errorf("expected '=' after key %q", key)
+	}
+	p.pos++
+	p.skip(" \t")
+
+	var value string
+	var err error
+	switch q := p.peek(); {
+	case p.eof():
+	case q == '\'' || q == '`':
+		value, err = p.parseLiteral(q)
+	case q == '"':
+		value, err = p.parseDoubleQuoted()
+	default:
+		value = p.parseUnquoted()
+	}
+	if err != nil {
+		return err
+	}
+
+	p.vars[key] = value
+	return nil
+}
+
+// parseKey returns the characters up to the next separator.
+func (p *envParser) parseKey() string {
+	start := p.pos
+	for !p.eof() && !strings.ContainsRune("= \t\r\n#", rune(p.peek())) {
+		p.pos++
+	}
+	return p.src[start:p.pos]
+}
+
+// parseLiteral returns a single or back quoted value without any processing.
+func (p *envParser) parseLiteral(quote byte) (string, error) {
+	p.pos++
+	end := strings.IndexByte(p.src[p.pos:], quote)
+	if end < 0 {
+		return "", fmt.Errorf("unterminated quoted value")
+	}
+
+	value := p.src[p.pos : p.pos+end]
+	p.line += strings.Count(value, "\n")
+	p.pos += end + 1
+	return value, p.endQuoted()
+}
+
+// parseDoubleQuoted returns a double quoted value with escapes and variable references processed.
+func (p *envParser) parseDoubleQuoted() (string, error) {
+	p.pos++
+	var sb strings.Builder
+	for {
+		if p.eof() {
+			return "", fmt.Errorf("unterminated quoted value")
+		}
+
+		c := p.src[p.pos]
+		switch {
+		case c == '"':
+			p.pos++
+			return sb.String(), p.endQuoted()
+
+		case c == '\\' && p.pos+1 < len(p.src):
+			p.pos += 2
+			switch e := p.src[p.pos-1]; e {
+			case 'n':
+				sb.WriteByte('\n')
+			case 'r':
+				sb.WriteByte('\r')
+			case 't':
+				sb.WriteByte('\t')
+			case '\\', '"', '$':
+				sb.WriteByte(e)
+			default:
+				sb.WriteByte('\\')
+				sb.WriteByte(e)
+			}
+
+		case c == '$':
+			sb.WriteString(p.parseReference())
+
+		default:
+			if c == '\n' {
+				p.line++
+			}
+			sb.WriteByte(c)
+			p.pos++
+		}
+	}
+}
+
+// parseUnquoted returns an unquoted value without any processing.
+func (p *envParser) parseUnquoted() string {
+	var sb strings.Builder
+	for !p.eof() {
+		c := p.src[p.pos]
+		switch {
+		case c == '\n':
+			return strings.TrimRight(sb.String(), " \t\r")
+
+		case c == '#' && p.pos > 0 && strings.ContainsRune(" \t", rune(p.src[p.pos-1])):
+			p.skipLine()
+			return strings.TrimRight(sb.String(), " \t\r")
+
+		default:
+			sb.WriteByte(c)
+			p.pos++
+		}
+	}
+	return strings.TrimRight(sb.String(), " \t\r")
+}
+
+// parseDefault returns the default value of a reference with nested variable references processed.
+func (p *envParser) parseDefault() string {
+	var sb strings.Builder
+	for !p.eof() {
+		if p.peek() == '$' {
+			sb.WriteString(p.parseReference())
+			continue
+		}
+		sb.WriteByte(p.src[p.pos])
+		p.pos++
+	}
+	return sb.String()
+}
+
+// parseReference expands a variable reference starting at the current `$`.
+func (p *envParser) parseReference() string {
+	p.pos++
+	if p.eof() {
+		return "$"
+	}
+
+	// Simple `$VAR` references
+	if p.peek() != '{' {
+		start := p.pos
+		for !p.eof() && isEnvNameChar(p.peek(), p.pos == start) {
+			p.pos++
+		}
+		if start == p.pos {
+			return "$"
+		}
+		return p.resolve(p.src[start:p.pos])
+	}
+
+	// Braced `${VAR}` references, find the matching brace
+	depth, end := 1, p.pos+1
+	for ; end < len(p.src) && depth > 0; end++ {
+		switch p.src[end] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+		case '\n':
+			end = len(p.src)
+		}
+	}
+	if depth > 0 {
+		// Not a valid reference, treat it literally
+		return "$"
+	}
+
+	expr := p.src[p.pos+1 : end-1]
+	p.pos = end
+
+	name, def, hasDefault := expr, "", false
+	var emptyIsUnset bool
+	if i := strings.Index(expr, ":-"); i >= 0 {
+		name, def, hasDefault, emptyIsUnset = expr[:i], expr[i+2:], true, true
+	} else if i := strings.IndexByte(expr, '-'); i >= 0 {
+		name, def, hasDefault = expr[:i], expr[i+1:], true
+	}
+
+	value, ok := p.vars[name]
+	if !ok && p.lookup != nil {
+		value, ok = p.lookup(name)
+	}
+	if hasDefault && (!ok || (emptyIsUnset && value == "")) {
+		// Defaults may contain references of their own
+		dp := &envParser{src: def, vars: p.vars, lookup: p.lookup}
+		return dp.parseDefault()
+	}
+	return value
+}
+
+// resolve returns the value of a variable from earlier keys or the lookup function.
+func (p *envParser) resolve(name string) string {
+	if value, ok := p.vars[name]; ok {
+		return value
+	}
+	if p.lookup != nil {
+		value, _ := p.lookup(name)
+		return value
+	}
+	return ""
+}
+
+// endQuoted verifies only white space or a comment follows a quoted value.
+func (p *envParser) endQuoted() error {
+	p.skip(" \t\r")
+	if p.eof() || p.peek() == '\n' {
+		return nil
+	}
+	if p.peek() == '#' {
+		p.skipLine()
+		return nil
+	}
+	return fmt.Errorf("unexpected character %q after quoted value", p.peek())
+}
+
+func (p *envParser) eof() bool { return p.pos >= len(p.src) }
+
+func (p *envParser) peek() byte {
+	if p.eof() {
+		return 0
+	}
+	return p.src[p.pos]
+}
+
+// skip advances past any of the supplied characters.
+func (p *envParser) skip(chars string) {
+	for !p.eof() && strings.IndexByte(chars, p.src[p.pos]) >= 0 {
+		if p.src[p.pos] == '\n' {
+			p.line++
+		}
+		p.pos++
+	}
+}
+
+// skipLine advances to the end of the current line.
+func (p *envParser) skipLine() {
+	if i := strings.IndexByte(p.src[p.pos:], '\n'); i >= 0 {
+		p.pos += i
+	} else {
+		p.pos = len(p.src)
+	}
+}
+
+// isEnvKey checks if the supplied key is valid.
+func isEnvKey(key string) bool {
+	if key == "" {
+		return false
+	}
+	for i := 0; i < len(key); i++ {
+		if !isEnvNameChar(key[i], i == 0) && (i == 0 || !strings.ContainsRune(".-", rune(key[i]))) {
+			return false
+		}
+	}
+	return true
+}
+
+// isEnvNameChar checks if the supplied character can be used in a variable name.
+func isEnvNameChar(c byte, first bool) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (!first && c >= '0' && c <= '9')
+}
@@ -0,0 +1,136 @@
+/*
+Copyright 2022 GramLabs, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package readers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseEnv(t *testing.T) {
+	env := map[string]string{
+		"HOME":  "/home/test",
+		"EMPTY": "",
+	}
+	lookup := func(key string) (string, bool) {
+		value, ok := env[key]
+		return value, ok
+	}
+
+	cases := []struct {
+		desc        string
+		input       string
+		expected    map[string]string
+		expectedErr string
+	}{
+		// Basics
+		{desc: "empty", input: "", expected: map[string]string{}},
+		{desc: "simple", input: "FOO=bar", expected: map[string]string{"FOO": "bar"}},
+		{desc: "empty value", input: "FOO=", expected: map[string]string{"FOO": ""}},
+		{desc: "multiple", input: "FOO=bar\nBAR=baz\n", expected: map[string]string{"FOO": "bar", "BAR": "baz"}},
+		{desc: "crlf", input: "FOO=bar\r\nBAR=baz\r\n", expected: map[string]string{"FOO": "bar", "BAR": "baz"}},
+		{desc: "byte order mark", input: "\uFEFFFOO=bar", expected: map[string]string{"FOO": "bar"}},
+		{desc: "blank lines", input: "\n\nFOO=bar\n\n", expected: map[string]string{"FOO": "bar"}},
+		{desc: "spaces around equals", input: "FOO = bar", expected: map[string]string{"FOO": "bar"}},
+		{desc: "leading white space", input: "  FOO=bar", expected: map[string]string{"FOO": "bar"}},
+		{desc: "trailing white space", input: "FOO=bar  \t", expected: map[string]string{"FOO": "bar"}},
+		{desc: "inner white space", input: "FOO=bar baz", expected: map[string]string{"FOO": "bar baz"}},
+		{desc: "equals in value", input: "FOO=bar=baz", expected: map[string]string{"FOO": "bar=baz"}},
+		{desc: "dotted key", input: "app.name=test", expected: map[string]string{"app.name": "test"}},
+		{desc: "dashed key", input: "app-name=test", expected: map[string]string{"app-name": "test"}},
+		{desc: "later keys win", input: "FOO=bar\nFOO=baz", expected: map[string]string{"FOO": "baz"}},
+		{desc: "export", input: "export FOO=bar", expected: map[string]string{"FOO": "bar"}},
+		{desc: "export as key", input: "export=bar", expected: map[string]string{"export": "bar"}},
+
+		// Comments
+		{desc: "comment line", input: "# FOO=bar\nBAR=baz", expected: map[string]string{"BAR": "baz"}},
+		{desc: "indented comment", input: "  # FOO=bar", expected: map[string]string{}},
+		{desc: "inline comment", input: "FOO=bar # comment", expected: map[string]string{"FOO": "bar"}},
+		{desc: "hash without space", input: "FOO=bar#baz", expected: map[string]string{"FOO": "bar#baz"}},
+		{desc: "hash value", input: "FOO=#bar", expected: map[string]string{"FOO": "#bar"}},
+		{desc: "comment only value", input: "FOO= # comment", expected: map[string]string{"FOO": ""}},
+		{desc: "quoted hash", input: `FOO="bar # baz"`, expected: map[string]string{"FOO": "bar # baz"}},
+		{desc: "comment after quotes", input: `FOO="bar" # baz`, expected: map[string]string{"FOO": "bar"}},
+
+		// Single quotes
+		{desc: "single quoted", input: `FOO='bar'`, expected: map[string]string{"FOO": "bar"}},
+		{desc: "single quoted spaces", input: `FOO='  bar  '`, expected: map[string]string{"FOO": "  bar  "}},
+		{desc: "single quoted escapes", input: `FOO='bar\nbaz'`, expected: map[string]string{"FOO": `bar\nbaz`}},
+		{desc: "single quoted reference", input: `FOO='$HOME'`, expected: map[string]string{"FOO": "$HOME"}},
+		{desc: "single quoted double quote", input: `FOO='"bar"'`, expected: map[string]string{"FOO": `"bar"`}},
+		{desc: "single quoted multi-line", input: "FOO='bar\nbaz'\nBAR=1", expected: map[string]string{"FOO": "bar\nbaz", "BAR": "1"}},
+		{desc: "back quoted", input: "FOO=`it's \"bar\"`", expected: map[string]string{"FOO": `it's "bar"`}},
+
+		// Double quotes
+		{desc: "double quoted", input: `FOO="bar"`, expected: map[string]string{"FOO": "bar"}},
+		{desc: "double quoted empty", input: `FOO=""`, expected: map[string]string{"FOO": ""}},
+		{desc: "double quoted spaces", input: `FOO="  bar  "`, expected: map[string]string{"FOO": "  bar  "}},
+		{desc: "double quoted escapes", input: `FOO="a\nb\tc\rd\\e\"f\$g"`, expected: map[string]string{"FOO": "a\nb\tc\rd\\e\"f$g"}},
+		{desc: "double quoted unknown escape", input: `FOO="a\qb"`, expected: map[string]string{"FOO": `a\qb`}},
+		{desc: "double quoted single quote", input: `FOO="it's"`, expected: map[string]string{"FOO": "it's"}},
+		{desc: "double quoted multi-line", input: "FOO=\"bar\nbaz\"\nBAR=1", expected: map[string]string{"FOO": "bar\nbaz", "BAR": "1"}},
+		{desc: "double quoted pem", input: "KEY=\"-----BEGIN KEY-----\nabc\n-----END KEY-----\n\"", expected: map[string]string{"KEY": "-----BEGIN KEY-----\nabc\n-----END KEY-----\n"}},
+
+		// Interpolation
+		{desc: "reference earlier key", input: "FOO=bar\nBAR=\"$FOO\"", expected: map[string]string{"FOO": "bar", "BAR": "bar"}},
+		{desc: "braced reference", input: "FOO=bar\nBAR=\"${FOO}baz\"", expected: map[string]string{"FOO": "bar", "BAR": "barbaz"}},
+		{desc: "quoted reference", input: "FOO=bar\nBAR=\"${FOO} baz\"", expected: map[string]string{"FOO": "bar", "BAR": "bar baz"}},
+		{desc: "reference environment", input: `FOO="$HOME/.config"`, expected: map[string]string{"FOO": "/home/test/.config"}},
+		{desc: "earlier key before environment", input: "HOME=/root\nFOO=\"$HOME\"", expected: map[string]string{"HOME": "/root", "FOO": "/root"}},
+		{desc: "reference later key", input: "FOO=\"$BAR\"\nBAR=bar", expected: map[string]string{"FOO": "", "BAR": "bar"}},
+		{desc: "undefined reference", input: `FOO="${UNDEFINED}"`, expected: map[string]string{"FOO": ""}},
+		{desc: "default", input: `FOO="${UNDEFINED:-bar}"`, expected: map[string]string{"FOO": "bar"}},
+		{desc: "default for empty", input: `FOO="${EMPTY:-bar}"`, expected: map[string]string{"FOO": "bar"}},
+		{desc: "default for unset only", input: "FOO=\"${EMPTY-bar}\"\nBAR=\"${UNDEFINED-baz}\"", expected: map[string]string{"FOO": "", "BAR": "baz"}},
+		{desc: "default reference", input: `FOO="${UNDEFINED:-$HOME}"`, expected: map[string]string{"FOO": "/home/test"}},
+		{desc: "nested default reference", input: `FOO="${UNDEFINED:-${HOME}/bin}"`, expected: map[string]string{"FOO": "/home/test/bin"}},
+		{desc: "lone dollar", input: `FOO="$ 5"`, expected: map[string]string{"FOO": "$ 5"}},
+		{desc: "trailing dollar", input: `FOO="bar$"`, expected: map[string]string{"FOO": "bar$"}},
+		{desc: "unterminated brace", input: `FOO="${HOME"`, expected: map[string]string{"FOO": "${HOME"}},
+		{desc: "unquoted reference", input: "FOO=$HOME", expected: map[string]string{"FOO": "$HOME"}},
+		{desc: "unquoted braced reference", input: "FOO=bar\nBAR=${FOO}baz", expected: map[string]string{"FOO": "bar", "BAR": "${FOO}baz"}},
+		{desc: "unquoted password", input: "PASSWORD=pa$$word", expected: map[string]string{"PASSWORD": "pa$$word"}},
+		{desc: "unquoted backslash dollar", input: `FOO=\$HOME`, expected: map[string]string{"FOO": `\$HOME`}},
+
+		// Keys without values
+		{desc: "environment key", input: "HOME", expected: map[string]string{"HOME": "/home/test"}},
+		{desc: "undefined environment key", input: "UNDEFINED", expected: map[string]string{"UNDEFINED": ""}},
+
+		// Errors
+		{desc: "invalid key", input: "1FOO=bar", expectedErr: `line 1: invalid key "1FOO"`},
+		{desc: "missing equals", input: "FOO bar", expectedErr: `line 1: expected '=' after key "FOO"`},
+		{desc: "unterminated single quote", input: "FOO='bar", expectedErr: "line 1: unterminated quoted value"},
+		{desc: "unterminated double quote", input: "A=1\nFOO=\"bar\n", expectedErr: "line 2: unterminated quoted value"},
+		{desc: "text after quotes", input: `FOO="bar"baz`, expectedErr: `line 1: unexpected character 'b' after quoted value`},
+		{desc: "line number after multi-line", input: "FOO='a\nb'\n=bar", expectedErr: `line 3: invalid key ""`},
+		{desc: "invalid utf-8", input: "FOO=\xff", expectedErr: "env file contains invalid UTF-8 bytes"},
+	}
+	for _, c := range cases {
+		t.Run(c.desc, func(t *testing.T) {
+			actual := make(map[string]string)
+			err := parseEnv([]byte(c.input), actual, lookup)
+			if c.expectedErr != "" {
+				assert.EqualError(t, err, c.expectedErr)
+				return
+			}
+			if assert.NoError(t, err) {
+				assert.Equal(t, c.expected, actual)
+			}
+		})
+	}
+}
@@ -24,6 +24,7 @@ import (
 	"path/filepath"
 	"strings"
 
+	"github.com/thestormforge/konjure/internal/sops"
 	konjurev1beta2 "github.com/thestormforge/konjure/pkg/api/core/v1beta2"
 	"sigs.k8s.io/kustomize/kyaml/kio"
 	"sigs.k8s.io/kustomize/kyaml/kio/kioutil"
@@ -85,6 +86,13 @@ func (r *FileReader) Read() ([]*yaml.RNode, error) {
 				return err
 			}
 
+			// Decrypt SOPS encrypted documents using the locally available keys
+			if sops.IsEncrypted(data) {
+				if data, err = sops.Decrypt(data); err != nil {
+					return fmt.Errorf("unable to decrypt %s: %w", path, err)
+				}
+			}
+
 			br := &kio.ByteReader{
 				Reader: bytes.NewReader(data),
 				SetAnnotations: map[string]string{
@@ -77,6 +77,14 @@ func (f *Filter) expandToDepth(nodes []*yaml.RNode, depth int) ([]*yaml.RNode, e
 			r = opt(n, r)
 		}
 
+		// Overlays and replacements must fully expand their own resources before they can be transformed
+		switch nr := r.(type) {
+		case *OverlayReader:
+			nr.Expander = &Filter{Depth: depth - 1, ReaderOptions: f.ReaderOptions}
+		case *ReplacementReader:
+			nr.Expander = &Filter{Depth: depth - 1, ReaderOptions: f.ReaderOptions}
+		}
+
 		expanded, err := r.Read()
 		if err != nil {
 			return nil, err
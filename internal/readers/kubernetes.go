@@ -25,6 +25,7 @@ import (
 
 	konjurev1beta2 "github.com/thestormforge/konjure/pkg/api/core/v1beta2"
 	"github.com/thestormforge/konjure/pkg/filters"
+	"sigs.k8s.io/kustomize/kyaml/kio"
 	"sigs.k8s.io/kustomize/kyaml/yaml"
 )
 
@@ -32,15 +33,20 @@ type KubernetesReader struct {
 	konjurev1beta2.Kubernetes
 	Runtime
 
-	// Override the default path to the kubeconfig file.
+	// Override the default path to the kubeconfig file (ignored if the resource specifies a kubeconfig).
 	Kubeconfig string
-	// Override the default kubeconfig context.
+	// Override the default kubeconfig context (ignored if the resource specifies a context).
 	Context string
 	// The list of default types to use if none are specified.
 	DefaultTypes []string
 }
 
 func (k *KubernetesReader) Read() ([]*yaml.RNode, error) {
+	// kubectl does not allow a name to be combined with a selector
+	if k.Name != "" && (k.Selector != "" || k.FieldSelector != "") {
+		return nil, fmt.Errorf("name cannot be provided when a selector is specified")
+	}
+
 	p := &filters.Pipeline{}
 
 	var namespaces []string
@@ -62,8 +68,12 @@ func (k *KubernetesReader) Read() ([]*yaml.RNode, error) {
 		cmd.Args = append(cmd.Args, "get")
 		cmd.Args = append(cmd.Args, "--ignore-not-found")
 		cmd.Args = append(cmd.Args, "--output", "yaml")
-		cmd.Args = append(cmd.Args, "--selector", k.Selector)
-		cmd.Args = append(cmd.Args, "--field-selector", k.FieldSelector)
+		if k.Selector != "" {
+			cmd.Args = append(cmd.Args, "--selector", k.Selector)
+		}
+		if k.FieldSelector != "" {
+			cmd.Args = append(cmd.Args, "--field-selector", k.FieldSelector)
+		}
 
 		if k.AllNamespaces {
 			cmd.Args = append(cmd.Args, "--all-namespaces")
@@ -73,21 +83,38 @@ func (k *KubernetesReader) Read() ([]*yaml.RNode, error) {
 		}
 
 		cmd.Args = append(cmd.Args, strings.Join(types, ","))
+		if k.Name != "" {
+			cmd.Args = append(cmd.Args, k.Name)
+		}
 
 		p.Inputs = append(p.Inputs, cmd)
 	}
 
+	// Record that the resources came from a cluster so immutable fields can be protected
+	p.Filters = append(p.Filters, kio.FilterAll(yaml.SetAnnotation(filters.LiveAnnotation, "true")))
+
 	return p.Read()
 }
 
 func (k *KubernetesReader) command() *command {
 	cmd := k.Runtime.command("kubectl")
-	if k.Kubeconfig != "" {
-		cmd.Args = append(cmd.Args, "--kubeconfig", k.Kubeconfig)
+
+	kubeconfig := k.Kubernetes.Kubeconfig
+	if kubeconfig == "" {
+		kubeconfig = k.Kubeconfig
+	}
+	if kubeconfig != "" {
+		cmd.Args = append(cmd.Args, "--kubeconfig", kubeconfig)
 	}
-	if k.Context != "" {
-		cmd.Args = append(cmd.Args, "--context", k.Context)
+
+	context := k.Kubernetes.Context
+	if context == "" {
+		context = k.Context
 	}
+	if context != "" {
+		cmd.Args = append(cmd.Args, "--context", context)
+	}
+
 	return cmd
 }
 
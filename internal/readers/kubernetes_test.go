@@ -0,0 +1,46 @@
+/*
+Copyright 2022 GramLabs, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package readers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	konjurev1beta2 "github.com/thestormforge/konjure/pkg/api/core/v1beta2"
+)
+
+func TestKubernetesReader_NameAndSelector(t *testing.T) {
+	cases := []struct {
+		desc string
+		spec konjurev1beta2.Kubernetes
+	}{
+		{
+			desc: "label selector",
+			spec: konjurev1beta2.Kubernetes{Types: []string{"deployments"}, Name: "test", Selector: "app=test"},
+		},
+		{
+			desc: "field selector",
+			spec: konjurev1beta2.Kubernetes{Types: []string{"deployments"}, Name: "test", FieldSelector: "metadata.namespace=default"},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.desc, func(t *testing.T) {
+			_, err := (&KubernetesReader{Kubernetes: c.spec}).Read()
+			assert.EqualError(t, err, "name cannot be provided when a selector is specified")
+		})
+	}
+}
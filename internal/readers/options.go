@@ -67,7 +67,10 @@ func WithRecursiveDirectories(recurse bool) Option {
 // WithKubeconfig controls the default path of the kubeconfig file.
 func WithKubeconfig(kubeconfig string) Option {
 	return func(_ *yaml.RNode, r kio.Reader) kio.Reader {
-		if kr, ok := r.(*KubernetesReader); ok {
+		switch kr := r.(type) {
+		case *KubernetesReader:
+			kr.Kubeconfig = kubeconfig
+		case *SecretReader:
 			kr.Kubeconfig = kubeconfig
 		}
 		return r
@@ -77,7 +80,10 @@ func WithKubeconfig(kubeconfig string) Option {
 // WithKubectlExecutor controls the alternate executor for kubectl.
 func WithKubectlExecutor(executor Executor) Option {
 	return func(_ *yaml.RNode, r kio.Reader) kio.Reader {
-		if kr, ok := r.(*KubernetesReader); ok {
+		switch kr := r.(type) {
+		case *KubernetesReader:
+			kr.Executor = executor
+		case *SecretReader:
 			kr.Executor = executor
 		}
 		return r
@@ -0,0 +1,82 @@
+/*
+Copyright 2022 GramLabs, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package readers
+
+import (
+	konjurev1beta2 "github.com/thestormforge/konjure/pkg/api/core/v1beta2"
+	"github.com/thestormforge/konjure/pkg/filters"
+	"sigs.k8s.io/kustomize/kyaml/kio"
+	"sigs.k8s.io/kustomize/kyaml/yaml"
+)
+
+// OverlayReader expands the nested resources of an overlay and transforms them
+// in isolation from any other resources.
+type OverlayReader struct {
+	konjurev1beta2.Overlay
+	// The filter used to expand the nested resources, if nil the nested
+	// resources are not expanded beyond their Konjure resource specifications.
+	Expander kio.Filter
+}
+
+// Read expands and transforms the overlay resources.
+func (r *OverlayReader) Read() ([]*yaml.RNode, error) {
+	n, err := konjurev1beta2.GetRNode(&konjurev1beta2.Resource{Resources: r.Resources})
+	if err != nil {
+		return nil, err
+	}
+
+	p := &filters.Pipeline{
+		Inputs: []kio.Reader{kio.ResourceNodeSlice{n}},
+	}
+
+	if r.Expander != nil {
+		p.Filters = append(p.Filters, r.Expander)
+	}
+
+	for i := range r.Patches {
+		p.Filters = append(p.Filters, &filters.TargetedPatchFilter{
+			Target: resourceMetaFilter(r.Patches[i].Target),
+			Patch:  filters.PatchFilter{PatchType: r.Patches[i].Type, PatchData: []byte(r.Patches[i].Patch)},
+		})
+	}
+
+	p.Filters = append(p.Filters,
+		&filters.NamespaceFilter{Namespace: r.Namespace},
+		&filters.CommonMetadataFilter{Labels: r.Labels, IncludeTemplates: true, IncludeSelectors: true, ProtectImmutableSelectors: true},
+		&filters.NameFilter{Prefix: r.NamePrefix},
+	)
+
+	return p.Read()
+}
+
+// resourceMetaFilter converts an API selector into a filter.
+func resourceMetaFilter(s *konjurev1beta2.Selector) *filters.ResourceMetaFilter {
+	if s == nil {
+		return nil
+	}
+	return &filters.ResourceMetaFilter{
+		Group:              s.Group,
+		Version:            s.Version,
+		Kind:               s.Kind,
+		Namespace:          s.Namespace,
+		Name:               s.Name,
+		LabelSelector:      s.LabelSelector,
+		AnnotationSelector: s.AnnotationSelector,
+		Expression:         s.Expression,
+		InvertMatch:        s.InvertMatch,
+	}
+}
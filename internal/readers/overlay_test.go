@@ -0,0 +1,93 @@
+/*
+Copyright 2022 GramLabs, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package readers
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	konjurev1beta2 "github.com/thestormforge/konjure/pkg/api/core/v1beta2"
+	"sigs.k8s.io/kustomize/kyaml/yaml"
+)
+
+func TestOverlayReader(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "app.yaml"), []byte(`apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+spec:
+  replicas: 1
+  template:
+    spec:
+      containers:
+      - name: app
+        image: nginx
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: config
+`), 0644))
+
+	overlay, err := konjurev1beta2.GetRNode(&konjurev1beta2.Overlay{
+		Resources:  []string{filepath.Join(dir, "app.yaml")},
+		Namespace:  "test",
+		NamePrefix: "dev-",
+		Patches: []konjurev1beta2.Patch{
+			{
+				Target: &konjurev1beta2.Selector{Kind: "Deployment"},
+				Patch:  `{"spec": {"replicas": 3, "template": {"spec": {"containers": [{"name": "app", "image": "nginx:1.25"}]}}}}`,
+			},
+			{
+				Target: &konjurev1beta2.Selector{Kind: "ConfigMap"},
+				Type:   "json",
+				Patch:  `[{"op": "add", "path": "/data", "value": {"key": "value"}}]`,
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	// Resources outside the overlay must not be transformed
+	other := yaml.MustParse(`apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: other
+`)
+
+	nodes, err := (&Filter{Depth: 10}).Filter([]*yaml.RNode{overlay, other})
+	require.NoError(t, err)
+	require.Len(t, nodes, 3)
+
+	assert.Equal(t, "dev-app", nodes[0].GetName())
+	assert.Equal(t, "test", nodes[0].GetNamespace())
+	replicas, err := nodes[0].GetFieldValue("spec.replicas")
+	require.NoError(t, err)
+	assert.Equal(t, 3, replicas)
+	image, err := nodes[0].Pipe(yaml.Lookup("spec", "template", "spec", "containers", "[name=app]", "image"))
+	require.NoError(t, err)
+	assert.Equal(t, "nginx:1.25", yaml.GetValue(image))
+
+	assert.Equal(t, "dev-config", nodes[1].GetName())
+	assert.Equal(t, map[string]string{"key": "value"}, nodes[1].GetDataMap())
+
+	assert.Equal(t, "other", nodes[2].GetName())
+	assert.Empty(t, nodes[2].GetNamespace())
+}
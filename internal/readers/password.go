@@ -0,0 +1,312 @@
+/*
+Copyright 2022 GramLabs, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package readers
+
+import (
+	"crypto/sha512"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/sethvargo/go-diceware/diceware"
+	"github.com/sethvargo/go-password/password"
+	konjurev1beta2 "github.com/thestormforge/konjure/pkg/api/core/v1beta2"
+	"golang.org/x/crypto/bcrypt"
+	"sigs.k8s.io/kustomize/kyaml/yaml"
+)
+
+// ambiguousCharacters are excluded from passwords when requested.
+const ambiguousCharacters = "Il1|O0o`'\""
+
+func (r *SecretReader) passwords(n *yaml.RNode) (*yaml.RNode, error) {
+	if len(r.PasswordSources) == 0 {
+		return n, nil
+	}
+
+	m := make(map[string]string)
+	for i := range r.PasswordSources {
+		s := &r.PasswordSources[i]
+
+		var pwd string
+		var err error
+		if s.Words != nil {
+			pwd, err = generatePassphrase(s, r.random(s.Key))
+		} else {
+			pwd, err = generatePassword(s, r.PasswordOptions, r.random(s.Key))
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		m[s.Key] = pwd
+	}
+	m = r.preserve(m)
+
+	// Compute derived values from the final (possibly preserved) passwords
+	for i := range r.PasswordSources {
+		s := &r.PasswordSources[i]
+		_, preserved := r.existing[s.Key]
+		for _, d := range s.Derived {
+			if v, ok := r.existing[d.Key]; ok && preserved {
+				m[d.Key] = v
+				continue
+			}
+
+			v, err := derive(d.Algorithm, m[s.Key], r.random(d.Key))
+			if err != nil {
+				return nil, err
+			}
+			m[d.Key] = v
+		}
+	}
+
+	return n, n.LoadMapIntoSecretData(m)
+}
+
+// generatePassword generates a random string of characters.
+func generatePassword(s *konjurev1beta2.PasswordRecipe, defaults *password.GeneratorInput, rnd io.Reader) (string, error) {
+	opts := password.GeneratorInput{}
+	if defaults != nil {
+		opts = *defaults
+	}
+	opts.Reader = rnd
+
+	if s.LowerLetters != "" {
+		opts.LowerLetters = s.LowerLetters
+	}
+	if s.UpperLetters != "" {
+		opts.UpperLetters = s.UpperLetters
+	}
+	if s.Digits != "" {
+		opts.Digits = s.Digits
+	}
+	if s.Symbols != "" {
+		opts.Symbols = s.Symbols
+	}
+	if s.ExcludeAmbiguous != nil && *s.ExcludeAmbiguous {
+		opts.LowerLetters = withoutAmbiguous(opts.LowerLetters, password.LowerLetters)
+		opts.UpperLetters = withoutAmbiguous(opts.UpperLetters, password.UpperLetters)
+		opts.Digits = withoutAmbiguous(opts.Digits, password.Digits)
+		opts.Symbols = withoutAmbiguous(opts.Symbols, password.Symbols)
+	}
+
+	gen, err := password.NewGenerator(&opts)
+	if err != nil {
+		return "", err
+	}
+
+	return gen.Generate(passwordArgs(s))
+}
+
+// generatePassphrase generates a diceware style passphrase.
+func generatePassphrase(s *konjurev1beta2.PasswordRecipe, rnd io.Reader) (string, error) {
+	opts := diceware.GeneratorInput{RandReader: rnd}
+	switch s.WordList {
+	case "", "eff-large":
+		opts.WordList = diceware.WordListEffLarge()
+	case "eff-small":
+		opts.WordList = diceware.WordListEffSmall()
+	case "original":
+		opts.WordList = diceware.WordListOriginal()
+	default:
+		return "", fmt.Errorf("unknown word list %q", s.WordList)
+	}
+
+	gen, err := diceware.NewGenerator(&opts)
+	if err != nil {
+		return "", err
+	}
+
+	words, err := gen.Generate(*s.Words)
+	if err != nil {
+		return "", err
+	}
+
+	sep := "-"
+	if s.WordSeparator != nil {
+		sep = *s.WordSeparator
+	}
+	return strings.Join(words, sep), nil
+}
+
+func passwordArgs(s *konjurev1beta2.PasswordRecipe) (length int, numDigits int, numSymbols int, noUpper bool, allowRepeat bool) {
+	if s.Length != nil {
+		length = *s.Length
+	}
+	if s.NumDigits != nil {
+		numDigits = *s.NumDigits
+	}
+	if s.NumSymbols != nil {
+		numSymbols = *s.NumSymbols
+	}
+	if s.NoUpper != nil {
+		noUpper = *s.NoUpper
+	}
+	if s.AllowRepeat != nil {
+		allowRepeat = *s.AllowRepeat
+	}
+
+	// TODO Is this reasonable default logic?
+	if length == 0 {
+		length = 64
+	}
+	if numDigits == 0 && numSymbols+10 <= length {
+		numDigits = 10
+	}
+	if numSymbols == 0 && numDigits+10 <= length {
+		numSymbols = 10
+	}
+
+	return length, numDigits, numSymbols, noUpper, allowRepeat
+}
+
+// withoutAmbiguous removes the ambiguous characters from a character set.
+func withoutAmbiguous(chars, defaultChars string) string {
+	if chars == "" {
+		chars = defaultChars
+	}
+	return strings.Map(func(r rune) rune {
+		if strings.ContainsRune(ambiguousCharacters, r) {
+			return -1
+		}
+		return r
+	}, chars)
+}
+
+// derive computes a value from the supplied password.
+func derive(algorithm, pwd string, rnd io.Reader) (string, error) {
+	switch strings.ToLower(algorithm) {
+	case "bcrypt":
+		return bcryptHash([]byte(pwd), bcrypt.DefaultCost, rnd)
+
+	case "sha512-crypt", "sha512crypt":
+		salt := make([]byte, 16)
+		if _, err := io.ReadFull(rnd, salt); err != nil {
+			return "", err
+		}
+		for i := range salt {
+			salt[i] = cryptAlphabet[int(salt[i])%len(cryptAlphabet)]
+		}
+		return sha512Crypt(pwd, string(salt), 5000), nil
+
+	default:
+		return "", fmt.Errorf("unknown derived key algorithm %q", algorithm)
+	}
+}
+
+// cryptAlphabet is the alphabet used by the crypt(3) base-64 encoding.
+const cryptAlphabet = "./0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+// sha512Crypt implements the SHA-512 based crypt(3) algorithm (the "$6$" scheme).
+// See https://www.akkadia.org/drepper/SHA-crypt.txt for details.
+func sha512Crypt(pwd, salt string, rounds int) string {
+	if len(salt) > 16 {
+		salt = salt[:16]
+	}
+	p, s := []byte(pwd), []byte(salt)
+
+	b := sha512.New()
+	b.Write(p)
+	b.Write(s)
+	b.Write(p)
+	sumB := b.Sum(nil)
+
+	a := sha512.New()
+	a.Write(p)
+	a.Write(s)
+	for i := len(p); i > 0; i -= 64 {
+		a.Write(sumB[:min(i, 64)])
+	}
+	for i := len(p); i > 0; i >>= 1 {
+		if i&1 != 0 {
+			a.Write(sumB)
+		} else {
+			a.Write(p)
+		}
+	}
+	sumA := a.Sum(nil)
+
+	dp := sha512.New()
+	for range p {
+		dp.Write(p)
+	}
+	sumDP := dp.Sum(nil)
+	seqP := make([]byte, 0, len(p))
+	for i := len(p); i > 0; i -= 64 {
+		seqP = append(seqP, sumDP[:min(i, 64)]...)
+	}
+
+	ds := sha512.New()
+	for i := 0; i < 16+int(sumA[0]); i++ {
+		ds.Write(s)
+	}
+	sumDS := ds.Sum(nil)
+	seqS := sumDS[:len(s)]
+
+	sumC := sumA
+	for i := 0; i < rounds; i++ {
+		c := sha512.New()
+		if i&1 != 0 {
+			c.Write(seqP)
+		} else {
+			c.Write(sumC)
+		}
+		if i%3 != 0 {
+			c.Write(seqS)
+		}
+		if i%7 != 0 {
+			c.Write(seqP)
+		}
+		if i&1 != 0 {
+			c.Write(sumC)
+		} else {
+			c.Write(seqP)
+		}
+		sumC = c.Sum(nil)
+	}
+
+	var sb strings.Builder
+	sb.WriteString("$6$")
+	if rounds != 5000 {
+		sb.WriteString("rounds=" + strconv.Itoa(rounds) + "$")
+	}
+	sb.WriteString(salt)
+	sb.WriteByte('$')
+
+	b64 := func(b2, b1, b0 byte, n int) {
+		w := uint(b2)<<16 | uint(b1)<<8 | uint(b0)
+		for ; n > 0; n-- {
+			sb.WriteByte(cryptAlphabet[w&0x3f])
+			w >>= 6
+		}
+	}
+	for i := 0; i < 21; i++ {
+		x, y, z := sumC[i], sumC[i+21], sumC[i+42]
+		switch i % 3 {
+		case 0:
+			b64(x, y, z, 4)
+		case 1:
+			b64(y, z, x, 4)
+		case 2:
+			b64(z, x, y, 4)
+		}
+	}
+	b64(0, 0, sumC[63], 2)
+
+	return sb.String()
+}
@@ -0,0 +1,120 @@
+/*
+Copyright 2022 GramLabs, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package readers
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	konjurev1beta2 "github.com/thestormforge/konjure/pkg/api/core/v1beta2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestSecretReader_Passwords(t *testing.T) {
+	length, words, separator, yes := 40, 5, " ", true
+	_, data := readSecret(t, konjurev1beta2.Secret{
+		SecretName: "test",
+		PasswordSources: []konjurev1beta2.PasswordRecipe{
+			{
+				Key:              "charset",
+				Length:           &length,
+				Symbols:          "!#",
+				ExcludeAmbiguous: &yes,
+				AllowRepeat:      &yes,
+			},
+			{
+				Key:           "passphrase",
+				Words:         &words,
+				WordSeparator: &separator,
+				WordList:      "eff-small",
+			},
+			{
+				Key: "derived",
+				Derived: []konjurev1beta2.DerivedKeyRecipe{
+					{Key: "derived.bcrypt", Algorithm: "bcrypt"},
+					{Key: "derived.sha512", Algorithm: "sha512-crypt"},
+				},
+			},
+		},
+	})
+
+	assert.Len(t, data["charset"], 40)
+	assert.False(t, strings.ContainsAny(data["charset"], ambiguousCharacters), data["charset"])
+	assert.Equal(t, 10, strings.Count(data["charset"], "!")+strings.Count(data["charset"], "#"))
+
+	assert.Len(t, strings.Split(data["passphrase"], " "), 5)
+
+	assert.NoError(t, bcrypt.CompareHashAndPassword([]byte(data["derived.bcrypt"]), []byte(data["derived"])))
+	if assert.True(t, strings.HasPrefix(data["derived.sha512"], "$6$")) {
+		salt := strings.Split(data["derived.sha512"], "$")[2]
+		assert.Equal(t, sha512Crypt(data["derived"], salt, 5000), data["derived.sha512"])
+	}
+}
+
+func TestSecretReader_SeededDerivedKeys(t *testing.T) {
+	t.Setenv("KONJURE_TEST_SEED", "not-very-secret")
+	secret := konjurev1beta2.Secret{
+		SecretName: "test",
+		SeedEnv:    "KONJURE_TEST_SEED",
+		PasswordSources: []konjurev1beta2.PasswordRecipe{
+			{
+				Key: "derived",
+				Derived: []konjurev1beta2.DerivedKeyRecipe{
+					{Key: "derived.bcrypt", Algorithm: "bcrypt"},
+					{Key: "derived.sha512", Algorithm: "sha512-crypt"},
+				},
+			},
+		},
+	}
+
+	_, first := readSecret(t, secret)
+	_, second := readSecret(t, secret)
+	assert.Equal(t, first, second)
+	assert.Len(t, first, 3)
+	assert.NoError(t, bcrypt.CompareHashAndPassword([]byte(first["derived.bcrypt"]), []byte(first["derived"])))
+}
+
+func TestSha512Crypt(t *testing.T) {
+	cases := []struct {
+		desc     string
+		password string
+		salt     string
+		rounds   int
+		expected string
+	}{
+		{
+			desc:     "default rounds",
+			password: "Hello world!",
+			salt:     "saltstring",
+			rounds:   5000,
+			expected: "$6$saltstring$svn8UoSVapNtMuq1ukKS4tPQd8iKwSMHWjl/O817G3uBnIFNjnQJuesI68u4OTLiBFdcbYEdFCoEOfaS35inz1",
+		},
+		{
+			desc:     "custom rounds",
+			password: "Hello world!",
+			salt:     "saltstringsaltstring",
+			rounds:   10000,
+			expected: "$6$rounds=10000$saltstringsaltst$OW1/O6BYHV6BcXZu8QVeXbDWra3Oeqh0sbHbbMCVNSnCM/UrjmM0Dp8vOuZeHBy/YTBmSK6H9qs/y3RnOaw5v.",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.desc, func(t *testing.T) {
+			assert.Equal(t, c.expected, sha512Crypt(c.password, c.salt, c.rounds))
+		})
+	}
+}
@@ -44,12 +44,18 @@ func New(res any) kio.Reader {
 		return &KustomizeReader{Kustomize: *res}
 	case *konjurev1beta2.Secret:
 		return &SecretReader{Secret: *res}
+	case *konjurev1beta2.ConfigMap:
+		return &ConfigMapReader{ConfigMap: *res}
 	case *konjurev1beta2.Git:
 		return &GitReader{Git: *res}
 	case *konjurev1beta2.HTTP:
 		return &HTTPReader{HTTP: *res}
 	case *konjurev1beta2.File:
 		return &FileReader{File: *res}
+	case *konjurev1beta2.Overlay:
+		return &OverlayReader{Overlay: *res}
+	case *konjurev1beta2.Replacement:
+		return &ReplacementReader{Replacement: *res}
 	}
 	return nil
 }
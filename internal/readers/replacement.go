@@ -0,0 +1,79 @@
+/*
+Copyright 2022 GramLabs, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package readers
+
+import (
+	konjurev1beta2 "github.com/thestormforge/konjure/pkg/api/core/v1beta2"
+	"github.com/thestormforge/konjure/pkg/filters"
+	"sigs.k8s.io/kustomize/kyaml/kio"
+	"sigs.k8s.io/kustomize/kyaml/yaml"
+)
+
+// ReplacementReader expands the nested resources of a replacement and copies
+// the source value into the targets.
+type ReplacementReader struct {
+	konjurev1beta2.Replacement
+	// The filter used to expand the nested resources, if nil the nested
+	// resources are not expanded beyond their Konjure resource specifications.
+	Expander kio.Filter
+}
+
+// Read expands the resources and applies the replacement.
+func (r *ReplacementReader) Read() ([]*yaml.RNode, error) {
+	n, err := konjurev1beta2.GetRNode(&konjurev1beta2.Resource{Resources: r.Resources})
+	if err != nil {
+		return nil, err
+	}
+
+	p := &filters.Pipeline{
+		Inputs: []kio.Reader{kio.ResourceNodeSlice{n}},
+	}
+
+	if r.Expander != nil {
+		p.Filters = append(p.Filters, r.Expander)
+	}
+
+	f := &filters.ReplacementFilter{
+		Source: filters.ReplacementSource{
+			ResourceMetaFilter: *resourceMetaFilter(&r.Source.Selector),
+			FieldPath:          r.Source.FieldPath,
+			Options:            replacementOptions(r.Source.Options),
+		},
+	}
+	for i := range r.Targets {
+		f.Targets = append(f.Targets, filters.ReplacementTarget{
+			ResourceMetaFilter: *resourceMetaFilter(&r.Targets[i].Selector),
+			FieldPaths:         r.Targets[i].FieldPaths,
+			Options:            replacementOptions(r.Targets[i].Options),
+		})
+	}
+	p.Filters = append(p.Filters, f)
+
+	return p.Read()
+}
+
+// replacementOptions converts the API replacement options into filter options.
+func replacementOptions(o *konjurev1beta2.ReplacementOptions) *filters.ReplacementOptions {
+	if o == nil {
+		return nil
+	}
+	return &filters.ReplacementOptions{
+		Delimiter: o.Delimiter,
+		Index:     o.Index,
+		Create:    o.Create,
+	}
+}
@@ -17,28 +17,59 @@ limitations under the License.
 package readers
 
 import (
-	"bufio"
 	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/hmac"
 	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"encoding/pem"
 	"fmt"
+	"hash"
+	"io"
+	"math/big"
+	"net"
 	"os"
-	"path"
 	"strings"
-	"unicode"
-	"unicode/utf8"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/oklog/ulid/v2"
-	"github.com/sethvargo/go-password/password"
 	konjurev1beta2 "github.com/thestormforge/konjure/pkg/api/core/v1beta2"
+	"github.com/thestormforge/konjure/pkg/filters"
+	"golang.org/x/crypto/bcrypt"
+	"golang.org/x/crypto/ssh"
 	"sigs.k8s.io/kustomize/kyaml/yaml"
 )
 
 type SecretReader struct {
 	konjurev1beta2.Secret
+	// The kubectl runtime used to fetch the existing secret when preserving values.
+	Runtime
+	// Override the default path to the kubeconfig file.
+	Kubeconfig string
+
+	seed     []byte
+	existing map[string]string
 }
 
 func (r *SecretReader) Read() ([]*yaml.RNode, error) {
+	var err error
+	if r.seed, err = r.loadSeed(); err != nil {
+		return nil, err
+	}
+	if r.existing, err = r.loadExisting(); err != nil {
+		return nil, err
+	}
+
 	// Build the basic secret node
 	n, err := yaml.FromMap(map[string]any{"apiVersion": "v1", "kind": "Secret"})
 	if err != nil {
@@ -47,8 +78,18 @@ func (r *SecretReader) Read() ([]*yaml.RNode, error) {
 	if err := n.PipeE(yaml.SetK8sName(r.SecretName)); err != nil {
 		return nil, err
 	}
-	if r.Type != "" {
-		if err := n.PipeE(yaml.SetField("type", yaml.NewStringRNode(r.Type))); err != nil {
+	if r.AppendHash {
+		if err := n.PipeE(yaml.SetAnnotation(filters.HashSuffixAnnotation, "true")); err != nil {
+			return nil, err
+		}
+	}
+	if r.Namespace != "" {
+		if err := n.PipeE(yaml.SetK8sNamespace(r.Namespace)); err != nil {
+			return nil, err
+		}
+	}
+	if t := r.secretType(); t != "" {
+		if err := n.PipeE(yaml.SetField("type", yaml.NewStringRNode(t))); err != nil {
 			return nil, err
 		}
 	}
@@ -61,6 +102,10 @@ func (r *SecretReader) Read() ([]*yaml.RNode, error) {
 		yaml.FilterFunc(r.uuids),
 		yaml.FilterFunc(r.ulids),
 		yaml.FilterFunc(r.passwords),
+		yaml.FilterFunc(r.tls),
+		yaml.FilterFunc(r.sshKey),
+		yaml.FilterFunc(r.dockerRegistries),
+		yaml.FilterFunc(r.htpasswd),
 	)); err != nil {
 		return nil, err
 	}
@@ -68,92 +113,142 @@ func (r *SecretReader) Read() ([]*yaml.RNode, error) {
 	return []*yaml.RNode{n}, nil
 }
 
-func (r *SecretReader) literals(n *yaml.RNode) (*yaml.RNode, error) {
-	if len(r.LiteralSources) == 0 {
-		return n, nil
-	}
+// loadSeed returns the configured seed for deriving random values, if any.
+func (r *SecretReader) loadSeed() ([]byte, error) {
+	switch {
+	case r.SeedFile != "":
+		data, err := os.ReadFile(r.SeedFile)
+		if err != nil {
+			return nil, err
+		}
+		return bytes.TrimSpace(data), nil
 
-	m := make(map[string]string)
-	for _, s := range r.LiteralSources {
-		items := strings.SplitN(s, "=", 2)
-		if items[0] == "" || len(items) != 2 {
-			return nil, fmt.Errorf("invalid literal, expected key=value: %s", s)
+	case r.SeedEnv != "":
+		seed, ok := os.LookupEnv(r.SeedEnv)
+		if !ok || seed == "" {
+			return nil, fmt.Errorf("seed environment variable %q is not set", r.SeedEnv)
 		}
-		m[items[0]] = strings.Trim(items[1], `"'`)
-	}
+		return []byte(seed), nil
 
-	return n, n.LoadMapIntoSecretData(m)
+	default:
+		return nil, nil
+	}
 }
 
-func (r *SecretReader) files(n *yaml.RNode) (*yaml.RNode, error) {
-	if len(r.FileSources) == 0 {
-		return n, nil
+// loadExisting returns the decoded data of the existing secret when preserving values.
+func (r *SecretReader) loadExisting() (map[string]string, error) {
+	if !r.PreserveExisting {
+		return nil, nil
 	}
 
-	m := make(map[string]string)
-	for _, s := range r.FileSources {
-		items := strings.SplitN(s, "=", 3)
-		switch len(items) {
-		case 1:
-			data, err := os.ReadFile(items[0])
+	kr := &KubernetesReader{
+		Kubernetes: konjurev1beta2.Kubernetes{
+			Namespace: r.Namespace,
+			Types:     []string{"secrets"},
+			Name:      r.SecretName,
+		},
+		Runtime:    r.Runtime,
+		Kubeconfig: r.Kubeconfig,
+	}
+	nodes, err := kr.Read()
+	if err != nil {
+		return nil, err
+	}
+
+	existing := make(map[string]string)
+	for _, node := range nodes {
+		for k, v := range node.GetDataMap() {
+			data, err := base64.StdEncoding.DecodeString(v)
 			if err != nil {
 				return nil, err
 			}
-			m[path.Base(items[0])] = string(data)
+			existing[k] = string(data)
+		}
+	}
+	return existing, nil
+}
 
-		case 2:
-			if items[0] == "" || items[1] == "" {
-				return nil, fmt.Errorf("key or file path is missing: %s", s)
-			}
+// random returns the source of randomness used to generate the value of the supplied key.
+func (r *SecretReader) random(key string) io.Reader {
+	if r.seed == nil {
+		return rand.Reader
+	}
+	return newSeededReader(r.seed, r.SecretName, key)
+}
 
-			data, err := os.ReadFile(items[1])
-			if err != nil {
-				return nil, err
-			}
-			m[items[0]] = string(data)
+// preserve replaces generated values with the values from the existing secret.
+func (r *SecretReader) preserve(m map[string]string) map[string]string {
+	for k := range m {
+		if v, ok := r.existing[k]; ok {
+			m[k] = v
+		}
+	}
+	return m
+}
 
-		default:
-			return nil, fmt.Errorf("key names or file paths cannot contain '='")
+// preserved returns the existing values for the supplied keys. Generators producing
+// values which depend on each other (e.g. a certificate and private key) should
+// skip generation entirely if anything is preserved.
+func (r *SecretReader) preserved(keys ...string) map[string]string {
+	m := make(map[string]string)
+	for _, k := range keys {
+		if v, ok := r.existing[k]; ok {
+			m[k] = v
 		}
 	}
+	return m
+}
 
-	return n, n.LoadMapIntoSecretData(m)
+// secretType returns the explicit secret type or one implied by the generated data.
+func (r *SecretReader) secretType() string {
+	switch {
+	case r.Type != "":
+		return r.Type
+	case r.TLSSource != nil:
+		return "kubernetes.io/tls"
+	case r.SSHKeySource != nil:
+		return "kubernetes.io/ssh-auth"
+	case len(r.DockerRegistrySources) > 0:
+		return "kubernetes.io/dockerconfigjson"
+	default:
+		return ""
+	}
 }
 
-func (r *SecretReader) envs(n *yaml.RNode) (*yaml.RNode, error) {
-	if len(r.EnvSources) == 0 {
+func (r *SecretReader) literals(n *yaml.RNode) (*yaml.RNode, error) {
+	if len(r.LiteralSources) == 0 {
 		return n, nil
 	}
 
-	m := make(map[string]string)
-	for _, s := range r.EnvSources {
-		data, err := os.ReadFile(s)
-		if err != nil {
-			return nil, err
-		}
+	m, err := literalSources(r.LiteralSources)
+	if err != nil {
+		return nil, err
+	}
 
-		scanner := bufio.NewScanner(bytes.NewReader(bytes.TrimPrefix(data, []byte{0xEF, 0xBB, 0xBF})))
-		currentLine := 0
-		for scanner.Scan() {
-			currentLine++
+	return n, n.LoadMapIntoSecretData(m)
+}
 
-			line := scanner.Bytes()
-			if !utf8.Valid(line) {
-				return nil, fmt.Errorf("line %d has invalid UTF-8 bytes: %s", currentLine, string(line))
-			}
+func (r *SecretReader) files(n *yaml.RNode) (*yaml.RNode, error) {
+	if len(r.FileSources) == 0 {
+		return n, nil
+	}
 
-			line = bytes.TrimLeftFunc(line, unicode.IsSpace)
-			if len(line) == 0 || line[0] == '#' {
-				continue
-			}
+	m, err := fileSources(r.FileSources)
+	if err != nil {
+		return nil, err
+	}
 
-			items := strings.SplitN(string(line), "=", 2)
-			if len(items) == 2 {
-				m[items[0]] = items[1]
-			} else {
-				m[items[0]] = os.Getenv(items[0])
-			}
-		}
+	return n, n.LoadMapIntoSecretData(m)
+}
+
+func (r *SecretReader) envs(n *yaml.RNode) (*yaml.RNode, error) {
+	if len(r.EnvSources) == 0 {
+		return n, nil
+	}
+
+	m, err := envSources(r.EnvSources)
+	if err != nil {
+		return nil, err
 	}
 
 	return n, n.LoadMapIntoSecretData(m)
@@ -166,14 +261,14 @@ func (r *SecretReader) uuids(n *yaml.RNode) (*yaml.RNode, error) {
 
 	m := make(map[string]string)
 	for _, s := range r.UUIDSources {
-		v, err := uuid.NewRandom()
+		v, err := uuid.NewRandomFromReader(r.random(s))
 		if err != nil {
 			return nil, err
 		}
 		m[s] = v.String()
 	}
 
-	return n, n.LoadMapIntoSecretData(m)
+	return n, n.LoadMapIntoSecretData(r.preserve(m))
 }
 
 func (r *SecretReader) ulids(n *yaml.RNode) (*yaml.RNode, error) {
@@ -183,65 +278,346 @@ func (r *SecretReader) ulids(n *yaml.RNode) (*yaml.RNode, error) {
 
 	m := make(map[string]string)
 	for _, s := range r.ULIDSources {
-		v, err := ulid.New(ulid.Now(), rand.Reader)
+		var v ulid.ULID
+		var err error
+		if r.seed != nil {
+			// Derive the entire ULID (including the timestamp) so it is stable
+			_, err = io.ReadFull(r.random(s), v[:])
+		} else {
+			v, err = ulid.New(ulid.Now(), rand.Reader)
+		}
 		if err != nil {
 			return nil, err
 		}
 		m[s] = v.String()
 	}
 
-	return n, n.LoadMapIntoSecretData(m)
+	return n, n.LoadMapIntoSecretData(r.preserve(m))
 }
 
-func (r *SecretReader) passwords(n *yaml.RNode) (*yaml.RNode, error) {
-	if len(r.PasswordSources) == 0 {
+func (r *SecretReader) tls(n *yaml.RNode) (*yaml.RNode, error) {
+	if r.TLSSource == nil {
 		return n, nil
 	}
+	if m := r.preserved("tls.crt", "tls.key", "ca.crt"); len(m) > 0 {
+		return n, n.LoadMapIntoSecretData(m)
+	}
+	if r.seed != nil {
+		// Certificates depend on the current time so they cannot be reproduced
+		return nil, fmt.Errorf("TLS certificates cannot be derived from a seed, preserve the existing secret instead")
+	}
+
+	validFor := 365 * 24 * time.Hour
+	if r.TLSSource.ValidFor != "" {
+		var err error
+		if validFor, err = time.ParseDuration(r.TLSSource.ValidFor); err != nil {
+			return nil, err
+		}
+	}
+	notBefore := time.Now().Truncate(time.Hour)
+	notAfter := notBefore.Add(validFor)
+
+	caCommonName := r.TLSSource.CACommonName
+	if caCommonName == "" {
+		caCommonName = r.TLSSource.CommonName + " CA"
+	}
 
-	gen, err := password.NewGenerator(r.PasswordOptions)
+	// Generate the self-signed certificate authority
+	caKey, err := generateKey(r.TLSSource.KeyAlgorithm, "ecdsa")
+	if err != nil {
+		return nil, err
+	}
+	caTemplate := &x509.Certificate{
+		Subject:               pkix.Name{CommonName: caCommonName},
+		NotBefore:             notBefore,
+		NotAfter:              notAfter,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	caCert, err := createCertificate(caTemplate, caTemplate, caKey, caKey)
 	if err != nil {
 		return nil, err
 	}
 
+	// Generate the leaf certificate signed by the certificate authority
+	key, err := generateKey(r.TLSSource.KeyAlgorithm, "ecdsa")
+	if err != nil {
+		return nil, err
+	}
+	template := &x509.Certificate{
+		Subject:               pkix.Name{CommonName: r.TLSSource.CommonName},
+		NotBefore:             notBefore,
+		NotAfter:              notAfter,
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+	}
+	for _, h := range append([]string{r.TLSSource.CommonName}, r.TLSSource.Hosts...) {
+		if ip := net.ParseIP(h); ip != nil {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		} else if h != "" {
+			template.DNSNames = append(template.DNSNames, h)
+		}
+	}
+	cert, err := createCertificate(template, caCert, key, caKey)
+	if err != nil {
+		return nil, err
+	}
+
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return n, n.LoadMapIntoSecretData(map[string]string{
+		"tls.crt": string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})),
+		"tls.key": string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER})),
+		"ca.crt":  string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: caCert.Raw})),
+	})
+}
+
+func (r *SecretReader) sshKey(n *yaml.RNode) (*yaml.RNode, error) {
+	if r.SSHKeySource == nil {
+		return n, nil
+	}
+	if m := r.preserved("ssh-privatekey", "ssh-publickey"); len(m) > 0 {
+		return n, n.LoadMapIntoSecretData(m)
+	}
+
+	var key crypto.Signer
+	var err error
+	if r.seed != nil {
+		key, err = deriveKey(r.SSHKeySource.KeyAlgorithm, "ed25519", r.random("ssh-privatekey"))
+	} else {
+		key, err = generateKey(r.SSHKeySource.KeyAlgorithm, "ed25519")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	privateKey, err := ssh.MarshalPrivateKey(key, r.SSHKeySource.Comment)
+	if err != nil {
+		return nil, err
+	}
+	if r.seed != nil {
+		if err := deriveCheck(privateKey, r.random("ssh-privatekey-check")); err != nil {
+			return nil, err
+		}
+	}
+
+	publicKey, err := ssh.NewPublicKey(key.Public())
+	if err != nil {
+		return nil, err
+	}
+	authorizedKey := bytes.TrimSpace(ssh.MarshalAuthorizedKey(publicKey))
+	if r.SSHKeySource.Comment != "" {
+		authorizedKey = append(authorizedKey, ' ')
+		authorizedKey = append(authorizedKey, r.SSHKeySource.Comment...)
+	}
+
+	return n, n.LoadMapIntoSecretData(map[string]string{
+		"ssh-privatekey": string(pem.EncodeToMemory(privateKey)),
+		"ssh-publickey":  string(authorizedKey) + "\n",
+	})
+}
+
+func (r *SecretReader) dockerRegistries(n *yaml.RNode) (*yaml.RNode, error) {
+	if len(r.DockerRegistrySources) == 0 {
+		return n, nil
+	}
+
+	type dockerConfigEntry struct {
+		Username string `json:"username,omitempty"`
+		Password string `json:"password,omitempty"`
+		Email    string `json:"email,omitempty"`
+		Auth     string `json:"auth,omitempty"`
+	}
+
+	auths := make(map[string]dockerConfigEntry, len(r.DockerRegistrySources))
+	for _, s := range r.DockerRegistrySources {
+		server := s.Server
+		if server == "" {
+			server = "https://index.docker.io/v1/"
+		}
+		if s.Username == "" {
+			return nil, fmt.Errorf("username is required for registry %q", server)
+		}
+
+		auths[server] = dockerConfigEntry{
+			Username: s.Username,
+			Password: s.Password,
+			Email:    s.Email,
+			Auth:     base64.StdEncoding.EncodeToString([]byte(s.Username + ":" + s.Password)),
+		}
+	}
+
+	data, err := json.Marshal(map[string]any{"auths": auths})
+	if err != nil {
+		return nil, err
+	}
+
+	return n, n.LoadMapIntoSecretData(map[string]string{".dockerconfigjson": string(data)})
+}
+
+func (r *SecretReader) htpasswd(n *yaml.RNode) (*yaml.RNode, error) {
+	if len(r.HTPasswdSources) == 0 {
+		return n, nil
+	}
+
 	m := make(map[string]string)
-	for i := range r.PasswordSources {
-		pwd, err := gen.Generate(passwordArgs(&r.PasswordSources[i]))
+	for _, s := range r.HTPasswdSources {
+		if s.Username == "" || strings.ContainsRune(s.Username, ':') {
+			return nil, fmt.Errorf("invalid htpasswd username: %q", s.Username)
+		}
+
+		key := s.Key
+		if key == "" {
+			key = "auth"
+		}
+
+		hash, err := bcryptHash([]byte(s.Password), bcrypt.DefaultCost, r.random(key+":"+s.Username))
 		if err != nil {
 			return nil, err
 		}
-		m[r.PasswordSources[i].Key] = pwd
+
+		m[key] += s.Username + ":" + hash + "\n"
 	}
 
-	return n, n.LoadMapIntoSecretData(m)
+	return n, n.LoadMapIntoSecretData(r.preserve(m))
+}
+
+// generateKey generates a new private key using the named algorithm.
+func generateKey(algorithm, defaultAlgorithm string) (crypto.Signer, error) {
+	if algorithm == "" {
+		algorithm = defaultAlgorithm
+	}
+
+	switch strings.ToLower(algorithm) {
+	case "ecdsa":
+		return ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case "rsa":
+		return rsa.GenerateKey(rand.Reader, 3072)
+	case "ed25519":
+		_, key, err := ed25519.GenerateKey(rand.Reader)
+		return key, err
+	default:
+		return nil, fmt.Errorf("unknown key algorithm: %s", algorithm)
+	}
+}
+
+// deriveKey derives a private key using the named algorithm from the supplied source of
+// randomness. Only algorithms whose keys can be reproduced from the same source are supported.
+func deriveKey(algorithm, defaultAlgorithm string, rnd io.Reader) (crypto.Signer, error) {
+	if algorithm == "" {
+		algorithm = defaultAlgorithm
+	}
+
+	switch strings.ToLower(algorithm) {
+	case "ecdsa":
+		// Retry until the bytes represent a valid scalar (this is very unlikely to loop)
+		for {
+			d := make([]byte, 32)
+			if _, err := io.ReadFull(rnd, d); err != nil {
+				return nil, err
+			}
+			if key, err := ecdsa.ParseRawPrivateKey(elliptic.P256(), d); err == nil {
+				return key, nil
+			}
+		}
+	case "ed25519":
+		seed := make([]byte, ed25519.SeedSize)
+		if _, err := io.ReadFull(rnd, seed); err != nil {
+			return nil, err
+		}
+		return ed25519.NewKeyFromSeed(seed), nil
+	case "rsa":
+		return nil, fmt.Errorf("rsa keys cannot be derived from a seed")
+	default:
+		return nil, fmt.Errorf("unknown key algorithm: %s", algorithm)
+	}
 }
 
-func passwordArgs(s *konjurev1beta2.PasswordRecipe) (length int, numDigits int, numSymbols int, noUpper bool, allowRepeat bool) {
-	if s.Length != nil {
-		length = *s.Length
+// deriveCheck replaces the random "check" value of an OpenSSH private key with
+// one read from the supplied source of randomness.
+func deriveCheck(block *pem.Block, rnd io.Reader) error {
+	magic := []byte("openssh-key-v1\x00")
+	if !bytes.HasPrefix(block.Bytes, magic) {
+		return fmt.Errorf("invalid OpenSSH private key")
 	}
-	if s.NumDigits != nil {
-		numDigits = *s.NumDigits
+
+	var w struct {
+		CipherName   string
+		KdfName      string
+		KdfOpts      string
+		NumKeys      uint32
+		PubKey       []byte
+		PrivKeyBlock []byte
 	}
-	if s.NumSymbols != nil {
-		numSymbols = *s.NumSymbols
+	if err := ssh.Unmarshal(block.Bytes[len(magic):], &w); err != nil {
+		return err
 	}
-	if s.NoUpper != nil {
-		noUpper = *s.NoUpper
+	if len(w.PrivKeyBlock) < 8 {
+		return fmt.Errorf("invalid OpenSSH private key")
 	}
-	if s.AllowRepeat != nil {
-		allowRepeat = *s.AllowRepeat
+
+	// The two check values must match for the key to be valid
+	if _, err := io.ReadFull(rnd, w.PrivKeyBlock[0:4]); err != nil {
+		return err
 	}
+	copy(w.PrivKeyBlock[4:8], w.PrivKeyBlock[0:4])
 
-	// TODO Is this reasonable default logic?
-	if length == 0 {
-		length = 64
+	block.Bytes = append(magic, ssh.Marshal(&w)...)
+	return nil
+}
+
+// createCertificate creates a new certificate with a random serial number.
+func createCertificate(template, parent *x509.Certificate, key, parentKey crypto.Signer) (*x509.Certificate, error) {
+	var err error
+	template.SerialNumber, err = rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, err
 	}
-	if numDigits == 0 && numSymbols+10 <= length {
-		numDigits = 10
+
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, key.Public(), parentKey)
+	if err != nil {
+		return nil, err
 	}
-	if numSymbols == 0 && numDigits+10 <= length {
-		numSymbols = 10
+
+	return x509.ParseCertificate(der)
+}
+
+// seededReader is a deterministic stream of bytes derived from a seed using
+// HMAC-SHA256 in counter mode.
+type seededReader struct {
+	mac     hash.Hash
+	info    []byte
+	counter uint64
+	buf     []byte
+}
+
+// newSeededReader returns a reader that derives bytes from the seed and context.
+func newSeededReader(seed []byte, context ...string) io.Reader {
+	return &seededReader{
+		mac:  hmac.New(sha256.New, seed),
+		info: []byte(strings.Join(context, "\x00")),
 	}
+}
 
-	return length, numDigits, numSymbols, noUpper, allowRepeat
+// Read fills the supplied buffer with derived bytes.
+func (r *seededReader) Read(p []byte) (int, error) {
+	n := 0
+	for n < len(p) {
+		if len(r.buf) == 0 {
+			r.mac.Reset()
+			r.mac.Write(r.info)
+			r.mac.Write(binary.BigEndian.AppendUint64(nil, r.counter))
+			r.buf = r.mac.Sum(nil)
+			r.counter++
+		}
+		c := copy(p[n:], r.buf)
+		r.buf = r.buf[c:]
+		n += c
+	}
+	return n, nil
 }
@@ -0,0 +1,280 @@
+/*
+Copyright 2022 GramLabs, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package readers
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	konjurev1beta2 "github.com/thestormforge/konjure/pkg/api/core/v1beta2"
+	"golang.org/x/crypto/bcrypt"
+	"golang.org/x/crypto/ssh"
+)
+
+func TestSecretReader_TLS(t *testing.T) {
+	for _, alg := range []string{"", "ecdsa", "rsa", "ed25519"} {
+		t.Run("algorithm "+alg, func(t *testing.T) {
+			secretType, data := readSecret(t, konjurev1beta2.Secret{
+				SecretName: "test",
+				TLSSource: &konjurev1beta2.TLSRecipe{
+					CommonName:   "example.com",
+					Hosts:        []string{"www.example.com", "127.0.0.1"},
+					KeyAlgorithm: alg,
+				},
+			})
+			assert.Equal(t, "kubernetes.io/tls", secretType)
+
+			// The key pair must be usable
+			_, err := tls.X509KeyPair([]byte(data["tls.crt"]), []byte(data["tls.key"]))
+			require.NoError(t, err)
+
+			// The leaf certificate must verify using the CA
+			roots := x509.NewCertPool()
+			require.True(t, roots.AppendCertsFromPEM([]byte(data["ca.crt"])))
+			cert := parseCertificate(t, data["tls.crt"])
+			for _, host := range []string{"example.com", "www.example.com", "127.0.0.1"} {
+				_, err := cert.Verify(x509.VerifyOptions{DNSName: host, Roots: roots})
+				assert.NoError(t, err, host)
+			}
+
+			ca := parseCertificate(t, data["ca.crt"])
+			assert.True(t, ca.IsCA)
+			assert.Equal(t, "example.com CA", ca.Subject.CommonName)
+		})
+	}
+}
+
+func TestSecretReader_SSHKey(t *testing.T) {
+	for _, alg := range []string{"", "ed25519", "rsa", "ecdsa"} {
+		t.Run("algorithm "+alg, func(t *testing.T) {
+			secretType, data := readSecret(t, konjurev1beta2.Secret{
+				SecretName:   "test",
+				SSHKeySource: &konjurev1beta2.SSHKeyRecipe{KeyAlgorithm: alg, Comment: "test@example.com"},
+			})
+			assert.Equal(t, "kubernetes.io/ssh-auth", secretType)
+
+			signer, err := ssh.ParsePrivateKey([]byte(data["ssh-privatekey"]))
+			require.NoError(t, err)
+
+			publicKey, comment, _, _, err := ssh.ParseAuthorizedKey([]byte(data["ssh-publickey"]))
+			require.NoError(t, err)
+			assert.Equal(t, "test@example.com", comment)
+			assert.Equal(t, signer.PublicKey().Marshal(), publicKey.Marshal())
+		})
+	}
+}
+
+func TestSecretReader_DockerRegistries(t *testing.T) {
+	secretType, data := readSecret(t, konjurev1beta2.Secret{
+		SecretName: "test",
+		DockerRegistrySources: []konjurev1beta2.DockerRegistryRecipe{
+			{Server: "registry.example.com", Username: "user", Password: "secret", Email: "user@example.com"},
+			{Username: "hubuser", Password: "hubsecret"},
+		},
+	})
+	assert.Equal(t, "kubernetes.io/dockerconfigjson", secretType)
+
+	config := struct {
+		Auths map[string]struct {
+			Username string `json:"username"`
+			Password string `json:"password"`
+			Email    string `json:"email"`
+			Auth     string `json:"auth"`
+		} `json:"auths"`
+	}{}
+	require.NoError(t, json.Unmarshal([]byte(data[".dockerconfigjson"]), &config))
+	if assert.Contains(t, config.Auths, "registry.example.com") {
+		entry := config.Auths["registry.example.com"]
+		assert.Equal(t, "user", entry.Username)
+		assert.Equal(t, "secret", entry.Password)
+		assert.Equal(t, "user@example.com", entry.Email)
+		assert.Equal(t, base64.StdEncoding.EncodeToString([]byte("user:secret")), entry.Auth)
+	}
+	if assert.Contains(t, config.Auths, "https://index.docker.io/v1/") {
+		assert.Equal(t, "hubuser", config.Auths["https://index.docker.io/v1/"].Username)
+	}
+}
+
+func TestSecretReader_HTPasswd(t *testing.T) {
+	secretType, data := readSecret(t, konjurev1beta2.Secret{
+		SecretName: "test",
+		HTPasswdSources: []konjurev1beta2.HTPasswdRecipe{
+			{Username: "alice", Password: "wonderland"},
+			{Username: "bob", Password: "builder"},
+			{Key: "admin", Username: "root", Password: "toor"},
+		},
+	})
+	assert.Empty(t, secretType)
+
+	passwords := map[string]string{"alice": "wonderland", "bob": "builder", "root": "toor"}
+	lines := strings.Split(strings.TrimSpace(data["auth"]), "\n")
+	lines = append(lines, strings.TrimSpace(data["admin"]))
+	if assert.Len(t, lines, 3) {
+		for _, line := range lines {
+			username, hash, ok := strings.Cut(line, ":")
+			if assert.True(t, ok, line) {
+				assert.NoError(t, bcrypt.CompareHashAndPassword([]byte(hash), []byte(passwords[username])), username)
+			}
+		}
+	}
+}
+
+func TestSecretReader_Seed(t *testing.T) {
+	t.Setenv("KONJURE_TEST_SEED", "not-very-secret")
+	secret := konjurev1beta2.Secret{
+		SecretName:      "test",
+		SeedEnv:         "KONJURE_TEST_SEED",
+		UUIDSources:     []string{"uuid1", "uuid2"},
+		ULIDSources:     []string{"ulid"},
+		PasswordSources: []konjurev1beta2.PasswordRecipe{{Key: "password"}},
+	}
+
+	_, first := readSecret(t, secret)
+	_, second := readSecret(t, secret)
+	assert.Equal(t, first, second)
+	assert.NotEqual(t, first["uuid1"], first["uuid2"])
+	assert.Len(t, first["password"], 64)
+
+	// Changing the name must change the values
+	secret.SecretName = "other"
+	_, other := readSecret(t, secret)
+	assert.NotEqual(t, first["uuid1"], other["uuid1"])
+	assert.NotEqual(t, first["password"], other["password"])
+
+	// Missing seeds must fail instead of silently generating random values
+	secret.SeedEnv = "KONJURE_TEST_MISSING_SEED"
+	_, err := (&SecretReader{Secret: secret}).Read()
+	assert.Error(t, err)
+}
+
+func TestSecretReader_SeedCredentials(t *testing.T) {
+	t.Setenv("KONJURE_TEST_SEED", "not-very-secret")
+	for _, alg := range []string{"ed25519", "ecdsa"} {
+		t.Run("algorithm "+alg, func(t *testing.T) {
+			secret := konjurev1beta2.Secret{
+				SecretName:   "test",
+				SeedEnv:      "KONJURE_TEST_SEED",
+				SSHKeySource: &konjurev1beta2.SSHKeyRecipe{KeyAlgorithm: alg, Comment: "test@example.com"},
+				HTPasswdSources: []konjurev1beta2.HTPasswdRecipe{
+					{Username: "alice", Password: "wonderland"},
+					{Username: "bob", Password: "builder"},
+				},
+			}
+
+			_, first := readSecret(t, secret)
+			_, second := readSecret(t, secret)
+			assert.Equal(t, first, second)
+
+			signer, err := ssh.ParsePrivateKey([]byte(first["ssh-privatekey"]))
+			require.NoError(t, err)
+			publicKey, _, _, _, err := ssh.ParseAuthorizedKey([]byte(first["ssh-publickey"]))
+			require.NoError(t, err)
+			assert.Equal(t, signer.PublicKey().Marshal(), publicKey.Marshal())
+
+			lines := strings.Split(strings.TrimSpace(first["auth"]), "\n")
+			if assert.Len(t, lines, 2) {
+				assert.NoError(t, bcrypt.CompareHashAndPassword([]byte(strings.TrimPrefix(lines[0], "alice:")), []byte("wonderland")))
+				assert.NoError(t, bcrypt.CompareHashAndPassword([]byte(strings.TrimPrefix(lines[1], "bob:")), []byte("builder")))
+				assert.NotEqual(t, lines[0][len("alice:")+7:][:22], lines[1][len("bob:")+7:][:22], "salts must differ")
+			}
+		})
+	}
+
+	// Credentials which cannot be reproduced must fail instead of rotating
+	for desc, secret := range map[string]konjurev1beta2.Secret{
+		"tls": {TLSSource: &konjurev1beta2.TLSRecipe{CommonName: "example.com"}},
+		"rsa": {SSHKeySource: &konjurev1beta2.SSHKeyRecipe{KeyAlgorithm: "rsa"}},
+	} {
+		t.Run(desc, func(t *testing.T) {
+			secret.SecretName, secret.SeedEnv = "test", "KONJURE_TEST_SEED"
+			_, err := (&SecretReader{Secret: secret}).Read()
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestSecretReader_PreserveExisting(t *testing.T) {
+	existing := `apiVersion: v1
+kind: Secret
+metadata:
+  name: test
+  namespace: default
+data:
+  password: ` + base64.StdEncoding.EncodeToString([]byte("existing-password")) + `
+  tls.crt: ` + base64.StdEncoding.EncodeToString([]byte("existing-certificate")) + `
+`
+
+	var args []string
+	r := &SecretReader{
+		Secret: konjurev1beta2.Secret{
+			SecretName:       "test",
+			Namespace:        "default",
+			PreserveExisting: true,
+			UUIDSources:      []string{"uuid"},
+			PasswordSources:  []konjurev1beta2.PasswordRecipe{{Key: "password"}},
+			TLSSource:        &konjurev1beta2.TLSRecipe{CommonName: "example.com"},
+		},
+		Runtime: Runtime{Executor: func(cmd *exec.Cmd) ([]byte, error) {
+			args = cmd.Args
+			return []byte(existing), nil
+		}},
+	}
+	nodes, err := r.Read()
+	require.NoError(t, err)
+	require.Len(t, nodes, 1)
+	assert.Equal(t, "default", nodes[0].GetNamespace())
+	assert.Subset(t, args, []string{"get", "secrets", "test", "--namespace", "default"})
+
+	data := nodes[0].GetDataMap()
+	assert.Equal(t, base64.StdEncoding.EncodeToString([]byte("existing-password")), data["password"])
+	assert.Equal(t, base64.StdEncoding.EncodeToString([]byte("existing-certificate")), data["tls.crt"])
+	assert.NotContains(t, data, "tls.key")
+	assert.Contains(t, data, "uuid")
+}
+
+// readSecret returns the type and decoded data of the secret generated from the supplied configuration.
+func readSecret(t *testing.T, secret konjurev1beta2.Secret) (string, map[string]string) {
+	nodes, err := (&SecretReader{Secret: secret}).Read()
+	require.NoError(t, err)
+	require.Len(t, nodes, 1)
+
+	secretType, _ := nodes[0].GetString("type")
+	data := nodes[0].GetDataMap()
+	for k, v := range data {
+		decoded, err := base64.StdEncoding.DecodeString(v)
+		require.NoError(t, err)
+		data[k] = string(decoded)
+	}
+	return secretType, data
+}
+
+// parseCertificate parses a single PEM encoded certificate.
+func parseCertificate(t *testing.T, data string) *x509.Certificate {
+	block, _ := pem.Decode([]byte(data))
+	require.NotNil(t, block)
+	cert, err := x509.ParseCertificate(block.Bytes)
+	require.NoError(t, err)
+	return cert
+}
@@ -0,0 +1,108 @@
+/*
+Copyright 2022 GramLabs, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package readers
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// literalSources parses a list of `key=value` pairs.
+func literalSources(sources []string) (map[string]string, error) {
+	m := make(map[string]string)
+	for _, s := range sources {
+		items := strings.SplitN(s, "=", 2)
+		if items[0] == "" || len(items) != 2 {
+			return nil, fmt.Errorf("invalid literal, expected key=value: %s", s)
+		}
+		m[items[0]] = strings.Trim(items[1], `"'`)
+	}
+	return m, nil
+}
+
+// fileSources reads a list of file names, directory names or `key=filename` pairs.
+// Directories include every regular file directly within the directory.
+func fileSources(sources []string) (map[string]string, error) {
+	m := make(map[string]string)
+	for _, s := range sources {
+		items := strings.SplitN(s, "=", 3)
+		switch len(items) {
+		case 1:
+			info, err := os.Stat(items[0])
+			if err != nil {
+				return nil, err
+			}
+			if !info.IsDir() {
+				data, err := os.ReadFile(items[0])
+				if err != nil {
+					return nil, err
+				}
+				m[filepath.Base(items[0])] = string(data)
+				continue
+			}
+
+			entries, err := os.ReadDir(items[0])
+			if err != nil {
+				return nil, err
+			}
+			for _, e := range entries {
+				if !e.Type().IsRegular() {
+					continue
+				}
+				data, err := os.ReadFile(filepath.Join(items[0], e.Name()))
+				if err != nil {
+					return nil, err
+				}
+				m[e.Name()] = string(data)
+			}
+
+		case 2:
+			if items[0] == "" || items[1] == "" {
+				return nil, fmt.Errorf("key or file path is missing: %s", s)
+			}
+
+			data, err := os.ReadFile(items[1])
+			if err != nil {
+				return nil, err
+			}
+			m[items[0]] = string(data)
+
+		default:
+			return nil, fmt.Errorf("key names or file paths cannot contain '='")
+		}
+	}
+	return m, nil
+}
+
+// envSources reads a list of .env files (files containing `key=value` pairs). Values may reference keys defined
+// earlier in the same (or a previous) file as well as environment variables.
+func envSources(sources []string) (map[string]string, error) {
+	m := make(map[string]string)
+	for _, s := range sources {
+		data, err := os.ReadFile(s)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := parseEnv(data, m, os.LookupEnv); err != nil {
+			return nil, fmt.Errorf("invalid env file %s: %w", s, err)
+		}
+	}
+	return m, nil
+}
@@ -0,0 +1,127 @@
+/*
+Copyright 2022 GramLabs, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sops
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"filippo.io/age"
+	"filippo.io/age/armor"
+)
+
+// Identity is an age identity used to decrypt SOPS data keys.
+type Identity = age.Identity
+
+// loadIdentities returns the locally available age identities using the same
+// conventions as SOPS: the `SOPS_AGE_KEY` and `SOPS_AGE_KEY_FILE` environment
+// variables followed by the `sops/age/keys.txt` file in the user configuration
+// directory.
+func loadIdentities() ([]Identity, error) {
+	var identities []Identity
+
+	if key, ok := os.LookupEnv("SOPS_AGE_KEY"); ok {
+		ids, err := age.ParseIdentities(strings.NewReader(key))
+		if err != nil {
+			return nil, fmt.Errorf("invalid SOPS_AGE_KEY: %w", err)
+		}
+		identities = append(identities, ids...)
+	}
+
+	var keyFiles []string
+	if keyFile, ok := os.LookupEnv("SOPS_AGE_KEY_FILE"); ok {
+		keyFiles = append(keyFiles, keyFile)
+	}
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		keyFiles = append(keyFiles, filepath.Join(dir, "sops", "age", "keys.txt"))
+	} else if dir, err := os.UserConfigDir(); err == nil {
+		keyFiles = append(keyFiles, filepath.Join(dir, "sops", "age", "keys.txt"))
+	}
+
+	for _, keyFile := range keyFiles {
+		data, err := os.ReadFile(keyFile)
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+
+		ids, err := age.ParseIdentities(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("invalid age key file %s: %w", keyFile, err)
+		}
+		identities = append(identities, ids...)
+	}
+
+	return identities, nil
+}
+
+// encryptDataKey encrypts the SOPS data key for each of the supplied recipients.
+func encryptDataKey(dataKey []byte, recipients []string) ([]ageKey, error) {
+	if len(recipients) == 0 {
+		return nil, errors.New("at least one age recipient is required")
+	}
+
+	result := make([]ageKey, 0, len(recipients))
+	for _, recipient := range recipients {
+		r, err := age.ParseX25519Recipient(recipient)
+		if err != nil {
+			return nil, err
+		}
+
+		var buf bytes.Buffer
+		aw := armor.NewWriter(&buf)
+		w, err := age.Encrypt(aw, r)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := w.Write(dataKey); err != nil {
+			return nil, err
+		}
+		if err := w.Close(); err != nil {
+			return nil, err
+		}
+		if err := aw.Close(); err != nil {
+			return nil, err
+		}
+
+		result = append(result, ageKey{Recipient: recipient, Enc: buf.String()})
+	}
+	return result, nil
+}
+
+// decryptDataKey attempts to decrypt the SOPS data key using the supplied identities.
+func decryptDataKey(keys []ageKey, identities []Identity) ([]byte, error) {
+	if len(identities) == 0 {
+		return nil, errors.New("no age identities available to decrypt SOPS data key")
+	}
+
+	for _, key := range keys {
+		r, err := age.Decrypt(armor.NewReader(strings.NewReader(key.Enc)), identities...)
+		if err != nil {
+			continue
+		}
+		return io.ReadAll(r)
+	}
+
+	return nil, errors.New("failed to decrypt SOPS data key with the available age identities")
+}
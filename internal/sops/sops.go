@@ -0,0 +1,543 @@
+/*
+Copyright 2022 GramLabs, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package sops implements the subset of the SOPS file format needed to decrypt
+// and encrypt Kubernetes manifests using age keys. Documents produced by this
+// package can be decrypted by the `sops` CLI (and vice versa).
+package sops
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha512"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"hash"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"sigs.k8s.io/kustomize/kyaml/kio"
+	"sigs.k8s.io/kustomize/kyaml/yaml"
+)
+
+const (
+	// MetadataField is the name of the top-level field containing the SOPS metadata.
+	MetadataField = "sops"
+	// Version is the SOPS format version recorded on encrypted documents.
+	Version = "3.9.0"
+	// DefaultUnencryptedSuffix is the suffix SOPS uses to leave values unencrypted.
+	DefaultUnencryptedSuffix = "_unencrypted"
+	// KubernetesSecretRegex is the conventional regular expression for encrypting only the data of Kubernetes secrets.
+	KubernetesSecretRegex = `^(data|stringData)$`
+
+	// SOPS uses a non-standard nonce size with AES-GCM.
+	nonceSize = 32
+)
+
+// macOnlyEncryptedInitialization is used to initialize the MAC when only encrypted values are included.
+var macOnlyEncryptedInitialization = []byte{0x8a, 0x3f, 0xd2, 0xad, 0x54, 0xce, 0x66, 0x52, 0x7b, 0x10, 0x34, 0xf3, 0xd1, 0x47, 0xbe, 0xb, 0xb, 0x97, 0x5b, 0x3b, 0xf4, 0x4f, 0x72, 0xc6, 0xfd, 0xad, 0xec, 0x81, 0x76, 0xf2, 0x7d, 0x69}
+
+// encPattern matches the values encrypted by SOPS.
+var encPattern = regexp.MustCompile(`^ENC\[AES256_GCM,data:(.*),iv:(.+),tag:(.+),type:(.+)\]$`)
+
+// metadata is the SOPS metadata stored on an encrypted document. Fields are
+// declared in the same (sorted) order SOPS uses when writing the metadata.
+type metadata struct {
+	Age               []ageKey `yaml:"age,omitempty"`
+	EncryptedRegex    string   `yaml:"encrypted_regex,omitempty"`
+	EncryptedSuffix   string   `yaml:"encrypted_suffix,omitempty"`
+	LastModified      string   `yaml:"lastmodified"`
+	MAC               string   `yaml:"mac"`
+	MACOnlyEncrypted  bool     `yaml:"mac_only_encrypted,omitempty"`
+	UnencryptedRegex  string   `yaml:"unencrypted_regex,omitempty"`
+	UnencryptedSuffix string   `yaml:"unencrypted_suffix,omitempty"`
+	Version           string   `yaml:"version"`
+}
+
+// ageKey is a data key encrypted for an age recipient.
+type ageKey struct {
+	Enc       string `yaml:"enc"`
+	Recipient string `yaml:"recipient"`
+}
+
+// shouldBeEncrypted determines if the value at the supplied path is encrypted.
+func (md *metadata) shouldBeEncrypted(path []string) (bool, error) {
+	encrypted := true
+	if md.UnencryptedSuffix != "" && anyPath(path, func(p string) bool { return strings.HasSuffix(p, md.UnencryptedSuffix) }) {
+		encrypted = false
+	}
+	if md.EncryptedSuffix != "" {
+		encrypted = anyPath(path, func(p string) bool { return strings.HasSuffix(p, md.EncryptedSuffix) })
+	}
+	if md.UnencryptedRegex != "" {
+		re, err := regexp.Compile(md.UnencryptedRegex)
+		if err != nil {
+			return false, err
+		}
+		if anyPath(path, re.MatchString) {
+			encrypted = false
+		}
+	}
+	if md.EncryptedRegex != "" {
+		re, err := regexp.Compile(md.EncryptedRegex)
+		if err != nil {
+			return false, err
+		}
+		encrypted = anyPath(path, re.MatchString)
+	}
+	return encrypted, nil
+}
+
+// newMAC returns the hash used to compute the message authentication code.
+func (md *metadata) newMAC() hash.Hash {
+	h := sha512.New()
+	if md.MACOnlyEncrypted {
+		// SOPS initializes the hash so the MAC is different when this option is enabled
+		h.Write(macOnlyEncryptedInitialization)
+	}
+	return h
+}
+
+// anyPath checks to see if any of the path elements match the supplied predicate.
+func anyPath(path []string, f func(string) bool) bool {
+	for _, p := range path {
+		if f(p) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsEncrypted performs a quick check to see if the supplied data may contain SOPS encrypted documents.
+func IsEncrypted(data []byte) bool {
+	return bytes.Contains(data, []byte(MetadataField)) && bytes.Contains(data, []byte("ENC[AES256_GCM,"))
+}
+
+// Decrypt decrypts the SOPS encrypted documents in the supplied YAML or JSON data
+// using the locally available age identities. Unencrypted documents are returned
+// unchanged (though the data may be re-formatted).
+func Decrypt(data []byte) ([]byte, error) {
+	nodes, err := kio.FromBytes(data)
+	if err != nil {
+		return nil, err
+	}
+
+	identities, err := loadIdentities()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := DecryptNodes(nodes, identities...); err != nil {
+		return nil, err
+	}
+
+	out, err := kio.StringAll(nodes)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(out), nil
+}
+
+// HasMetadata checks to see if the supplied node has SOPS metadata.
+func HasMetadata(node *yaml.RNode) bool {
+	return node.Field(MetadataField) != nil
+}
+
+// Encrypt returns a filter which encrypts a document for the supplied age
+// recipients. If the encrypted regex is not empty, only values whose path
+// matches are encrypted. The returned filter should be applied to documents
+// in their final form: any change after encryption will invalidate the MAC.
+func Encrypt(recipients []string, encryptedRegex string) yaml.Filter {
+	return yaml.FilterFunc(func(node *yaml.RNode) (*yaml.RNode, error) {
+		if HasMetadata(node) {
+			return node, nil
+		}
+
+		md := &metadata{
+			LastModified:   time.Now().UTC().Format(time.RFC3339),
+			EncryptedRegex: encryptedRegex,
+			Version:        Version,
+		}
+		if md.EncryptedRegex == "" {
+			md.UnencryptedSuffix = DefaultUnencryptedSuffix
+		}
+
+		dataKey := make([]byte, 32)
+		if _, err := rand.Read(dataKey); err != nil {
+			return nil, err
+		}
+
+		var err error
+		if md.Age, err = encryptDataKey(dataKey, recipients); err != nil {
+			return nil, err
+		}
+
+		mac := md.newMAC()
+		if err := walk(node.YNode(), nil, func(n *yaml.Node, path []string) error {
+			encrypted, err := md.shouldBeEncrypted(path)
+			if err != nil {
+				return err
+			}
+
+			value, valueType := plaintext(n)
+			if !md.MACOnlyEncrypted || encrypted {
+				mac.Write([]byte(value))
+			}
+			if !encrypted || value == "" {
+				return nil
+			}
+
+			n.Value, err = encrypt(value, valueType, dataKey, additionalData(path))
+			n.Tag = yaml.NodeTagString
+			n.Style = 0
+			return err
+		}); err != nil {
+			return nil, err
+		}
+
+		if md.MAC, err = encrypt(fmt.Sprintf("%X", mac.Sum(nil)), "str", dataKey, md.LastModified); err != nil {
+			return nil, err
+		}
+
+		mdNode := &yaml.Node{}
+		if err := mdNode.Encode(md); err != nil {
+			return nil, err
+		}
+		return node, node.PipeE(yaml.SetField(MetadataField, yaml.NewRNode(mdNode)))
+	})
+}
+
+// DecryptNodes decrypts the supplied nodes in place using the supplied identities.
+// Nodes without SOPS metadata are ignored. Nodes with the same MAC (e.g. the
+// documents of a multi-document file) are verified together.
+func DecryptNodes(nodes []*yaml.RNode, identities ...Identity) error {
+	type group struct {
+		md    *metadata
+		nodes []*yaml.RNode
+	}
+	var groups []*group
+	byMAC := make(map[string]*group)
+	for _, node := range nodes {
+		mdNode := node.Field(MetadataField)
+		if mdNode == nil {
+			continue
+		}
+
+		md := &metadata{}
+		if err := mdNode.Value.YNode().Decode(md); err != nil {
+			return fmt.Errorf("invalid SOPS metadata: %w", err)
+		}
+
+		g, ok := byMAC[md.MAC]
+		if !ok {
+			g = &group{md: md}
+			byMAC[md.MAC] = g
+			groups = append(groups, g)
+		}
+		g.nodes = append(g.nodes, node)
+	}
+
+	for _, g := range groups {
+		if err := decryptGroup(g.md, g.nodes, identities); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// decryptGroup decrypts a list of documents sharing the same metadata.
+func decryptGroup(md *metadata, nodes []*yaml.RNode, identities []Identity) error {
+	dataKey, err := decryptDataKey(md.Age, identities)
+	if err != nil {
+		return err
+	}
+
+	mac := md.newMAC()
+	for _, node := range nodes {
+		comments := make(map[*yaml.Node]bool)
+		if err := walk(node.YNode(), nil, func(n *yaml.Node, path []string) error {
+			encrypted, err := md.shouldBeEncrypted(path)
+			if err != nil {
+				return err
+			}
+
+			if encrypted && encPattern.MatchString(n.Value) {
+				valueType, err := decryptScalar(n, dataKey, additionalData(path))
+				if err != nil {
+					return fmt.Errorf("failed to decrypt %s: %w", strings.Join(path, "."), err)
+				}
+
+				// SOPS represents comments in sequences as encrypted elements
+				if valueType == "comment" {
+					comments[n] = true
+					return nil
+				}
+			}
+
+			if !md.MACOnlyEncrypted || encrypted {
+				value, _ := plaintext(n)
+				mac.Write([]byte(value))
+			}
+			return nil
+		}); err != nil {
+			return err
+		}
+
+		foldComments(node.YNode(), comments)
+
+		if _, err := node.Pipe(yaml.Clear(MetadataField)); err != nil {
+			return err
+		}
+
+		decryptComments(node.YNode(), nil, dataKey)
+	}
+
+	lastModified, err := time.Parse(time.RFC3339, md.LastModified)
+	if err != nil {
+		return err
+	}
+	expectedMAC, _, err := decrypt(md.MAC, dataKey, lastModified.Format(time.RFC3339))
+	if err != nil {
+		return fmt.Errorf("failed to decrypt SOPS MAC: %w", err)
+	}
+	if actualMAC := fmt.Sprintf("%X", mac.Sum(nil)); actualMAC != expectedMAC {
+		return fmt.Errorf("SOPS MAC mismatch, file has been tampered with")
+	}
+
+	return nil
+}
+
+// walk invokes the supplied function on every non-null scalar value (excluding the SOPS metadata).
+func walk(node *yaml.Node, path []string, fn func(*yaml.Node, []string) error) error {
+	switch node.Kind {
+	case yaml.DocumentNode:
+		for _, n := range node.Content {
+			if err := walk(n, path, fn); err != nil {
+				return err
+			}
+		}
+
+	case yaml.MappingNode:
+		for i := 0; i < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			if len(path) == 0 && key.Value == MetadataField {
+				continue
+			}
+			if err := walk(value, append(path[:len(path):len(path)], key.Value), fn); err != nil {
+				return err
+			}
+		}
+
+	case yaml.SequenceNode:
+		for _, n := range node.Content {
+			if err := walk(n, path, fn); err != nil {
+				return err
+			}
+		}
+
+	case yaml.ScalarNode:
+		if node.ShortTag() == yaml.NodeTagNull {
+			return nil
+		}
+		return fn(node, path)
+	}
+
+	return nil
+}
+
+// decryptComments decrypts the comments of the supplied node and its children.
+// SOPS authenticates comments using the path of the enclosing collection.
+// Comments which cannot be decrypted are assumed to be plain text.
+func decryptComments(node *yaml.Node, path []string, key []byte) {
+	for _, c := range []*string{&node.HeadComment, &node.LineComment, &node.FootComment} {
+		lines := strings.Split(*c, "\n")
+		for i, line := range lines {
+			if value, valueType, err := decrypt(strings.TrimPrefix(line, "#"), key, additionalData(path)); err == nil && valueType == "comment" {
+				lines[i] = "#" + value
+			}
+		}
+		*c = strings.Join(lines, "\n")
+	}
+
+	switch node.Kind {
+	case yaml.DocumentNode, yaml.SequenceNode:
+		for _, n := range node.Content {
+			decryptComments(n, path, key)
+		}
+
+	case yaml.MappingNode:
+		for i := 0; i < len(node.Content); i += 2 {
+			k, v := node.Content[i], node.Content[i+1]
+			decryptComments(k, path, key)
+			if v.Kind == yaml.ScalarNode || v.Kind == yaml.AliasNode {
+				decryptComments(v, path, key)
+			} else {
+				decryptComments(v, append(path[:len(path):len(path)], k.Value), key)
+			}
+		}
+	}
+}
+
+// additionalData returns the authenticated data for a value at the supplied path.
+func additionalData(path []string) string {
+	return strings.Join(path, ":") + ":"
+}
+
+// plaintext returns the SOPS representation of a scalar value and its type.
+func plaintext(n *yaml.Node) (string, string) {
+	switch n.ShortTag() {
+	case yaml.NodeTagInt:
+		if i, err := strconv.ParseInt(n.Value, 0, 64); err == nil {
+			return strconv.FormatInt(i, 10), "int"
+		}
+	case yaml.NodeTagFloat:
+		if f, err := strconv.ParseFloat(n.Value, 64); err == nil {
+			return strconv.FormatFloat(f, 'f', -1, 64), "float"
+		}
+	case yaml.NodeTagBool:
+		if b, err := strconv.ParseBool(strings.ToLower(n.Value)); err == nil {
+			if b {
+				return "True", "bool"
+			}
+			return "False", "bool"
+		}
+	}
+	return n.Value, "str"
+}
+
+// decryptScalar replaces the encrypted value of a scalar node, returning the type of the decrypted value.
+func decryptScalar(n *yaml.Node, key []byte, ad string) (string, error) {
+	value, valueType, err := decrypt(n.Value, key, ad)
+	if err != nil {
+		return "", err
+	}
+
+	n.Value, n.Style = value, 0
+	switch valueType {
+	case "int":
+		n.Tag = yaml.NodeTagInt
+	case "float":
+		n.Tag = yaml.NodeTagFloat
+	case "bool":
+		n.Tag, n.Value = yaml.NodeTagBool, strings.ToLower(value)
+	default:
+		n.Tag = yaml.NodeTagString
+		if strings.Contains(value, "\n") {
+			n.Style = yaml.LiteralStyle
+		}
+	}
+	return valueType, nil
+}
+
+// foldComments removes the decrypted comment elements from sequences and
+// restores them as comments on the adjacent elements.
+func foldComments(node *yaml.Node, comments map[*yaml.Node]bool) {
+	if len(comments) == 0 {
+		return
+	}
+
+	if node.Kind == yaml.SequenceNode {
+		var content []*yaml.Node
+		var pending []string
+		for _, n := range node.Content {
+			if comments[n] {
+				pending = append(pending, "#"+n.Value)
+				continue
+			}
+			if len(pending) > 0 {
+				n.HeadComment = strings.Join(append(pending, n.HeadComment), "\n")
+				n.HeadComment = strings.TrimSuffix(n.HeadComment, "\n")
+				pending = nil
+			}
+			content = append(content, n)
+		}
+		if len(pending) > 0 {
+			node.FootComment = strings.Join(pending, "\n")
+		}
+		node.Content = content
+	}
+
+	for _, n := range node.Content {
+		foldComments(n, comments)
+	}
+}
+
+// encrypt produces a SOPS encrypted value.
+func encrypt(value, valueType string, key []byte, ad string) (string, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+
+	iv := make([]byte, nonceSize)
+	if _, err := rand.Read(iv); err != nil {
+		return "", err
+	}
+
+	out := gcm.Seal(nil, iv, []byte(value), []byte(ad))
+	data, tag := out[:len(out)-gcm.Overhead()], out[len(out)-gcm.Overhead():]
+	return fmt.Sprintf("ENC[AES256_GCM,data:%s,iv:%s,tag:%s,type:%s]",
+		base64.StdEncoding.EncodeToString(data),
+		base64.StdEncoding.EncodeToString(iv),
+		base64.StdEncoding.EncodeToString(tag),
+		valueType), nil
+}
+
+// decrypt returns the plain text and type of a SOPS encrypted value.
+func decrypt(value string, key []byte, ad string) (string, string, error) {
+	m := encPattern.FindStringSubmatch(value)
+	if m == nil {
+		return "", "", errors.New("invalid encrypted value")
+	}
+
+	var parts [3][]byte
+	for i := range parts {
+		var err error
+		if parts[i], err = base64.StdEncoding.DecodeString(m[i+1]); err != nil {
+			return "", "", err
+		}
+	}
+	data, iv, tag := parts[0], parts[1], parts[2]
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return "", "", err
+	}
+	gcm, err := cipher.NewGCMWithNonceSize(block, len(iv))
+	if err != nil {
+		return "", "", err
+	}
+
+	plain, err := gcm.Open(nil, iv, append(data, tag...), []byte(ad))
+	if err != nil {
+		return "", "", err
+	}
+	return string(plain), m[4], nil
+}
+
+// newGCM returns the AES-GCM cipher used by SOPS.
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCMWithNonceSize(block, nonceSize)
+}
@@ -0,0 +1,243 @@
+/*
+Copyright 2022 GramLabs, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sops
+
+import (
+	"strings"
+	"testing"
+
+	"filippo.io/age"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"sigs.k8s.io/kustomize/kyaml/kio"
+	"sigs.k8s.io/kustomize/kyaml/yaml"
+)
+
+// sopsEncrypted is a Secret encrypted (including a comment) for a throwaway key generated for this test.
+const (
+	sopsIdentity  = "AGE-SECRET-KEY-1TQ64CPPMT6ZKRF5HP8AKZWQZ52SWJVXHTXZRW9WLRKJDSU8CMN0QSJV9AK"
+	sopsEncrypted = `apiVersion: v1
+kind: Secret
+metadata:
+    name: test
+type: Opaque
+stringData:
+    #ENC[AES256_GCM,data:PoG0CjEpbmdROdSu0w==,iv:ua/s2Af0LFysbFoadblJCjXFcKRqbEaLdo8xOqW0v9g=,tag:Q76LSMN14XXQ1Q4Erma3CA==,type:comment]
+    password: ENC[AES256_GCM,data:VhA22RtnRg==,iv:CSgoMY8IiZUyJffXWQSt/cAtkTzXeLD8x3LvDJxp9rQ=,tag:Ynwpw92/uVPWMuuDmrQHYQ==,type:str]
+    port: ENC[AES256_GCM,data:8oXNnQ==,iv:HTCYVheQv791HcofVr7ouyUF22e9wSSGtF6N99hp+NU=,tag:fEVTf3TZiCwDK6FBtCx8Hg==,type:int]
+    enabled: ENC[AES256_GCM,data:r1O2iA==,iv:kvYQ3V96bgIZDn7tLe5GnEGqvlRLnZNfFYw4idBiMgY=,tag:BvnTIrDKVpixqB29KrD72w==,type:bool]
+    hosts:
+        - ENC[AES256_GCM,data:1JYe,iv:f6KM6I7MQozi7z41fV1AwpBsiP1Wjmos2JAuQSfHhq8=,tag:DtI2ngaEBKPHnu1JhDh3xQ==,type:str]
+        - ENC[AES256_GCM,data:aj7u,iv:Fo9rW9yEUIp8sEniwOdnrAY5p/3wlopxhy4Ohd+LYIU=,tag:1eHS5Wxc45ayi5QTPYtZRQ==,type:str]
+sops:
+    age:
+        - enc: |
+            -----BEGIN AGE ENCRYPTED FILE-----
+            YWdlLWVuY3J5cHRpb24ub3JnL3YxCi0+IFgyNTUxOSAvaUtkdHFybVBrbzBIdnRU
+            MHIvYm9NWkJHVXV5Q000Skl3Nk1icFBqWVQ0Cm56NFk2Y3VSdVZ2Y1hQb0Myc0VP
+            Y05EMjlzbStXem1vZGZLUHRyWHFualkKLS0tIG43b0hhS3ZwdW1rOTNoQnNCeFhH
+            WndhOW1QVG5UdjVNOVpPY0FyZ1E1emcKh/E20QrB0Um2FifUuo0M8ZDYYTenlvgU
+            hQtfM64tEj33MUnfdpOOwHpNCgUYeEAysa2oTiLBLsU2mxPlN6tC6Q==
+            -----END AGE ENCRYPTED FILE-----
+          recipient: age18qce7l9aystaukcygn4n93tncyfjhw3ckvyjjzydm32twhwcxpzsuhdnlx
+    encrypted_regex: ^(data|stringData)$
+    lastmodified: "2026-10-18T20:41:00Z"
+    mac: ENC[AES256_GCM,data:Rk2bb03JbBZMsWBDTjiXzjxZ/dTe/+zXbQS+LHkgID6x8gucxsiZA1UsUulLeJq7xZ9sTeFCj2+iYNjtaFK5aXOFAQwFSVleuZCUzZY58MXEtNx3m2zg25oOnqoK34fBHkECsNVWgD4NBjFEPrZhueRHgR+bLdosuHSFT6FFtAw=,iv:IDQGaayZE7LBbYjEdLrk90rc+RXNtTEWy7OD70XIlAA=,tag:4CemRIS8a+0V4ITTzYM/6w==,type:str]
+    version: 3.13.3
+`
+)
+
+func TestDecrypt(t *testing.T) {
+	t.Setenv("SOPS_AGE_KEY", sopsIdentity)
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	assert.True(t, IsEncrypted([]byte(sopsEncrypted)))
+
+	actual, err := Decrypt([]byte(sopsEncrypted))
+	require.NoError(t, err)
+	assert.Equal(t, `apiVersion: v1
+kind: Secret
+metadata:
+  name: test
+type: Opaque
+stringData:
+  # The password
+  password: hunter2
+  port: 5432
+  enabled: true
+  hosts:
+  - one
+  - two
+`, string(actual))
+}
+
+func TestDecrypt_Tampered(t *testing.T) {
+	t.Setenv("SOPS_AGE_KEY", sopsIdentity)
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	_, err := Decrypt([]byte(strings.Replace(sopsEncrypted, "name: test", "name: tampered", 1)))
+	assert.ErrorContains(t, err, "MAC mismatch")
+}
+
+func TestEncrypt(t *testing.T) {
+	identity, err := age.GenerateX25519Identity()
+	require.NoError(t, err)
+	other, err := age.GenerateX25519Identity()
+	require.NoError(t, err)
+
+	const input = `apiVersion: v1
+kind: Secret
+metadata:
+  name: test
+type: Opaque
+data:
+  password: aHVudGVyMg==
+stringData:
+  username: admin
+`
+
+	cases := []struct {
+		desc           string
+		encryptedRegex string
+		unencrypted    []string
+		encrypted      []string
+	}{
+		{
+			desc:           "kubernetes secret",
+			encryptedRegex: KubernetesSecretRegex,
+			unencrypted:    []string{"kind", "metadata.name", "type"},
+			encrypted:      []string{"data.password", "stringData.username"},
+		},
+		{
+			desc:      "everything",
+			encrypted: []string{"kind", "metadata.name", "type", "data.password", "stringData.username"},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.desc, func(t *testing.T) {
+			node := yaml.MustParse(input)
+			require.NoError(t, node.PipeE(Encrypt([]string{identity.Recipient().String()}, c.encryptedRegex)))
+			assert.True(t, HasMetadata(node))
+
+			for _, p := range c.unencrypted {
+				assert.NotContains(t, fieldValue(t, node, p), "ENC[", p)
+			}
+			for _, p := range c.encrypted {
+				assert.Contains(t, fieldValue(t, node, p), "ENC[AES256_GCM,", p)
+			}
+
+			// Only the recipient can decrypt
+			assert.Error(t, DecryptNodes([]*yaml.RNode{node.Copy()}, other))
+			require.NoError(t, DecryptNodes([]*yaml.RNode{node}, identity))
+			assert.False(t, HasMetadata(node))
+
+			actual, err := kio.StringAll([]*yaml.RNode{node})
+			require.NoError(t, err)
+			assert.Equal(t, input, actual)
+		})
+	}
+}
+
+func TestEncrypt_RoundTrip(t *testing.T) {
+	identity, err := age.GenerateX25519Identity()
+	require.NoError(t, err)
+
+	const input = `# The test secret
+apiVersion: v1
+kind: Secret
+metadata:
+  name: test
+  labels:
+    app: test # the application
+type: Opaque
+stringData:
+  # The database settings
+  database:
+    host: db.example.com
+    port: 5432
+    replicas:
+    # The primary
+    - name: primary
+      readOnly: false
+    - name: secondary
+      readOnly: true
+`
+
+	cases := []struct {
+		desc           string
+		encryptedRegex string
+		tamper         func(*yaml.RNode) error
+		err            string
+	}{
+		{
+			desc:           "kubernetes secret",
+			encryptedRegex: KubernetesSecretRegex,
+		},
+		{
+			desc: "everything",
+		},
+		{
+			desc:           "tampered nested plain value",
+			encryptedRegex: KubernetesSecretRegex,
+			tamper: func(node *yaml.RNode) error {
+				return node.PipeE(yaml.Lookup("metadata", "labels"), yaml.SetField("app", yaml.NewStringRNode("other")))
+			},
+			err: "MAC mismatch",
+		},
+		{
+			desc:           "removed nested encrypted value",
+			encryptedRegex: KubernetesSecretRegex,
+			tamper: func(node *yaml.RNode) error {
+				return node.PipeE(yaml.Lookup("stringData", "database", "replicas", "1"), yaml.Clear("readOnly"))
+			},
+			err: "MAC mismatch",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.desc, func(t *testing.T) {
+			node := yaml.MustParse(input)
+			require.NoError(t, node.PipeE(Encrypt([]string{identity.Recipient().String()}, c.encryptedRegex)))
+
+			// Round trip through the encoded form
+			data, err := kio.StringAll([]*yaml.RNode{node})
+			require.NoError(t, err)
+			nodes, err := kio.FromBytes([]byte(data))
+			require.NoError(t, err)
+
+			if c.tamper != nil {
+				require.NoError(t, c.tamper(nodes[0]))
+			}
+
+			err = DecryptNodes(nodes, identity)
+			if c.err != "" {
+				assert.ErrorContains(t, err, c.err)
+				return
+			}
+			require.NoError(t, err)
+
+			actual, err := kio.StringAll(nodes)
+			require.NoError(t, err)
+			assert.Equal(t, input, actual)
+		})
+	}
+}
+
+func fieldValue(t *testing.T, node *yaml.RNode, path string) string {
+	value, err := node.GetString(path)
+	require.NoError(t, err)
+	return value
+}
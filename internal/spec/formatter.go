@@ -18,6 +18,8 @@ package spec
 
 import (
 	"fmt"
+	"net/url"
+	"strings"
 
 	konjurev1beta2 "github.com/thestormforge/konjure/pkg/api/core/v1beta2"
 )
@@ -47,7 +49,7 @@ func (f *Formatter) Encode(obj any) (string, error) {
 		}
 
 	case *konjurev1beta2.Kubernetes:
-		// Do nothing
+		return encodeKubernetesSpec(s), nil
 
 	case *konjurev1beta2.Kustomize:
 		return s.Root, nil
@@ -55,6 +57,9 @@ func (f *Formatter) Encode(obj any) (string, error) {
 	case *konjurev1beta2.Secret:
 		// There is no specification form for secrets
 
+	case *konjurev1beta2.ConfigMap:
+		// There is no specification form for config maps
+
 	case *konjurev1beta2.Git:
 		// TODO This is probably more complex because of all the allowed formats
 
@@ -63,7 +68,63 @@ func (f *Formatter) Encode(obj any) (string, error) {
 
 	case *konjurev1beta2.File:
 		return s.Path, nil
+
+	case *konjurev1beta2.Overlay:
+		// There is no specification form for overlays
+
+	case *konjurev1beta2.Replacement:
+		// There is no specification form for replacements
 	}
 
 	return "", fmt.Errorf("object cannot be formatted")
 }
+
+// encodeKubernetesSpec returns the "k8s:" URL representation of the supplied Kubernetes resource.
+func encodeKubernetesSpec(k8s *konjurev1beta2.Kubernetes) string {
+	var spec strings.Builder
+	spec.WriteString("k8s:")
+
+	namespace := k8s.Namespace
+	if namespace == "" {
+		namespace = strings.Join(k8s.Namespaces, ",")
+	}
+
+	if k8s.Context != "" {
+		spec.WriteString("//")
+		spec.WriteString(url.User(k8s.Context).String())
+		spec.WriteString("@")
+	}
+	spec.WriteString(namespace)
+
+	if len(k8s.Types) > 0 || k8s.Name != "" {
+		spec.WriteString("/")
+		spec.WriteString(strings.Join(k8s.Types, ","))
+	}
+	if k8s.Name != "" {
+		spec.WriteString("/")
+		spec.WriteString(k8s.Name)
+	}
+
+	q := url.Values{}
+	if k8s.Selector != "" {
+		q.Set("labelSelector", k8s.Selector)
+	}
+	if k8s.FieldSelector != "" {
+		q.Set("fieldSelector", k8s.FieldSelector)
+	}
+	if k8s.NamespaceSelector != "" {
+		q.Set("namespaceSelector", k8s.NamespaceSelector)
+	}
+	if k8s.AllNamespaces {
+		q.Set("allNamespaces", "true")
+	}
+	if k8s.Kubeconfig != "" {
+		q.Set("kubeconfig", k8s.Kubeconfig)
+	}
+	if len(q) > 0 {
+		spec.WriteString("?")
+		spec.WriteString(q.Encode())
+	}
+
+	return spec.String()
+}
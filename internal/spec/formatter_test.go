@@ -0,0 +1,100 @@
+/*
+Copyright 2022 GramLabs, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package spec
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	konjurev1beta2 "github.com/thestormforge/konjure/pkg/api/core/v1beta2"
+)
+
+func TestFormatter_Encode(t *testing.T) {
+	cases := []struct {
+		desc     string
+		obj      any
+		expected string
+	}{
+		{
+			desc:     "file",
+			obj:      &konjurev1beta2.File{Path: "/foo/bar"},
+			expected: "/foo/bar",
+		},
+		{
+			desc:     "kubernetes empty",
+			obj:      &konjurev1beta2.Kubernetes{},
+			expected: "k8s:",
+		},
+		{
+			desc: "kubernetes namespace types",
+			obj: &konjurev1beta2.Kubernetes{
+				Namespace: "default",
+				Types:     []string{"deployments", "services"},
+			},
+			expected: "k8s:default/deployments,services",
+		},
+		{
+			desc: "kubernetes types only",
+			obj: &konjurev1beta2.Kubernetes{
+				Types: []string{"deployments"},
+			},
+			expected: "k8s:/deployments",
+		},
+		{
+			desc: "kubernetes context name",
+			obj: &konjurev1beta2.Kubernetes{
+				Context:   "my-cluster",
+				Namespace: "default",
+				Types:     []string{"deployments"},
+				Name:      "my-app",
+			},
+			expected: "k8s://my-cluster@default/deployments/my-app",
+		},
+		{
+			desc: "kubernetes context ARN",
+			obj: &konjurev1beta2.Kubernetes{
+				Context:   "arn:aws:eks:us-east-1:000000000000:cluster/my-cluster",
+				Namespace: "default",
+			},
+			expected: "k8s://arn%3Aaws%3Aeks%3Aus-east-1%3A000000000000%3Acluster%2Fmy-cluster@default",
+		},
+		{
+			desc: "kubernetes query",
+			obj: &konjurev1beta2.Kubernetes{
+				Namespaces:    []string{"default", "kube-system"},
+				Selector:      "app=test",
+				FieldSelector: "status.phase=Running",
+				Kubeconfig:    "/tmp/kubeconfig",
+			},
+			expected: "k8s:default,kube-system?fieldSelector=status.phase%3DRunning&kubeconfig=%2Ftmp%2Fkubeconfig&labelSelector=app%3Dtest",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.desc, func(t *testing.T) {
+			actual, err := (&Formatter{}).Encode(c.obj)
+			if assert.NoError(t, err) {
+				assert.Equal(t, c.expected, actual)
+
+				// Make sure the formatted value parses back to the original object
+				parsed, err := (&Parser{}).Decode(actual)
+				if assert.NoError(t, err) {
+					assert.Equal(t, c.obj, parsed)
+				}
+			}
+		})
+	}
+}
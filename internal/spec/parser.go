@@ -64,15 +64,20 @@ func (p *Parser) Decode(spec string) (any, error) {
 		return &konjurev1beta2.File{Path: spec}, nil
 	}
 
+	schemes := p.schemes()
+
 	// Process scheme overrides
 	if so := schemeOverride.FindString(spec); so != "" {
-		switch strings.ToLower(so) {
-		case "git::":
-			return p.parseGitSpec(spec[len(so):])
-		case "helm::":
-			return p.parseHelmSpec(spec[len(so):])
-		default:
-			return nil, fmt.Errorf("unknown scheme override: %s", so)
+		if decode := schemes.SchemeOverride(so); decode != nil {
+			return decode(spec[len(so):])
+		}
+		return nil, fmt.Errorf("unknown scheme override: %s", so)
+	}
+
+	// Kubernetes URLs may contain context names which are not valid URL user information
+	if strings.HasPrefix(spec, "k8s://") {
+		if decode := schemes.Scheme("k8s"); decode != nil {
+			return decode(spec)
 		}
 	}
 
@@ -88,25 +93,31 @@ func (p *Parser) Decode(spec string) (any, error) {
 			return p.parseGitSpec(spec)
 		}
 
-		switch u.Scheme {
-		case "ssh":
-			return p.parseGitSpec(spec)
-		case "http", "https":
-			return p.parseHTTPSpec(spec)
-		case "helm":
-			return p.parseHelmSpec(spec)
-		case "k8s":
-			return p.parseKubernetesSpec(spec)
-		case "data":
-			return p.parseDataSpec(spec)
-		case "file":
-			return &konjurev1beta2.File{Path: filepath.Join(path.Split(u.Path))}, nil
+		if decode := schemes.Scheme(u.Scheme); decode != nil {
+			return decode(spec)
 		}
 	}
 
 	return &konjurev1beta2.File{Path: spec}, nil
 }
 
+// schemes returns the registry of decode functions for this parser: the
+// built-in schemes overlaid with the registered default schemes.
+func (p *Parser) schemes() *Schemes {
+	s := &Schemes{}
+	s.RegisterSchemeOverride("git", p.parseGitSpec)
+	s.RegisterSchemeOverride("helm", p.parseHelmSpec)
+	s.RegisterScheme("ssh", p.parseGitSpec)
+	s.RegisterScheme("http", p.parseHTTPSpec)
+	s.RegisterScheme("https", p.parseHTTPSpec)
+	s.RegisterScheme("helm", p.parseHelmSpec)
+	s.RegisterScheme("k8s", p.parseKubernetesSpec)
+	s.RegisterScheme("data", p.parseDataSpec)
+	s.RegisterScheme("file", p.parseFileSpec)
+	s.merge(DefaultSchemes)
+	return s
+}
+
 func (p *Parser) parseGitSpec(spec string) (any, error) {
 	u, err := ParseURL(spec)
 	if err != nil {
@@ -243,6 +254,21 @@ func (p *Parser) parseHTTPSpec(spec string) (any, error) {
 }
 
 func (p *Parser) parseKubernetesSpec(spec string) (any, error) {
+	// Extract the context before parsing the URL, context names like
+	// "arn:aws:eks:us-east-1:000000000000:cluster/my-cluster" are not valid user
+	// information so they may appear escaped or unescaped
+	var context string
+	if rest, ok := strings.CutPrefix(spec, "k8s://"); ok {
+		authority, _, _ := strings.Cut(rest, "?")
+		if pos := strings.LastIndex(authority, "@"); pos >= 0 {
+			var err error
+			if context, err = url.PathUnescape(rest[:pos]); err != nil {
+				return nil, err
+			}
+			spec = "k8s://" + rest[pos+1:]
+		}
+	}
+
 	u, err := url.Parse(spec)
 	if err != nil {
 		return nil, err
@@ -250,28 +276,49 @@ func (p *Parser) parseKubernetesSpec(spec string) (any, error) {
 		return nil, fmt.Errorf("unexpected scheme: %s", spec)
 	}
 
+	// The specification can take a few forms:
+	// k8s:namespace/types/name
+	// k8s:/types
+	// k8s://[context@]namespace/types/name
+
 	var parts []string
-	if u.Opaque != "" {
+	switch {
+	case u.Opaque != "":
 		parts = strings.Split(u.Opaque, "/")
-	} else {
+	case u.Host != "":
+		parts = append([]string{u.Host}, strings.Split(strings.TrimPrefix(u.Path, "/"), "/")...)
+	default:
 		parts = strings.Split(u.Path, "/")
 	}
-	if len(parts) > 2 {
-		return nil, fmt.Errorf("expected namespace/types: %s", spec)
+	if len(parts) > 3 {
+		return nil, fmt.Errorf("expected namespace/types/name: %s", spec)
 	}
 
+	q := u.Query()
 	k8s := &konjurev1beta2.Kubernetes{}
-	k8s.Selector = u.Query().Get("labelSelector")
-	k8s.AllNamespaces, _ = strconv.ParseBool(u.Query().Get("allNamespaces"))
-	k8s.NamespaceSelector = u.Query().Get("namespaceSelector")
-	k8s.Namespaces = strings.Split(parts[0], ",")
-	if len(k8s.Namespaces) == 1 {
-		k8s.Namespace = k8s.Namespaces[0]
-		k8s.Namespaces = nil
-	}
-	if len(parts) > 1 {
+	k8s.Selector = q.Get("labelSelector")
+	k8s.FieldSelector = q.Get("fieldSelector")
+	k8s.AllNamespaces, _ = strconv.ParseBool(q.Get("allNamespaces"))
+	k8s.NamespaceSelector = q.Get("namespaceSelector")
+	k8s.Kubeconfig = q.Get("kubeconfig")
+	k8s.Context = q.Get("context")
+	if context != "" {
+		k8s.Context = context
+	}
+
+	if parts[0] != "" {
+		k8s.Namespaces = strings.Split(parts[0], ",")
+		if len(k8s.Namespaces) == 1 {
+			k8s.Namespace = k8s.Namespaces[0]
+			k8s.Namespaces = nil
+		}
+	}
+	if len(parts) > 1 && parts[1] != "" {
 		k8s.Types = strings.Split(parts[1], ",")
 	}
+	if len(parts) > 2 {
+		k8s.Name = parts[2]
+	}
 	return k8s, nil
 }
 
@@ -304,6 +351,17 @@ func (p *Parser) parseDataSpec(spec string) (any, error) {
 	return &kio.ByteReader{Reader: bytes.NewReader(data)}, nil
 }
 
+func (p *Parser) parseFileSpec(spec string) (any, error) {
+	u, err := url.Parse(spec)
+	if err != nil {
+		return nil, err
+	} else if u.Scheme != "file" {
+		return nil, fmt.Errorf("unexpected scheme: %s", spec)
+	}
+
+	return &konjurev1beta2.File{Path: filepath.Join(path.Split(u.Path))}, nil
+}
+
 func normalizeGitRepositoryURL(repo *URL) bool {
 	h := strings.ToLower(repo.Hostname())
 	switch {
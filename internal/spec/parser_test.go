@@ -70,6 +70,68 @@ func TestParser_Decode(t *testing.T) {
 				Types: []string{"deployments"},
 			},
 		},
+		{
+			desc: "kubernetes context",
+			spec: "k8s://my-cluster@default/deployments,services",
+			expected: &konjurev1beta2.Kubernetes{
+				Context:   "my-cluster",
+				Namespace: "default",
+				Types:     []string{"deployments", "services"},
+			},
+		},
+		{
+			desc: "kubernetes unescaped context",
+			spec: "k8s://arn:aws:eks:us-east-1:000000000000:cluster@default",
+			expected: &konjurev1beta2.Kubernetes{
+				Context:   "arn:aws:eks:us-east-1:000000000000:cluster",
+				Namespace: "default",
+			},
+		},
+		{
+			desc: "kubernetes unescaped context with slash",
+			spec: "k8s://arn:aws:eks:us-east-1:000000000000:cluster/my-cluster@default/deployments/my-app",
+			expected: &konjurev1beta2.Kubernetes{
+				Context:   "arn:aws:eks:us-east-1:000000000000:cluster/my-cluster",
+				Namespace: "default",
+				Types:     []string{"deployments"},
+				Name:      "my-app",
+			},
+		},
+		{
+			desc: "kubernetes escaped context",
+			spec: "k8s://arn%3Aaws%3Aeks%3Aus-east-1%3A000000000000%3Acluster%2Fmy-cluster@default",
+			expected: &konjurev1beta2.Kubernetes{
+				Context:   "arn:aws:eks:us-east-1:000000000000:cluster/my-cluster",
+				Namespace: "default",
+			},
+		},
+		{
+			desc: "kubernetes context without namespace",
+			spec: "k8s://my-cluster@/deployments",
+			expected: &konjurev1beta2.Kubernetes{
+				Context: "my-cluster",
+				Types:   []string{"deployments"},
+			},
+		},
+		{
+			desc: "kubernetes resource name",
+			spec: "k8s:default/deployments/my-app?kubeconfig=/tmp/kubeconfig",
+			expected: &konjurev1beta2.Kubernetes{
+				Namespace:  "default",
+				Types:      []string{"deployments"},
+				Name:       "my-app",
+				Kubeconfig: "/tmp/kubeconfig",
+			},
+		},
+		{
+			desc: "kubernetes field selector",
+			spec: "k8s:default,kube-system/pods?fieldSelector=status.phase%3DRunning",
+			expected: &konjurev1beta2.Kubernetes{
+				Namespaces:    []string{"default", "kube-system"},
+				Types:         []string{"pods"},
+				FieldSelector: "status.phase=Running",
+			},
+		},
 		{
 			desc: "file plain URI",
 			spec: "file:/foo/bar/",
@@ -0,0 +1,99 @@
+/*
+Copyright 2022 GramLabs, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package spec
+
+import (
+	"strings"
+	"sync"
+)
+
+// SchemeFunc decodes a resource specification into either a Konjure object
+// (e.g. `*konjurev1beta2.File`) or a `kio.Reader`.
+type SchemeFunc func(spec string) (any, error)
+
+// Schemes is a registry of the functions used to decode resource specifications
+// by URL scheme (e.g. "s3" for `s3://bucket/key`) or scheme override (e.g.
+// "git" for `git::https://example.com/repo`).
+type Schemes struct {
+	mu        sync.RWMutex
+	schemes   map[string]SchemeFunc
+	overrides map[string]SchemeFunc
+}
+
+// DefaultSchemes is the registry consulted by every parser. Schemes registered
+// here take precedence over the built-in schemes of the parser.
+var DefaultSchemes = &Schemes{}
+
+// RegisterScheme associates the supplied URL scheme with a decode function.
+// The scheme is case-insensitive; registering a nil function removes it.
+func (s *Schemes) RegisterScheme(scheme string, fn SchemeFunc) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.schemes = register(s.schemes, scheme, fn)
+}
+
+// RegisterSchemeOverride associates the supplied scheme override with a decode
+// function. The name should not include the trailing "::" and is case-insensitive;
+// registering a nil function removes it. Scheme override functions are invoked
+// with the remainder of the specification (i.e. without the "name::" prefix).
+func (s *Schemes) RegisterSchemeOverride(name string, fn SchemeFunc) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.overrides = register(s.overrides, strings.TrimSuffix(name, "::"), fn)
+}
+
+// Scheme returns the decode function for the supplied URL scheme or nil.
+func (s *Schemes) Scheme(scheme string) SchemeFunc {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.schemes[strings.ToLower(scheme)]
+}
+
+// SchemeOverride returns the decode function for the supplied scheme override or nil.
+func (s *Schemes) SchemeOverride(name string) SchemeFunc {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.overrides[strings.ToLower(strings.TrimSuffix(name, "::"))]
+}
+
+// merge copies all the registrations from the supplied registry into this registry.
+func (s *Schemes) merge(other *Schemes) {
+	other.mu.RLock()
+	defer other.mu.RUnlock()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for k, v := range other.schemes {
+		s.schemes = register(s.schemes, k, v)
+	}
+	for k, v := range other.overrides {
+		s.overrides = register(s.overrides, k, v)
+	}
+}
+
+// register adds (or removes) a function to a map, creating the map if necessary.
+func register(m map[string]SchemeFunc, name string, fn SchemeFunc) map[string]SchemeFunc {
+	name = strings.ToLower(name)
+	if fn == nil {
+		delete(m, name)
+		return m
+	}
+	if m == nil {
+		m = make(map[string]SchemeFunc)
+	}
+	m[name] = fn
+	return m
+}
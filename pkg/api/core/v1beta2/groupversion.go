@@ -51,12 +51,18 @@ func NewForType(t *yaml.TypeMeta) (any, error) {
 		result = new(Kustomize)
 	case "Secret":
 		result = new(Secret)
+	case "ConfigMap":
+		result = new(ConfigMap)
 	case "Git":
 		result = new(Git)
 	case "HTTP":
 		result = new(HTTP)
 	case "File":
 		result = new(File)
+	case "Overlay":
+		result = new(Overlay)
+	case "Replacement":
+		result = new(Replacement)
 	default:
 		return nil, fmt.Errorf("unknown kind: %s", t.Kind)
 	}
@@ -105,6 +111,12 @@ func GetRNode(obj any) (*yaml.RNode, error) {
 			Meta *yaml.ResourceMeta `yaml:",inline"`
 			Spec *Secret            `yaml:",inline"`
 		}{Meta: m, Spec: s}
+	case *ConfigMap:
+		m.Kind = "ConfigMap"
+		node = struct {
+			Meta *yaml.ResourceMeta `yaml:",inline"`
+			Spec *ConfigMap         `yaml:",inline"`
+		}{Meta: m, Spec: s}
 	case *Git:
 		m.Kind = "Git"
 		node = struct {
@@ -123,6 +135,18 @@ func GetRNode(obj any) (*yaml.RNode, error) {
 			Meta *yaml.ResourceMeta `yaml:",inline"`
 			Spec *File              `yaml:",inline"`
 		}{Meta: m, Spec: s}
+	case *Overlay:
+		m.Kind = "Overlay"
+		node = struct {
+			Meta *yaml.ResourceMeta `yaml:",inline"`
+			Spec *Overlay           `yaml:",inline"`
+		}{Meta: m, Spec: s}
+	case *Replacement:
+		m.Kind = "Replacement"
+		node = struct {
+			Meta *yaml.ResourceMeta `yaml:",inline"`
+			Spec *Replacement       `yaml:",inline"`
+		}{Meta: m, Spec: s}
 	default:
 		return nil, fmt.Errorf("unknown type: %T", obj)
 	}
@@ -108,6 +108,12 @@ type Kubernetes struct {
 	Selector string `json:"selector,omitempty" yaml:"selector,omitempty"`
 	// A field selector to limit which resources are included. Defaults to "" (match everything).
 	FieldSelector string `json:"fieldSelector,omitempty" yaml:"fieldSelector,omitempty"`
+	// The name of an individual resource to include. Cannot be combined with a label or field selector.
+	Name string `json:"name,omitempty" yaml:"name,omitempty"`
+	// The kubeconfig context to use. Defaults to the current context.
+	Context string `json:"context,omitempty" yaml:"context,omitempty"`
+	// Path to the kubeconfig file to use. Defaults to the standard kubeconfig resolution rules.
+	Kubeconfig string `json:"kubeconfig,omitempty" yaml:"kubeconfig,omitempty"`
 }
 
 // Kustomize is used to expand kustomizations.
@@ -130,14 +136,102 @@ type PasswordRecipe struct {
 	NoUpper *bool `json:"noUpper,omitempty" yaml:"noUpper,omitempty"`
 	// Flag restricting repeating characters.
 	AllowRepeat *bool `json:"allowRepeat,omitempty" yaml:"allowRepeat,omitempty"`
+	// Override the set of lowercase characters.
+	LowerLetters string `json:"lowerLetters,omitempty" yaml:"lowerLetters,omitempty"`
+	// Override the set of uppercase characters.
+	UpperLetters string `json:"upperLetters,omitempty" yaml:"upperLetters,omitempty"`
+	// Override the set of digit characters.
+	Digits string `json:"digits,omitempty" yaml:"digits,omitempty"`
+	// Override the set of symbol characters.
+	Symbols string `json:"symbols,omitempty" yaml:"symbols,omitempty"`
+	// Flag excluding characters which are easily confused with each other (e.g. "l", "1", "O" and "0").
+	ExcludeAmbiguous *bool `json:"excludeAmbiguous,omitempty" yaml:"excludeAmbiguous,omitempty"`
+
+	// The number of words in a generated passphrase. When specified, a diceware style passphrase is generated
+	// instead of a password and the character options are ignored.
+	Words *int `json:"words,omitempty" yaml:"words,omitempty"`
+	// The separator between passphrase words, defaults to "-".
+	WordSeparator *string `json:"wordSeparator,omitempty" yaml:"wordSeparator,omitempty"`
+	// The word list used to generate passphrases: "eff-large" (default), "eff-small" or "original".
+	WordList string `json:"wordList,omitempty" yaml:"wordList,omitempty"`
+
+	// Additional keys to store values derived from the generated password (e.g. a password hash).
+	Derived []DerivedKeyRecipe `json:"derived,omitempty" yaml:"derived,omitempty"`
+}
+
+// DerivedKeyRecipe is used to store a value derived from a generated password on a secret.
+type DerivedKeyRecipe struct {
+	// The key in the secret data field to use.
+	Key string `json:"key" yaml:"key"`
+	// The hashing algorithm used to derive the value: "bcrypt" or "sha512-crypt".
+	Algorithm string `json:"algorithm" yaml:"algorithm"`
+}
+
+// TLSRecipe is used to configure a generated certificate authority and leaf certificate for secrets.
+type TLSRecipe struct {
+	// The common name of the leaf certificate, it is also included as a DNS name.
+	CommonName string `json:"commonName" yaml:"commonName"`
+	// Additional DNS names or IP addresses to include on the leaf certificate.
+	Hosts []string `json:"hosts,omitempty" yaml:"hosts,omitempty"`
+	// The common name of the certificate authority. Defaults to the leaf common name with a " CA" suffix.
+	CACommonName string `json:"caCommonName,omitempty" yaml:"caCommonName,omitempty"`
+	// The duration the certificates are valid for (e.g. "2160h"). Defaults to one year.
+	ValidFor string `json:"validFor,omitempty" yaml:"validFor,omitempty"`
+	// The private key algorithm, one of "ecdsa", "rsa" or "ed25519". Defaults to "ecdsa".
+	KeyAlgorithm string `json:"keyAlgorithm,omitempty" yaml:"keyAlgorithm,omitempty"`
+}
+
+// SSHKeyRecipe is used to configure a generated SSH key pair for secrets.
+type SSHKeyRecipe struct {
+	// The private key algorithm, one of "ed25519", "rsa" or "ecdsa". Defaults to "ed25519".
+	KeyAlgorithm string `json:"keyAlgorithm,omitempty" yaml:"keyAlgorithm,omitempty"`
+	// The comment to include with the key.
+	Comment string `json:"comment,omitempty" yaml:"comment,omitempty"`
+}
+
+// DockerRegistryRecipe is used to configure container registry credentials for secrets.
+type DockerRegistryRecipe struct {
+	// The registry server location. Defaults to "https://index.docker.io/v1/".
+	Server string `json:"server,omitempty" yaml:"server,omitempty"`
+	// The username used to authenticate to the registry.
+	Username string `json:"username" yaml:"username"`
+	// The password used to authenticate to the registry.
+	Password string `json:"password" yaml:"password"`
+	// The optional email address associated with the username.
+	Email string `json:"email,omitempty" yaml:"email,omitempty"`
+}
+
+// HTPasswdRecipe is used to configure bcrypt hashed credentials for secrets.
+type HTPasswdRecipe struct {
+	// The key in the secret data field to use. Defaults to "auth".
+	Key string `json:"key,omitempty" yaml:"key,omitempty"`
+	// The username of the credential.
+	Username string `json:"username" yaml:"username"`
+	// The password to hash.
+	Password string `json:"password" yaml:"password"`
 }
 
 // Secret is used to expand a Secret resource.
 type Secret struct {
 	// The name of the secret to generate.
 	SecretName string `json:"secretName" yaml:"secretName"`
+	// The namespace of the secret to generate.
+	Namespace string `json:"namespace,omitempty" yaml:"namespace,omitempty"`
 	// The type of secret to generate.
 	Type string `json:"type,omitempty" yaml:"type,omitempty"`
+	// Flag indicating a hash of the secret contents should be appended to the name; references to the secret
+	// from workloads in the same stream are updated to use the suffixed name.
+	AppendHash bool `json:"appendHash,omitempty" yaml:"appendHash,omitempty"`
+
+	// Path to a file containing the seed used to derive random values (UUIDs, ULIDs, passwords, SSH keys and
+	// htpasswd salts) from the secret name and key instead of generating new values each time. TLS certificates
+	// and RSA keys cannot be derived from a seed.
+	SeedFile string `json:"seedFile,omitempty" yaml:"seedFile,omitempty"`
+	// The name of an environment variable containing the seed used to derive random values.
+	SeedEnv string `json:"seedEnv,omitempty" yaml:"seedEnv,omitempty"`
+	// Flag indicating that generated values should be preserved from the existing secret in the cluster; only
+	// missing keys are generated.
+	PreserveExisting bool `json:"preserveExisting,omitempty" yaml:"preserveExisting,omitempty"`
 
 	// A list of `key=value` pairs to include on the secret.
 	LiteralSources []string `json:"literals,omitempty" yaml:"literals,omitempty"`
@@ -151,11 +245,40 @@ type Secret struct {
 	ULIDSources []string `json:"ulids,omitempty" yaml:"ulids,omitempty"`
 	// A list of password recipes to include random strings on the secret.
 	PasswordSources []PasswordRecipe `json:"passwords,omitempty" yaml:"passwords,omitempty"`
+	// A recipe for a generated TLS certificate to include as `tls.crt`, `tls.key` and `ca.crt` on the secret.
+	TLSSource *TLSRecipe `json:"tls,omitempty" yaml:"tls,omitempty"`
+	// A recipe for a generated SSH key pair to include as `ssh-privatekey` and `ssh-publickey` on the secret.
+	SSHKeySource *SSHKeyRecipe `json:"sshKey,omitempty" yaml:"sshKey,omitempty"`
+	// A list of registry credentials to include as `.dockerconfigjson` on the secret.
+	DockerRegistrySources []DockerRegistryRecipe `json:"dockerRegistries,omitempty" yaml:"dockerRegistries,omitempty"`
+	// A list of credentials to include as htpasswd entries (with bcrypt hashed passwords) on the secret.
+	HTPasswdSources []HTPasswdRecipe `json:"htpasswd,omitempty" yaml:"htpasswd,omitempty"`
 
 	// Additional configuration for generating passwords.
 	PasswordOptions *password.GeneratorInput `json:"-" yaml:"-"`
 }
 
+// ConfigMap is used to expand a ConfigMap resource.
+type ConfigMap struct {
+	// The name of the config map to generate.
+	ConfigMapName string `json:"configMapName" yaml:"configMapName"`
+	// The namespace of the config map to generate.
+	Namespace string `json:"namespace,omitempty" yaml:"namespace,omitempty"`
+	// Flag indicating the generated config map should be immutable.
+	Immutable bool `json:"immutable,omitempty" yaml:"immutable,omitempty"`
+	// Flag indicating a hash of the config map contents should be appended to the name; references to the config
+	// map from workloads in the same stream are updated to use the suffixed name.
+	AppendHash bool `json:"appendHash,omitempty" yaml:"appendHash,omitempty"`
+
+	// A list of `key=value` pairs to include on the config map.
+	LiteralSources []string `json:"literals,omitempty" yaml:"literals,omitempty"`
+	// A list of files, directories (or `key=filename` pairs) to include on the config map. Files which are not
+	// valid UTF-8 are included as binary data.
+	FileSources []string `json:"files,omitempty" yaml:"files,omitempty"`
+	// A list of .env files (files containing `key=value` pairs) to include on the config map.
+	EnvSources []string `json:"envs,omitempty" yaml:"envs,omitempty"`
+}
+
 // Git is used to expand full or partial Git repositories.
 type Git struct {
 	// The Git repository URL.
@@ -177,3 +300,89 @@ type File struct {
 	// The file (or directory) name to read.
 	Path string `json:"path" yaml:"path"`
 }
+
+// Selector selects resources based on their metadata using regular expressions or Kubernetes selectors.
+type Selector struct {
+	// Regular expression matching the group.
+	Group string `json:"group,omitempty" yaml:"group,omitempty"`
+	// Regular expression matching the version.
+	Version string `json:"version,omitempty" yaml:"version,omitempty"`
+	// Regular expression matching the kind.
+	Kind string `json:"kind,omitempty" yaml:"kind,omitempty"`
+	// Regular expression matching the namespace.
+	Namespace string `json:"namespace,omitempty" yaml:"namespace,omitempty"`
+	// Regular expression matching the name.
+	Name string `json:"name,omitempty" yaml:"name,omitempty"`
+	// Kubernetes selector matching labels.
+	LabelSelector string `json:"labelSelector,omitempty" yaml:"labelSelector,omitempty"`
+	// Kubernetes selector matching annotations.
+	AnnotationSelector string `json:"annotationSelector,omitempty" yaml:"annotationSelector,omitempty"`
+	// CEL expression evaluated against each resource (available as `object`).
+	Expression string `json:"expression,omitempty" yaml:"expression,omitempty"`
+	// Invert the matching behavior (i.e. select non-matching resources).
+	InvertMatch bool `json:"invertMatch,omitempty" yaml:"invertMatch,omitempty"`
+}
+
+// Patch is used to patch a subset of the resources in an overlay.
+type Patch struct {
+	// Selects the resources to patch, all resources are patched by default.
+	Target *Selector `json:"target,omitempty" yaml:"target,omitempty"`
+	// The patch type, one of "strategic" (default), "merge" or "json".
+	Type string `json:"type,omitempty" yaml:"type,omitempty"`
+	// The patch to apply (as YAML or JSON).
+	Patch string `json:"patch" yaml:"patch"`
+}
+
+// Overlay is used to transform a nested set of resources without affecting any other resources.
+type Overlay struct {
+	// The list of URL-like specifications of the resources to transform.
+	Resources []string `json:"resources" yaml:"resources"`
+	// The namespace to set on the resources.
+	Namespace string `json:"namespace,omitempty" yaml:"namespace,omitempty"`
+	// Labels to add to the resources, including pod templates and selectors.
+	Labels map[string]string `json:"labels,omitempty" yaml:"labels,omitempty"`
+	// The prefix to add to the resource names.
+	NamePrefix string `json:"namePrefix,omitempty" yaml:"namePrefix,omitempty"`
+	// The patches to apply to the resources.
+	Patches []Patch `json:"patches,omitempty" yaml:"patches,omitempty"`
+}
+
+// Replacement is used to copy a field value between a nested set of resources.
+type Replacement struct {
+	// The list of URL-like specifications of the resources to transform.
+	Resources []string `json:"resources" yaml:"resources"`
+	// The source of the value to copy.
+	Source ReplacementSource `json:"source" yaml:"source"`
+	// The targets the value is copied into.
+	Targets []ReplacementTarget `json:"targets" yaml:"targets"`
+}
+
+// ReplacementSource selects the value to copy into other resources.
+type ReplacementSource struct {
+	// Selects the source resource, exactly one resource must match.
+	Selector `json:",inline" yaml:",inline"`
+	// The "/" separated path template of the source value, defaults to "metadata/name".
+	FieldPath string `json:"fieldPath,omitempty" yaml:"fieldPath,omitempty"`
+	// Options for using only part of the source value.
+	Options *ReplacementOptions `json:"options,omitempty" yaml:"options,omitempty"`
+}
+
+// ReplacementTarget selects the fields to copy a value into.
+type ReplacementTarget struct {
+	// Selects the target resources.
+	Selector `json:",inline" yaml:",inline"`
+	// The "/" separated path templates of the target fields.
+	FieldPaths []string `json:"fieldPaths" yaml:"fieldPaths"`
+	// Options for replacing only part of the target values.
+	Options *ReplacementOptions `json:"options,omitempty" yaml:"options,omitempty"`
+}
+
+// ReplacementOptions are used to select part of a delimited string value.
+type ReplacementOptions struct {
+	// The delimiter used to split the value.
+	Delimiter string `json:"delimiter,omitempty" yaml:"delimiter,omitempty"`
+	// The index of the part of the value, ignored if there is no delimiter.
+	Index int `json:"index,omitempty" yaml:"index,omitempty"`
+	// Flag indicating that missing target fields should be created.
+	Create bool `json:"create,omitempty" yaml:"create,omitempty"`
+}
@@ -2,6 +2,7 @@ package filters
 
 import (
 	"regexp"
+	"slices"
 	"strings"
 
 	"github.com/thestormforge/konjure/internal/application"
@@ -21,7 +22,9 @@ type ApplicationFilter struct {
 }
 
 // Filter keeps all the application resources and creates application resources
-// for all other nodes that are not associated with an application.
+// for all other nodes that are not associated with an application. Argo CD and
+// Flux application definitions and Helm releases are converted to application
+// resources which own the resources they track.
 func (f *ApplicationFilter) Filter(nodes []*yaml.RNode) ([]*yaml.RNode, error) {
 	if !f.Enabled {
 		return nodes, nil
@@ -31,6 +34,23 @@ func (f *ApplicationFilter) Filter(nodes []*yaml.RNode) ([]*yaml.RNode, error) {
 	var err error
 	var scannedAppLabels bool
 
+	// Create applications for the Helm releases which installed resources
+	nodes = slices.Clone(nodes) // Do not append to the caller's slice
+	releases := make(map[string]bool)
+	for _, node := range nodes {
+		app, err := application.FromHelmRelease(node)
+		if err != nil {
+			return nil, err
+		}
+		if app == nil {
+			continue
+		}
+		if key := app.GetNamespace() + "/" + app.GetName(); !releases[key] {
+			releases[key] = true
+			nodes = append(nodes, app)
+		}
+	}
+
 IndexApps:
 
 	// Index the existing applications
@@ -4,6 +4,10 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/thestormforge/konjure/internal/application"
+	"sigs.k8s.io/kustomize/kyaml/kio"
+	"sigs.k8s.io/kustomize/kyaml/yaml"
 )
 
 func TestSplitHelmChart(t *testing.T) {
@@ -52,3 +56,155 @@ func TestSplitHelmChart(t *testing.T) {
 		})
 	}
 }
+
+func TestApplicationFilter_Origins(t *testing.T) {
+	nodes, err := kio.FromBytes([]byte(`
+apiVersion: argoproj.io/v1alpha1
+kind: Application
+metadata:
+  name: guestbook
+  namespace: argocd
+spec:
+  destination:
+    namespace: default
+---
+apiVersion: argoproj.io/v1alpha1
+kind: ApplicationSet
+metadata:
+  name: guestbooks
+  namespace: argocd
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: guestbook-ui
+  namespace: default
+  annotations:
+    argocd.argoproj.io/tracking-id: guestbook:apps/Deployment:default/guestbook-ui
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: guestbook-ui
+  namespace: default
+  labels:
+    app.kubernetes.io/instance: guestbook
+---
+apiVersion: kustomize.toolkit.fluxcd.io/v1
+kind: Kustomization
+metadata:
+  name: infra
+  namespace: flux-system
+spec:
+  targetNamespace: infra
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: settings
+  namespace: infra
+  labels:
+    kustomize.toolkit.fluxcd.io/name: infra
+    kustomize.toolkit.fluxcd.io/namespace: flux-system
+---
+apiVersion: helm.toolkit.fluxcd.io/v2
+kind: HelmRelease
+metadata:
+  name: podinfo
+  namespace: flux-system
+spec:
+  targetNamespace: podinfo
+  chart:
+    spec:
+      chart: podinfo
+      version: 6.5.0
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: podinfo
+  namespace: podinfo
+  labels:
+    helm.toolkit.fluxcd.io/name: podinfo
+    helm.toolkit.fluxcd.io/namespace: flux-system
+  annotations:
+    meta.helm.sh/release-name: podinfo-podinfo
+    meta.helm.sh/release-namespace: podinfo
+---
+apiVersion: v1
+kind: Secret
+metadata:
+  name: redis
+  namespace: cache
+  annotations:
+    meta.helm.sh/release-name: redis
+    meta.helm.sh/release-namespace: cache
+`))
+	require.NoError(t, err)
+
+	// The input slice has room to grow, the filter must not use it
+	input := make([]*yaml.RNode, len(nodes), len(nodes)+10)
+	copy(input, nodes)
+
+	f := &ApplicationFilter{Enabled: true}
+	apps, err := f.Filter(input)
+	require.NoError(t, err)
+	for _, n := range input[len(nodes):cap(input)] {
+		assert.Nil(t, n)
+	}
+
+	type app struct {
+		Namespace      string
+		Origin         string
+		OriginName     string
+		ComponentKinds []string
+		Type           string
+	}
+	actual := make(map[string]app)
+	for _, n := range apps {
+		a := app{
+			Namespace:  n.GetNamespace(),
+			Origin:     n.GetAnnotations()[application.AnnotationOrigin],
+			OriginName: n.GetAnnotations()[application.AnnotationOriginName],
+		}
+		kinds, err := n.Pipe(yaml.Lookup("spec", "componentKinds"))
+		require.NoError(t, err)
+		if kinds != nil {
+			for _, k := range kinds.Content() {
+				a.ComponentKinds = append(a.ComponentKinds, yaml.GetValue(yaml.NewRNode(k).Field("kind").Value))
+			}
+		}
+		appType, err := n.Pipe(yaml.Lookup("spec", "descriptor", "type"))
+		require.NoError(t, err)
+		a.Type = yaml.GetValue(appType)
+		actual[n.GetName()] = a
+	}
+
+	assert.Equal(t, map[string]app{
+		"guestbook": {
+			Namespace:      "default",
+			Origin:         application.OriginArgoCDApplication,
+			OriginName:     "argocd/guestbook",
+			ComponentKinds: []string{"Deployment", "Service"},
+		},
+		"infra": {
+			Namespace:      "infra",
+			Origin:         application.OriginFluxKustomization,
+			OriginName:     "flux-system/infra",
+			ComponentKinds: []string{"ConfigMap"},
+		},
+		"podinfo": {
+			Namespace:      "podinfo",
+			Origin:         application.OriginFluxHelmRelease,
+			OriginName:     "flux-system/podinfo",
+			ComponentKinds: []string{"Deployment"},
+			Type:           "podinfo",
+		},
+		"redis": {
+			Namespace:      "cache",
+			Origin:         application.OriginHelmRelease,
+			OriginName:     "cache/redis",
+			ComponentKinds: []string{"Secret"},
+		},
+	}, actual)
+}
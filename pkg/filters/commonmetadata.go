@@ -0,0 +1,188 @@
+/*
+Copyright 2022 GramLabs, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package filters
+
+import (
+	"maps"
+	"slices"
+
+	"sigs.k8s.io/kustomize/kyaml/yaml"
+)
+
+// LiveAnnotation is the annotation used to indicate that a resource was read
+// from a cluster (e.g. using a `k8s:` resource specification).
+const LiveAnnotation = "konjure.stormforge.io/live"
+
+// CommonMetadataFilter adds labels and annotations to every resource. Labels
+// can optionally be propagated into the pod and volume claim templates and
+// into the selectors of workloads and services.
+type CommonMetadataFilter struct {
+	// Labels to add to every resource.
+	Labels map[string]string `json:"labels,omitempty" yaml:"labels,omitempty"`
+	// Annotations to add to every resource.
+	Annotations map[string]string `json:"annotations,omitempty" yaml:"annotations,omitempty"`
+	// Flag indicating that labels and annotations should also be added to templates.
+	IncludeTemplates bool `json:"includeTemplates,omitempty" yaml:"includeTemplates,omitempty"`
+	// Flag indicating that labels should also be added to selectors (implies `IncludeTemplates`).
+	IncludeSelectors bool `json:"includeSelectors,omitempty" yaml:"includeSelectors,omitempty"`
+	// Flag indicating that immutable selectors (and volume claim templates) should
+	// not be altered on resources read from a cluster (i.e. resources annotated with
+	// `LiveAnnotation`). The labels are still added to the pod templates so the
+	// selectors continue to match.
+	ProtectImmutableSelectors bool `json:"protectImmutableSelectors,omitempty" yaml:"protectImmutableSelectors,omitempty"`
+}
+
+// selectorPath is the location of a label selector.
+type selectorPath struct {
+	path      []string
+	create    bool
+	immutable bool
+}
+
+// selectorPaths are the label selectors of well known types, indexed by "Kind.group".
+var selectorPaths = map[string][]selectorPath{
+	"Deployment.apps":  {{path: []string{"spec", "selector", "matchLabels"}, create: true, immutable: true}},
+	"StatefulSet.apps": {{path: []string{"spec", "selector", "matchLabels"}, create: true, immutable: true}},
+	"DaemonSet.apps":   {{path: []string{"spec", "selector", "matchLabels"}, create: true, immutable: true}},
+	"ReplicaSet.apps":  {{path: []string{"spec", "selector", "matchLabels"}, create: true, immutable: true}},
+	"Service":          {{path: []string{"spec", "selector"}}},
+}
+
+// templatePaths are the paths to the metadata of (pod and job) templates.
+var templatePaths = [][]string{
+	{"spec", "template", "metadata"},
+	{"spec", "jobTemplate", "metadata"},
+	{"spec", "jobTemplate", "spec", "template", "metadata"},
+}
+
+// Filter adds the labels and annotations.
+func (f *CommonMetadataFilter) Filter(nodes []*yaml.RNode) ([]*yaml.RNode, error) {
+	if len(f.Labels) == 0 && len(f.Annotations) == 0 {
+		return nodes, nil
+	}
+
+	for _, node := range nodes {
+		if err := setEntries(node, []string{yaml.MetadataField, yaml.LabelsField}, true, f.Labels); err != nil {
+			return nil, err
+		}
+		if err := setEntries(node, []string{yaml.MetadataField, yaml.AnnotationsField}, true, f.Annotations); err != nil {
+			return nil, err
+		}
+
+		if f.IncludeTemplates || f.IncludeSelectors {
+			if err := f.templates(node); err != nil {
+				return nil, err
+			}
+		}
+
+		if f.IncludeSelectors {
+			if err := f.selectors(node); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return nodes, nil
+}
+
+// templates adds the labels and annotations to the templates of the supplied node.
+func (f *CommonMetadataFilter) templates(node *yaml.RNode) error {
+	for _, p := range templatePaths {
+		template, err := node.Pipe(yaml.Lookup(p[:len(p)-1]...))
+		if err != nil {
+			return err
+		}
+		if template == nil {
+			continue
+		}
+
+		if err := setEntries(template, []string{yaml.MetadataField, yaml.LabelsField}, true, f.Labels); err != nil {
+			return err
+		}
+		if err := setEntries(template, []string{yaml.MetadataField, yaml.AnnotationsField}, true, f.Annotations); err != nil {
+			return err
+		}
+	}
+
+	// Volume claim templates are immutable, like selectors
+	if live, err := f.isLive(node); err != nil || live {
+		return err
+	}
+
+	// Only the labels are added to volume claim templates
+	return node.PipeE(TeeMatched(
+		yaml.PathMatcher{Path: []string{"spec", "volumeClaimTemplates", "*"}},
+		yaml.FilterFunc(func(rn *yaml.RNode) (*yaml.RNode, error) {
+			return rn, setEntries(rn, []string{yaml.MetadataField, yaml.LabelsField}, true, f.Labels)
+		}),
+	))
+}
+
+// selectors adds the labels to the selectors of the supplied node.
+func (f *CommonMetadataFilter) selectors(node *yaml.RNode) error {
+	live, err := f.isLive(node)
+	if err != nil {
+		return err
+	}
+
+	for _, sp := range selectorPaths[groupKind(node.GetApiVersion(), node.GetKind())] {
+		if sp.immutable && live {
+			continue
+		}
+
+		if err := setEntries(node, sp.path, sp.create, f.Labels); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// isLive checks if immutable fields of the supplied node must be protected because
+// it was read from a cluster.
+func (f *CommonMetadataFilter) isLive(node *yaml.RNode) (bool, error) {
+	if !f.ProtectImmutableSelectors {
+		return false, nil
+	}
+
+	live, err := node.Pipe(yaml.GetAnnotation(LiveAnnotation))
+	return yaml.GetValue(live) == "true", err
+}
+
+// setEntries sets the string values on the map at the specified path.
+func setEntries(node *yaml.RNode, path []string, create bool, values map[string]string) error {
+	if len(values) == 0 {
+		return nil
+	}
+
+	var m *yaml.RNode
+	var err error
+	if create {
+		m, err = node.Pipe(yaml.LookupCreate(yaml.MappingNode, path...))
+	} else {
+		m, err = node.Pipe(yaml.Lookup(path...))
+	}
+	if err != nil || m == nil {
+		return err
+	}
+
+	for _, k := range slices.Sorted(maps.Keys(values)) {
+		if err := m.PipeE(yaml.SetField(k, yaml.NewStringRNode(values[k]))); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,320 @@
+/*
+Copyright 2022 GramLabs, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package filters
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"sigs.k8s.io/kustomize/kyaml/kio"
+)
+
+func TestCommonMetadataFilter_Filter(t *testing.T) {
+	cases := []struct {
+		desc     string
+		filter   CommonMetadataFilter
+		input    string
+		expected string
+	}{
+		{
+			desc: "metadata only",
+			filter: CommonMetadataFilter{
+				Labels:      map[string]string{"app.kubernetes.io/part-of": "test"},
+				Annotations: map[string]string{"owner": "team"},
+			},
+			input: `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: test
+spec:
+  selector:
+    matchLabels:
+      app: test
+  template:
+    metadata:
+      labels:
+        app: test
+`,
+			expected: `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: test
+  labels:
+    app.kubernetes.io/part-of: test
+  annotations:
+    owner: team
+spec:
+  selector:
+    matchLabels:
+      app: test
+  template:
+    metadata:
+      labels:
+        app: test
+`,
+		},
+		{
+			desc: "templates",
+			filter: CommonMetadataFilter{
+				Labels:           map[string]string{"team": "a"},
+				Annotations:      map[string]string{"owner": "team"},
+				IncludeTemplates: true,
+			},
+			input: `apiVersion: apps/v1
+kind: StatefulSet
+metadata:
+  name: test
+spec:
+  selector:
+    matchLabels:
+      app: test
+  template:
+    metadata:
+      labels:
+        app: test
+  volumeClaimTemplates:
+  - metadata:
+      name: data
+---
+apiVersion: batch/v1
+kind: CronJob
+metadata:
+  name: test
+spec:
+  jobTemplate:
+    spec:
+      template:
+        spec:
+          containers:
+          - name: test
+`,
+			expected: `apiVersion: apps/v1
+kind: StatefulSet
+metadata:
+  name: test
+  labels:
+    team: a
+  annotations:
+    owner: team
+spec:
+  selector:
+    matchLabels:
+      app: test
+  template:
+    metadata:
+      labels:
+        app: test
+        team: a
+      annotations:
+        owner: team
+  volumeClaimTemplates:
+  - metadata:
+      name: data
+      labels:
+        team: a
+---
+apiVersion: batch/v1
+kind: CronJob
+metadata:
+  name: test
+  labels:
+    team: a
+  annotations:
+    owner: team
+spec:
+  jobTemplate:
+    spec:
+      template:
+        spec:
+          containers:
+          - name: test
+        metadata:
+          labels:
+            team: a
+          annotations:
+            owner: team
+    metadata:
+      labels:
+        team: a
+      annotations:
+        owner: team
+`,
+		},
+		{
+			desc: "selectors",
+			filter: CommonMetadataFilter{
+				Labels:                    map[string]string{"team": "a"},
+				IncludeSelectors:          true,
+				ProtectImmutableSelectors: true,
+			},
+			input: `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: new
+spec:
+  selector:
+    matchLabels:
+      app: new
+  template:
+    metadata:
+      labels:
+        app: new
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: live
+  annotations:
+    konjure.stormforge.io/live: "true"
+spec:
+  selector:
+    matchLabels:
+      app: live
+  template:
+    metadata:
+      labels:
+        app: live
+---
+apiVersion: apps/v1
+kind: StatefulSet
+metadata:
+  name: live-db
+  annotations:
+    konjure.stormforge.io/live: "true"
+spec:
+  selector:
+    matchLabels:
+      app: db
+  template:
+    metadata:
+      labels:
+        app: db
+  volumeClaimTemplates:
+  - metadata:
+      name: data
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: selector
+spec:
+  selector:
+    app: new
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: external
+spec:
+  type: ExternalName
+  externalName: example.com
+`,
+			expected: `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: new
+  labels:
+    team: a
+spec:
+  selector:
+    matchLabels:
+      app: new
+      team: a
+  template:
+    metadata:
+      labels:
+        app: new
+        team: a
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: live
+  annotations:
+    konjure.stormforge.io/live: "true"
+  labels:
+    team: a
+spec:
+  selector:
+    matchLabels:
+      app: live
+  template:
+    metadata:
+      labels:
+        app: live
+        team: a
+---
+apiVersion: apps/v1
+kind: StatefulSet
+metadata:
+  name: live-db
+  annotations:
+    konjure.stormforge.io/live: "true"
+  labels:
+    team: a
+spec:
+  selector:
+    matchLabels:
+      app: db
+  template:
+    metadata:
+      labels:
+        app: db
+        team: a
+  volumeClaimTemplates:
+  - metadata:
+      name: data
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: selector
+  labels:
+    team: a
+spec:
+  selector:
+    app: new
+    team: a
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: external
+  labels:
+    team: a
+spec:
+  type: ExternalName
+  externalName: example.com
+`,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.desc, func(t *testing.T) {
+			nodes, err := kio.FromBytes([]byte(c.input))
+			require.NoError(t, err)
+
+			actual, err := c.filter.Filter(nodes)
+			if assert.NoError(t, err) {
+				out, err := kio.StringAll(actual)
+				if assert.NoError(t, err) {
+					assert.Equal(t, c.expected, out)
+				}
+			}
+		})
+	}
+}
@@ -17,6 +17,9 @@ limitations under the License.
 package filters
 
 import (
+	"fmt"
+	"regexp"
+	"slices"
 	"strconv"
 	"strings"
 	"text/template"
@@ -26,7 +29,9 @@ import (
 )
 
 // FieldPath evaluates a path template using the supplied context and then
-// splits it into individual path segments (honoring escaped delimiters).
+// splits it into individual path segments (honoring escaped delimiters). List
+// selectors attached to field names (e.g. `containers[*]`) become separate
+// segments suitable for use with `SetPath`.
 func FieldPath(p string, data map[string]string) ([]string, error) {
 	// Evaluate the path as a Go Template
 	t, err := template.New("path").
@@ -42,13 +47,31 @@ func FieldPath(p string, data map[string]string) ([]string, error) {
 		return nil, err
 	}
 
-	return cleanPath(utils.SmarterPathSplitter(pathBuf.String(), "/")), nil
+	path := cleanPath(utils.SmarterPathSplitter(pathBuf.String(), "/"))
+	if path == nil {
+		return nil, nil
+	}
+	return splitListSelectors(path), nil
 }
 
 // SetPath returns a filter that sets the node value at the specified path.
-// Note that this path uses "." separators rather then "/".
+// Note that this path uses "." separators rather then "/". The path may include
+// list selectors (e.g. `containers[name=app]`), wildcards matching every list
+// element (e.g. `containers[*]`) and `**` to match fields at any depth; the
+// value is set (or cleared) on every match. Missing fields and selected list
+// elements are created, however elements matched by a wildcard and the field
+// immediately following a `**` must already exist.
 func SetPath(p string, v *yaml.RNode) yaml.Filter {
-	path := cleanPath(utils.SmarterPathSplitter(p, "."))
+	return setPath(cleanPath(utils.SmarterPathSplitter(p, ".")), v)
+}
+
+// setPath returns a filter that sets the node value at the specified path segments.
+func setPath(path []string, v *yaml.RNode) yaml.Filter {
+	path = splitListSelectors(path)
+	if slices.ContainsFunc(path, isPathWildcard) {
+		f := setMatched(path, v, false)
+		return yaml.FilterFunc(func(object *yaml.RNode) (*yaml.RNode, error) { return object, object.PipeE(f) })
+	}
 
 	var fns []yaml.Filter
 	if yaml.IsMissingOrNull(v) {
@@ -83,6 +106,133 @@ func SetValues(nameValue []string, forceString bool) yaml.Filter {
 	return yaml.FilterFunc(func(object *yaml.RNode) (*yaml.RNode, error) { return object.Pipe(fns...) })
 }
 
+// setMatched returns a filter that sets (or clears) the node value at every match
+// of a path containing wildcards. When descent is true, the first path segment
+// must already exist.
+func setMatched(path []string, v *yaml.RNode, descent bool) yaml.Filter {
+	// Split the path at the first descent and apply the remainder at every depth
+	if i := slices.Index(path, "**"); i >= 0 {
+		f := teeDescendants(setMatched(path[i+1:], v, true))
+		if i == 0 {
+			return f
+		}
+		return TeeMatched(matchPath(path[:i]), f)
+	}
+
+	if len(path) == 0 {
+		return yaml.FilterFunc(func(*yaml.RNode) (*yaml.RNode, error) {
+			return nil, fmt.Errorf("path must not end with a descent")
+		})
+	}
+
+	if yaml.IsMissingOrNull(v) {
+		l := len(path) - 1
+		if isPathWildcard(path[l]) || yaml.IsListIndex(path[l]) {
+			return yaml.FilterFunc(func(*yaml.RNode) (*yaml.RNode, error) {
+				return nil, fmt.Errorf("cannot clear list elements matched by %q", path[l])
+			})
+		}
+		if l == 0 {
+			return &yaml.FieldClearer{Name: path[l]}
+		}
+		return TeeMatched(matchPath(path[:l]), &yaml.FieldClearer{Name: path[l], IfEmpty: true})
+	}
+
+	// Only the segments after the last wildcard can be created
+	n := 0
+	for i, p := range path {
+		if isPathWildcard(p) {
+			n = i + 1
+		}
+	}
+	if descent && n == 0 {
+		n = 1
+	}
+
+	set := yaml.FilterFunc(func(rn *yaml.RNode) (*yaml.RNode, error) {
+		rn.SetYNode(yaml.CopyYNode(v.YNode()))
+		return rn, nil
+	})
+	if n == len(path) {
+		return TeeMatched(matchPath(path), set)
+	}
+
+	create := yaml.FilterFunc(func(rn *yaml.RNode) (*yaml.RNode, error) {
+		return rn.Pipe(&yaml.PathGetter{Path: path[n:], Create: v.YNode().Kind}, set)
+	})
+	if n == 0 {
+		return create
+	}
+	return TeeMatched(matchPath(path[:n]), create)
+}
+
+// teeDescendants applies the filter to the node and every node below it.
+func teeDescendants(f yaml.Filter) yaml.Filter {
+	return yaml.FilterFunc(func(rn *yaml.RNode) (*yaml.RNode, error) {
+		// Collect the nodes first so nodes created by the filter are not visited
+		var nodes []*yaml.RNode
+		var collect func(*yaml.Node)
+		collect = func(n *yaml.Node) {
+			switch n.Kind {
+			case yaml.MappingNode:
+				nodes = append(nodes, yaml.NewRNode(n))
+				for i := 1; i < len(n.Content); i += 2 {
+					collect(n.Content[i])
+				}
+			case yaml.SequenceNode:
+				for _, e := range n.Content {
+					collect(e)
+				}
+			}
+		}
+		collect(rn.YNode())
+
+		for _, node := range nodes {
+			if err := node.PipeE(f); err != nil {
+				return nil, err
+			}
+		}
+		return rn, nil
+	})
+}
+
+// matchPath returns a path matcher for the supplied segments. Unlike the
+// regular expressions normally used by the path matcher, list selectors must
+// match the entire value.
+func matchPath(path []string) yaml.PathMatcher {
+	result := make([]string, len(path))
+	for i, p := range path {
+		if name, value, err := yaml.SplitIndexNameValue(p); err == nil && yaml.IsListIndex(p) {
+			p = "[" + name + "=^" + regexp.QuoteMeta(value) + "$]"
+		}
+		result[i] = p
+	}
+	return yaml.PathMatcher{Path: result}
+}
+
+// splitListSelectors separates list selectors attached to field names (e.g.
+// `containers[name=app]` becomes `containers` and `[name=app]`) and converts
+// the `[*]` selector into a wildcard.
+func splitListSelectors(path []string) []string {
+	result := make([]string, 0, len(path))
+	for _, p := range path {
+		if i := strings.IndexByte(p, '['); i > 0 && strings.HasSuffix(p, "]") {
+			result = append(result, p[:i])
+			p = p[i:]
+		}
+		if p == "[*]" {
+			p = "*"
+		}
+		result = append(result, p)
+	}
+	return result
+}
+
+// isPathWildcard checks if the path segment matches multiple nodes.
+func isPathWildcard(p string) bool {
+	return yaml.IsWildcard(p) || p == "**"
+}
+
 // cleanPath removes all empty and white space path elements.
 func cleanPath(path []string) []string {
 	result := make([]string, 0, len(path))
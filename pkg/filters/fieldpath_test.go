@@ -40,6 +40,12 @@ func TestFieldPath(t *testing.T) {
 			path:     "/foo/[bar=a/b]",
 			expected: []string{"foo", "[bar=a/b]"},
 		},
+		{
+			desc:     "attached selectors",
+			path:     "/spec/containers[*]/env[name={.x}]/value",
+			data:     map[string]string{"x": "TEST"},
+			expected: []string{"spec", "containers", "*", "env", "[name=TEST]", "value"},
+		},
 	}
 	for _, tc := range cases {
 		t.Run(tc.desc, func(t *testing.T) {
@@ -115,6 +121,190 @@ abc:
 			expected: `#
 foo: bar
 abc: {}
+`,
+		},
+		{
+			desc: "wildcard",
+			inputs: []*yaml.RNode{yaml.MustParse(`#
+spec:
+  containers:
+  - name: app
+    resources:
+      limits:
+        cpu: 2
+  - name: sidecar
+`)},
+			specs: []string{"spec.containers[*].resources.limits.cpu=1"},
+			expected: `#
+spec:
+  containers:
+  - name: app
+    resources:
+      limits:
+        cpu: 1
+  - name: sidecar
+    resources:
+      limits:
+        cpu: 1
+`,
+		},
+		{
+			desc: "wildcard no create",
+			inputs: []*yaml.RNode{yaml.MustParse(`#
+spec:
+  replicas: 1
+`)},
+			specs: []string{"spec.containers.[*].image=nginx"},
+			expected: `#
+spec:
+  replicas: 1
+`,
+		},
+		{
+			desc: "wildcard unset",
+			inputs: []*yaml.RNode{yaml.MustParse(`#
+spec:
+  containers:
+  - name: app
+    image: nginx
+  - name: sidecar
+    image: busybox
+`)},
+			specs: []string{"spec.containers[*].image=null"},
+			expected: `#
+spec:
+  containers:
+  - name: app
+  - name: sidecar
+`,
+		},
+		{
+			desc: "attached selector",
+			inputs: []*yaml.RNode{yaml.MustParse(`#
+spec:
+  containers:
+  - name: myapp
+    image: busybox
+  - name: app
+    image: busybox
+`)},
+			specs: []string{"spec.containers[name=app].image=nginx"},
+			expected: `#
+spec:
+  containers:
+  - name: myapp
+    image: busybox
+  - name: app
+    image: nginx
+`,
+		},
+		{
+			desc: "attached selector create",
+			inputs: []*yaml.RNode{yaml.MustParse(`#
+spec:
+  containers:
+  - name: app
+`)},
+			specs: []string{"spec.containers[name=sidecar].image=busybox"},
+			expected: `#
+spec:
+  containers:
+  - name: app
+  - name: sidecar
+    image: busybox
+`,
+		},
+		{
+			desc: "wildcard selector create",
+			inputs: []*yaml.RNode{yaml.MustParse(`#
+items:
+- containers:
+  - name: app
+- containers: []
+`)},
+			specs: []string{"items[*].containers[name=app].image=nginx"},
+			expected: `#
+items:
+- containers:
+  - name: app
+    image: nginx
+- containers:
+  - name: app
+    image: nginx
+`,
+		},
+		{
+			desc: "descent",
+			inputs: []*yaml.RNode{yaml.MustParse(`#
+spec:
+  template:
+    spec:
+      initContainers:
+      - name: init
+        image: busybox
+      containers:
+      - name: app
+        image: nginx
+      - name: sidecar
+`)},
+			specs: []string{"**.image=alpine"},
+			expected: `#
+spec:
+  template:
+    spec:
+      initContainers:
+      - name: init
+        image: alpine
+      containers:
+      - name: app
+        image: alpine
+      - name: sidecar
+`,
+		},
+		{
+			desc: "descent create",
+			inputs: []*yaml.RNode{yaml.MustParse(`#
+spec:
+  jobTemplate:
+    spec:
+      template:
+        spec:
+          containers:
+          - name: app
+  template:
+    spec:
+      containers:
+      - name: app
+`)},
+			specs: []string{"spec.**.containers[*].securityContext.runAsNonRoot=true"},
+			expected: `#
+spec:
+  jobTemplate:
+    spec:
+      template:
+        spec:
+          containers:
+          - name: app
+            securityContext:
+              runAsNonRoot: true
+  template:
+    spec:
+      containers:
+      - name: app
+        securityContext:
+          runAsNonRoot: true
+`,
+		},
+		{
+			desc: "descent no create",
+			inputs: []*yaml.RNode{yaml.MustParse(`#
+foo:
+  bar: baz
+`)},
+			specs: []string{"**.missing.value=1"},
+			expected: `#
+foo:
+  bar: baz
 `,
 		},
 	}
@@ -24,8 +24,6 @@ import (
 	"sigs.k8s.io/kustomize/kyaml/kio"
 	"sigs.k8s.io/kustomize/kyaml/openapi"
 	"sigs.k8s.io/kustomize/kyaml/yaml"
-	"sigs.k8s.io/kustomize/kyaml/yaml/merge2"
-	"sigs.k8s.io/kustomize/kyaml/yaml/walk"
 )
 
 // FilterOne is the opposite of kio.FilterAll, useful if you have a filter that
@@ -140,19 +138,36 @@ func (f TeeMatchedFilter) visitMatched(node *yaml.RNode) error {
 }
 
 // Flatten never returns more than a single node, every other node is merged
-// into that first node using the supplied schema
+// into that first node using strategic merge patch semantics. If a schema is
+// not supplied, the schema of the first node's type is used (including the
+// schemas of any custom resource definitions in the other nodes).
 func Flatten(schema *spec.Schema) kio.Filter {
 	return kio.FilterFunc(func(nodes []*yaml.RNode) ([]*yaml.RNode, error) {
-		w := walk.Walker{Visitor: merge2.Merger{}}
+		if len(nodes) < 2 {
+			return nodes, nil
+		}
+
+		var rs *openapi.ResourceSchema
 		if schema != nil {
-			w.Schema = &openapi.ResourceSchema{Schema: schema}
+			rs = &openapi.ResourceSchema{Schema: schema}
+		} else {
+			schemas, err := NewResourceSchemas(nodes)
+			if err != nil {
+				return nil, err
+			}
+			rs = schemas.SchemaForResourceType(yaml.TypeMeta{APIVersion: nodes[0].GetApiVersion(), Kind: nodes[0].GetKind()})
 		}
 
+		m := &strategicMerge{strategic: true}
 		for i := len(nodes); i > 1; i-- {
-			w.Sources = nodes[i-2 : i]
-			if _, err := w.Walk(); err != nil {
+			result, err := m.merge(nodes[i-2].YNode(), nodes[i-1].YNode(), rs)
+			if err != nil {
 				return nil, err
 			}
+			if result == nil {
+				result = &yaml.Node{Kind: yaml.MappingNode}
+			}
+			nodes[i-2].SetYNode(result)
 			nodes = nodes[:i-1]
 		}
 		return nodes, nil
@@ -0,0 +1,142 @@
+/*
+Copyright 2022 GramLabs, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package filters
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+
+	"sigs.k8s.io/kustomize/kyaml/yaml"
+)
+
+// HashSuffixAnnotation is the annotation used to indicate that a Secret or
+// ConfigMap should have a hash of its contents appended to its name.
+const HashSuffixAnnotation = "konjure.stormforge.io/hash-suffix"
+
+// HashSuffixFilter appends a content hash to the names of Secrets and
+// ConfigMaps annotated with `HashSuffixAnnotation` and rewrites references to
+// those resources from other resources in the same stream (see `DefaultNameReferences`).
+// Changing the contents of a suffixed resource will therefore trigger a rollout
+// of the workloads that consume it.
+type HashSuffixFilter struct {
+	// Flag indicating if this filter should act as a pass-through, the annotations are retained when disabled.
+	Enabled bool
+}
+
+// Filter renames the annotated resources and updates references to them.
+func (f *HashSuffixFilter) Filter(nodes []*yaml.RNode) ([]*yaml.RNode, error) {
+	if !f.Enabled {
+		return nodes, nil
+	}
+
+	names := make(resourceNames)
+	for _, node := range nodes {
+		if node.GetAnnotations()[HashSuffixAnnotation] != "true" {
+			continue
+		}
+
+		md, err := node.GetMeta()
+		if err != nil {
+			return nil, err
+		}
+		if md.APIVersion != "v1" || (md.Kind != "Secret" && md.Kind != "ConfigMap") {
+			continue
+		}
+
+		h, err := contentHash(node, md.Kind, md.Name)
+		if err != nil {
+			return nil, err
+		}
+
+		name := md.Name + "-" + h
+		if err := node.PipeE(
+			yaml.Tee(yaml.ClearAnnotation(HashSuffixAnnotation)),
+			yaml.SetK8sName(name),
+		); err != nil {
+			return nil, err
+		}
+
+		names.add(md, name, nil)
+	}
+
+	if len(names) == 0 {
+		return nodes, nil
+	}
+
+	refs := DefaultNameReferences()
+	for _, node := range nodes {
+		if err := rewriteNameReferences(node, refs, names); err != nil {
+			return nil, err
+		}
+	}
+
+	return nodes, nil
+}
+
+// contentHash computes a hash of the contents of a Secret or ConfigMap. The hash
+// is compatible with the name suffix generated by Kustomize. The string data of
+// a Secret is merged into the data first (as the API server would).
+func contentHash(node *yaml.RNode, kind, name string) (string, error) {
+	m := map[string]any{"kind": kind, "name": name}
+	switch kind {
+	case "ConfigMap":
+		m["data"] = node.GetDataMap()
+		if binaryData := node.GetBinaryDataMap(); len(binaryData) > 0 {
+			m["binaryData"] = binaryData
+		}
+	case "Secret":
+		data := make(map[string][]byte)
+		for k, v := range node.GetDataMap() {
+			// Normalize the encoding (e.g. remove line breaks) by decoding the value
+			b, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(v), ""))
+			if err != nil {
+				return "", err
+			}
+			data[k] = b
+		}
+		if stringData := node.Field("stringData"); stringData != nil {
+			if err := stringData.Value.VisitFields(func(f *yaml.MapNode) error {
+				data[yaml.GetValue(f.Key)] = []byte(yaml.GetValue(f.Value))
+				return nil
+			}); err != nil {
+				return "", err
+			}
+		}
+		m["data"] = data
+		m["type"] = ""
+		if t := node.Field("type"); t != nil {
+			m["type"] = yaml.GetValue(t.Value)
+		}
+	}
+
+	b, err := json.Marshal(m)
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(b)
+	return encodeHash(hex.EncodeToString(sum[:])), nil
+}
+
+// encodeHash truncates the hex encoded hash and replaces characters to avoid
+// producing "bad words" in the resulting name.
+func encodeHash(hex string) string {
+	return strings.NewReplacer("0", "g", "1", "h", "3", "k", "a", "m", "e", "t").Replace(hex[:10])
+}
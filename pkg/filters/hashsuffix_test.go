@@ -0,0 +1,296 @@
+/*
+Copyright 2022 GramLabs, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package filters
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"sigs.k8s.io/kustomize/kyaml/kio"
+	"sigs.k8s.io/kustomize/kyaml/yaml"
+)
+
+func TestHashSuffixFilter_Filter(t *testing.T) {
+	cases := []struct {
+		desc     string
+		input    string
+		expected string
+	}{
+		{
+			desc: "config map",
+			input: `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: test
+  annotations:
+    konjure.stormforge.io/hash-suffix: "true"
+data:
+  foo: bar
+`,
+			// Same as `kustomize` would produce for the same config map
+			expected: `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: test-4ddd2t8h29
+data:
+  foo: bar
+`,
+		},
+		{
+			desc: "secret",
+			input: `apiVersion: v1
+kind: Secret
+metadata:
+  name: test
+  annotations:
+    konjure.stormforge.io/hash-suffix: "true"
+type: Opaque
+data:
+  foo: YmFy
+`,
+			expected: `apiVersion: v1
+kind: Secret
+metadata:
+  name: test-tm8k6ffg6f
+type: Opaque
+data:
+  foo: YmFy
+`,
+		},
+		{
+			desc: "secret string data",
+			input: `apiVersion: v1
+kind: Secret
+metadata:
+  name: test
+  annotations:
+    konjure.stormforge.io/hash-suffix: "true"
+type: Opaque
+data:
+  foo: YmF6
+stringData:
+  foo: bar
+`,
+			// String data overrides data, same hash as the "secret" case
+			expected: `apiVersion: v1
+kind: Secret
+metadata:
+  name: test-tm8k6ffg6f
+type: Opaque
+data:
+  foo: YmF6
+stringData:
+  foo: bar
+`,
+		},
+		{
+			desc: "not annotated",
+			input: `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: test
+data:
+  foo: bar
+`,
+			expected: `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: test
+data:
+  foo: bar
+`,
+		},
+		{
+			desc: "references",
+			input: `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: config
+  annotations:
+    konjure.stormforge.io/hash-suffix: "true"
+data:
+  foo: bar
+---
+apiVersion: v1
+kind: Secret
+metadata:
+  name: secret
+  annotations:
+    konjure.stormforge.io/hash-suffix: "true"
+data:
+  foo: YmFy
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: test
+spec:
+  template:
+    spec:
+      imagePullSecrets:
+      - name: secret
+      initContainers:
+      - name: init
+        envFrom:
+        - configMapRef:
+            name: config
+      containers:
+      - name: test
+        envFrom:
+        - secretRef:
+            name: secret
+        - configMapRef:
+            name: other
+        env:
+        - name: FOO
+          valueFrom:
+            configMapKeyRef:
+              name: config
+              key: foo
+        - name: BAR
+          valueFrom:
+            secretKeyRef:
+              name: secret
+              key: foo
+      volumes:
+      - name: config
+        configMap:
+          name: config
+      - name: secret
+        secret:
+          secretName: secret
+      - name: projected
+        projected:
+          sources:
+          - configMap:
+              name: config
+          - secret:
+              name: secret
+---
+apiVersion: batch/v1
+kind: CronJob
+metadata:
+  name: test
+  namespace: other
+spec:
+  jobTemplate:
+    spec:
+      template:
+        spec:
+          containers:
+          - name: test
+            envFrom:
+            - configMapRef:
+                name: config
+`,
+			expected: `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: config-6fkhbk5bc7
+data:
+  foo: bar
+---
+apiVersion: v1
+kind: Secret
+metadata:
+  name: secret-hd29d98cct
+data:
+  foo: YmFy
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: test
+spec:
+  template:
+    spec:
+      imagePullSecrets:
+      - name: secret-hd29d98cct
+      initContainers:
+      - name: init
+        envFrom:
+        - configMapRef:
+            name: config-6fkhbk5bc7
+      containers:
+      - name: test
+        envFrom:
+        - secretRef:
+            name: secret-hd29d98cct
+        - configMapRef:
+            name: other
+        env:
+        - name: FOO
+          valueFrom:
+            configMapKeyRef:
+              name: config-6fkhbk5bc7
+              key: foo
+        - name: BAR
+          valueFrom:
+            secretKeyRef:
+              name: secret-hd29d98cct
+              key: foo
+      volumes:
+      - name: config
+        configMap:
+          name: config-6fkhbk5bc7
+      - name: secret
+        secret:
+          secretName: secret-hd29d98cct
+      - name: projected
+        projected:
+          sources:
+          - configMap:
+              name: config-6fkhbk5bc7
+          - secret:
+              name: secret-hd29d98cct
+---
+apiVersion: batch/v1
+kind: CronJob
+metadata:
+  name: test
+  namespace: other
+spec:
+  jobTemplate:
+    spec:
+      template:
+        spec:
+          containers:
+          - name: test
+            envFrom:
+            - configMapRef:
+                name: config
+`,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.desc, func(t *testing.T) {
+			nodes, err := kio.FromBytes([]byte(c.input))
+			require.NoError(t, err)
+
+			actual, err := (&HashSuffixFilter{Enabled: true}).Filter(nodes)
+			require.NoError(t, err)
+
+			for _, n := range actual {
+				require.NoError(t, yaml.ClearEmptyAnnotations(n))
+			}
+			out, err := kio.StringAll(actual)
+			require.NoError(t, err)
+			assert.Equal(t, c.expected, out)
+		})
+	}
+}
@@ -0,0 +1,182 @@
+/*
+Copyright 2022 GramLabs, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package filters
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"sigs.k8s.io/kustomize/kyaml/yaml"
+)
+
+// ImageOverride describes a replacement for a container image.
+type ImageOverride struct {
+	// The image name to match, with or without the registry.
+	Name string `json:"name" yaml:"name"`
+	// The replacement image name, the original name is retained if empty.
+	NewName string `json:"newName,omitempty" yaml:"newName,omitempty"`
+	// The replacement tag.
+	NewTag string `json:"newTag,omitempty" yaml:"newTag,omitempty"`
+	// The replacement digest.
+	Digest string `json:"digest,omitempty" yaml:"digest,omitempty"`
+}
+
+// ParseImageOverride parses an override of the form `name=[registry/]repo[:tag][@digest]`.
+// When the name is omitted, it is taken from the replacement image (e.g. `nginx:1.25`).
+func ParseImageOverride(spec string) (ImageOverride, error) {
+	var newName, tag, digest string
+	name, ref, found := strings.Cut(spec, "=")
+	if found {
+		newName, tag, digest = splitImage(ref)
+	} else {
+		name, tag, digest = splitImage(spec)
+	}
+
+	if name == "" {
+		return ImageOverride{}, fmt.Errorf("invalid image override %q, expected name=image", spec)
+	}
+	if newName == "" && tag == "" && digest == "" {
+		return ImageOverride{}, fmt.Errorf("invalid image override %q, missing replacement", spec)
+	}
+
+	return ImageOverride{Name: name, NewName: newName, NewTag: tag, Digest: digest}, nil
+}
+
+// ImageFilter replaces container images.
+type ImageFilter struct {
+	// The image overrides to apply.
+	Images []ImageOverride `json:"images,omitempty" yaml:"images,omitempty"`
+	// Additional paths to image fields, indexed by kind.
+	ImagePaths map[string][][]string `json:"imagePaths,omitempty" yaml:"imagePaths,omitempty"`
+	// Where warnings about unmatched overrides are written, ignored if nil.
+	Warnings io.Writer `json:"-" yaml:"-"`
+}
+
+// podSpecImagePaths are the paths (relative to a pod spec) of container images.
+var podSpecImagePaths = [][]string{
+	{"containers", "*", "image"},
+	{"initContainers", "*", "image"},
+	{"ephemeralContainers", "*", "image"},
+}
+
+// Filter replaces the images.
+func (f *ImageFilter) Filter(nodes []*yaml.RNode) ([]*yaml.RNode, error) {
+	if len(f.Images) == 0 {
+		return nodes, nil
+	}
+
+	matched := make([]bool, len(f.Images))
+	replace := yaml.FilterFunc(func(rn *yaml.RNode) (*yaml.RNode, error) {
+		if rn.YNode().Kind != yaml.ScalarNode {
+			return rn, nil
+		}
+
+		name, tag, digest := splitImage(rn.YNode().Value)
+		for i, img := range f.Images {
+			if !imageNameMatches(img.Name, name) {
+				continue
+			}
+
+			matched[i] = true
+			if img.NewName != "" {
+				name = img.NewName
+			}
+			if img.NewTag != "" || img.Digest != "" {
+				tag, digest = img.NewTag, img.Digest
+			}
+			rn.YNode().Value = joinImage(name, tag, digest)
+			break
+		}
+		return rn, nil
+	})
+
+	for _, node := range nodes {
+		var paths [][]string
+		for _, p := range podSpecPaths {
+			for _, ip := range podSpecImagePaths {
+				paths = append(paths, append(append([]string{}, p...), ip...))
+			}
+		}
+		paths = append(paths, f.ImagePaths[node.GetKind()]...)
+
+		for _, p := range paths {
+			if err := node.PipeE(TeeMatched(yaml.PathMatcher{Path: p}, replace)); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if f.Warnings != nil {
+		for i, img := range f.Images {
+			if !matched[i] {
+				_, _ = fmt.Fprintf(f.Warnings, "warning: image override %q did not match any images\n", img.Name)
+			}
+		}
+	}
+
+	return nodes, nil
+}
+
+// splitImage splits an image reference into the name, tag and digest.
+func splitImage(image string) (name, tag, digest string) {
+	name, digest, _ = strings.Cut(image, "@")
+	if i := strings.LastIndex(name, ":"); i > strings.LastIndex(name, "/") {
+		name, tag = name[:i], name[i+1:]
+	}
+	return name, tag, digest
+}
+
+// joinImage is the inverse of splitImage.
+func joinImage(name, tag, digest string) string {
+	if tag != "" {
+		name += ":" + tag
+	}
+	if digest != "" {
+		name += "@" + digest
+	}
+	return name
+}
+
+// imageNameMatches checks if the image name matches the pattern. If the
+// pattern does not include a registry, any registry is matched.
+func imageNameMatches(pattern, name string) bool {
+	pr, pp := splitRegistry(pattern)
+	nr, np := splitRegistry(name)
+	if pr != "" && normalizeRegistry(pr) != normalizeRegistry(nr) {
+		return false
+	}
+	return strings.TrimPrefix(pp, "library/") == strings.TrimPrefix(np, "library/")
+}
+
+// splitRegistry splits the registry host off the image name.
+func splitRegistry(name string) (registry, path string) {
+	first, rest, found := strings.Cut(name, "/")
+	if found && (strings.ContainsAny(first, ".:") || first == "localhost") {
+		return first, rest
+	}
+	return "", name
+}
+
+// normalizeRegistry returns the canonical name of the registry.
+func normalizeRegistry(registry string) string {
+	switch registry {
+	case "", "docker.io", "index.docker.io", "registry-1.docker.io":
+		return "docker.io"
+	}
+	return registry
+}
@@ -0,0 +1,165 @@
+/*
+Copyright 2022 GramLabs, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package filters
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"sigs.k8s.io/kustomize/kyaml/kio"
+)
+
+func TestParseImageOverride(t *testing.T) {
+	cases := []struct {
+		spec     string
+		expected ImageOverride
+		err      bool
+	}{
+		{
+			spec:     "nginx=registry.example.com/mirror/nginx:1.25@sha256:abc",
+			expected: ImageOverride{Name: "nginx", NewName: "registry.example.com/mirror/nginx", NewTag: "1.25", Digest: "sha256:abc"},
+		},
+		{
+			spec:     "localhost:5000/app=:v2",
+			expected: ImageOverride{Name: "localhost:5000/app", NewTag: "v2"},
+		},
+		{
+			spec:     "nginx:1.25",
+			expected: ImageOverride{Name: "nginx", NewTag: "1.25"},
+		},
+		{
+			spec: "nginx",
+			err:  true,
+		},
+		{
+			spec: "=nginx:1.25",
+			err:  true,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.spec, func(t *testing.T) {
+			actual, err := ParseImageOverride(c.spec)
+			if c.err {
+				assert.Error(t, err)
+			} else if assert.NoError(t, err) {
+				assert.Equal(t, c.expected, actual)
+			}
+		})
+	}
+}
+
+func TestImageFilter_Filter(t *testing.T) {
+	nodes, err := kio.FromBytes([]byte(`apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: test
+spec:
+  template:
+    spec:
+      initContainers:
+      - name: init
+        image: docker.io/library/busybox:1.36
+      containers:
+      - name: app
+        image: nginx:1.24@sha256:old
+      - name: sidecar
+        image: quay.io/example/sidecar:v1
+---
+apiVersion: batch/v1
+kind: CronJob
+metadata:
+  name: test
+spec:
+  jobTemplate:
+    spec:
+      template:
+        spec:
+          containers:
+          - name: job
+            image: ghcr.io/example/job
+---
+apiVersion: monitoring.coreos.com/v1
+kind: Prometheus
+metadata:
+  name: test
+spec:
+  image: quay.io/prometheus/prometheus:v2.50.0
+`))
+	require.NoError(t, err)
+
+	var warnings bytes.Buffer
+	f := &ImageFilter{
+		Images: []ImageOverride{
+			{Name: "busybox", NewTag: "1.37"},
+			{Name: "nginx", NewName: "mirror.example.com/nginx", NewTag: "1.25"},
+			{Name: "docker.io/example/sidecar", NewTag: "v2"},
+			{Name: "ghcr.io/example/job", Digest: "sha256:new"},
+			{Name: "prometheus/prometheus", NewTag: "v2.51.0"},
+			{Name: "redis", NewTag: "7"},
+		},
+		ImagePaths: map[string][][]string{
+			"Prometheus": {{"spec", "image"}},
+		},
+		Warnings: &warnings,
+	}
+
+	actual, err := f.Filter(nodes)
+	require.NoError(t, err)
+
+	out, err := kio.StringAll(actual)
+	require.NoError(t, err)
+	assert.Equal(t, `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: test
+spec:
+  template:
+    spec:
+      initContainers:
+      - name: init
+        image: docker.io/library/busybox:1.37
+      containers:
+      - name: app
+        image: mirror.example.com/nginx:1.25
+      - name: sidecar
+        image: quay.io/example/sidecar:v1
+---
+apiVersion: batch/v1
+kind: CronJob
+metadata:
+  name: test
+spec:
+  jobTemplate:
+    spec:
+      template:
+        spec:
+          containers:
+          - name: job
+            image: ghcr.io/example/job@sha256:new
+---
+apiVersion: monitoring.coreos.com/v1
+kind: Prometheus
+metadata:
+  name: test
+spec:
+  image: quay.io/prometheus/prometheus:v2.51.0
+`, out)
+	assert.Equal(t, "warning: image override \"docker.io/example/sidecar\" did not match any images\n"+
+		"warning: image override \"redis\" did not match any images\n", warnings.String())
+}
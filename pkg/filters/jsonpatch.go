@@ -0,0 +1,369 @@
+/*
+Copyright 2022 GramLabs, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package filters
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"sigs.k8s.io/kustomize/kyaml/yaml"
+)
+
+// jsonPatchOperation is a single RFC 6902 JSON Patch operation.
+type jsonPatchOperation struct {
+	Op    string
+	Path  string
+	From  string
+	Value *yaml.Node
+}
+
+// decodeJSONPatch parses a JSON Patch document; because YAML is a superset of
+// JSON, the patch may also be expressed using YAML.
+func decodeJSONPatch(data []byte) ([]jsonPatchOperation, error) {
+	doc := &yaml.Node{}
+	if err := yaml.NewDecoder(bytes.NewReader(data)).Decode(doc); err != nil {
+		return nil, err
+	}
+	if doc.Kind == yaml.DocumentNode && len(doc.Content) > 0 {
+		doc = doc.Content[0]
+	}
+	if doc.Kind != yaml.SequenceNode {
+		return nil, fmt.Errorf("invalid JSON patch: expected a list of operations")
+	}
+
+	ops := make([]jsonPatchOperation, 0, len(doc.Content))
+	for _, n := range doc.Content {
+		if n.Kind != yaml.MappingNode {
+			return nil, fmt.Errorf("invalid JSON patch: expected an operation")
+		}
+
+		op := jsonPatchOperation{}
+		for i := 0; i < len(n.Content)-1; i += 2 {
+			switch n.Content[i].Value {
+			case "op":
+				op.Op = n.Content[i+1].Value
+			case "path":
+				op.Path = n.Content[i+1].Value
+			case "from":
+				op.From = n.Content[i+1].Value
+			case "value":
+				op.Value = n.Content[i+1]
+				_ = yaml.NewRNode(op.Value).PipeE(ResetStyle())
+			}
+		}
+		ops = append(ops, op)
+	}
+	return ops, nil
+}
+
+// jsonPatch applies JSON Patch operations directly to the YAML node tree so
+// comments, field order and styles are preserved.
+type jsonPatch struct {
+	// Flag that enables strict JSON Patch processing, otherwise missing remove
+	// paths are ignored and missing add paths are created.
+	strict bool
+}
+
+// apply applies the operations to the supplied node.
+func (p *jsonPatch) apply(node *yaml.Node, ops []jsonPatchOperation) error {
+	for _, op := range ops {
+		if err := p.applyOperation(node, op); err != nil {
+			return fmt.Errorf("unable to apply JSON patch operation %q to %q: %w", op.Op, op.Path, err)
+		}
+	}
+	return nil
+}
+
+func (p *jsonPatch) applyOperation(root *yaml.Node, op jsonPatchOperation) error {
+	path, err := parseJSONPointer(op.Path)
+	if err != nil {
+		return err
+	}
+
+	switch op.Op {
+	case "add":
+		if op.Value == nil {
+			return fmt.Errorf("missing value")
+		}
+		return p.add(root, path, yaml.CopyYNode(op.Value))
+
+	case "remove":
+		_, err := p.remove(root, path)
+		return err
+
+	case "replace":
+		if op.Value == nil {
+			return fmt.Errorf("missing value")
+		}
+		target := lookupJSONPointer(root, path)
+		if target == nil {
+			return fmt.Errorf("path does not exist")
+		}
+		replaceNode(target, yaml.CopyYNode(op.Value))
+		return nil
+
+	case "move":
+		from, err := parseJSONPointer(op.From)
+		if err != nil {
+			return err
+		}
+		if len(path) > len(from) && reflect.DeepEqual(path[:len(from)], from) {
+			return fmt.Errorf("cannot move %q into one of its children", op.From)
+		}
+		if lookupJSONPointer(root, from) == nil {
+			return fmt.Errorf("from path %q does not exist", op.From)
+		}
+		value, err := p.remove(root, from)
+		if err != nil {
+			return err
+		}
+		return p.add(root, path, value)
+
+	case "copy":
+		from, err := parseJSONPointer(op.From)
+		if err != nil {
+			return err
+		}
+		value := lookupJSONPointer(root, from)
+		if value == nil {
+			return fmt.Errorf("from path %q does not exist", op.From)
+		}
+		return p.add(root, path, yaml.CopyYNode(value))
+
+	case "test":
+		if op.Value == nil {
+			return fmt.Errorf("missing value")
+		}
+		target := lookupJSONPointer(root, path)
+		if target == nil {
+			return fmt.Errorf("path does not exist")
+		}
+		var expected, actual any
+		if err := op.Value.Decode(&expected); err != nil {
+			return err
+		}
+		if err := target.Decode(&actual); err != nil {
+			return err
+		}
+		if !reflect.DeepEqual(normalizeJSONValue(expected), normalizeJSONValue(actual)) {
+			return fmt.Errorf("test failed")
+		}
+		return nil
+
+	default:
+		return fmt.Errorf("unknown operation")
+	}
+}
+
+// add inserts the value at the specified path.
+func (p *jsonPatch) add(root *yaml.Node, path []string, value *yaml.Node) error {
+	if len(path) == 0 {
+		replaceNode(root, value)
+		return nil
+	}
+
+	parent := lookupJSONPointer(root, path[:len(path)-1])
+	if parent == nil {
+		if p.strict {
+			return fmt.Errorf("path does not exist")
+		}
+		if parent = createJSONPointer(root, path[:len(path)-1]); parent == nil {
+			return fmt.Errorf("path cannot be created")
+		}
+	}
+
+	key := path[len(path)-1]
+	switch parent.Kind {
+	case yaml.MappingNode:
+		for i := 0; i < len(parent.Content)-1; i += 2 {
+			if parent.Content[i].Value == key {
+				parent.Content[i+1] = value
+				return nil
+			}
+		}
+		parent.Content = append(parent.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: yaml.NodeTagString, Value: key}, value)
+		return nil
+
+	case yaml.SequenceNode:
+		if key == "-" {
+			parent.Content = append(parent.Content, value)
+			return nil
+		}
+		i, err := sequenceIndex(key, len(parent.Content)+1)
+		if err != nil {
+			return err
+		}
+		parent.Content = append(parent.Content[:i], append([]*yaml.Node{value}, parent.Content[i:]...)...)
+		return nil
+
+	default:
+		return fmt.Errorf("cannot add to a scalar value")
+	}
+}
+
+// remove deletes and returns the value at the specified path.
+func (p *jsonPatch) remove(root *yaml.Node, path []string) (*yaml.Node, error) {
+	if len(path) == 0 {
+		return nil, fmt.Errorf("cannot remove the root document")
+	}
+
+	parent := lookupJSONPointer(root, path[:len(path)-1])
+	if parent != nil {
+		key := path[len(path)-1]
+		switch parent.Kind {
+		case yaml.MappingNode:
+			for i := 0; i < len(parent.Content)-1; i += 2 {
+				if parent.Content[i].Value == key {
+					value := parent.Content[i+1]
+					parent.Content = append(parent.Content[:i], parent.Content[i+2:]...)
+					return value, nil
+				}
+			}
+
+		case yaml.SequenceNode:
+			if i, err := sequenceIndex(key, len(parent.Content)); err == nil {
+				value := parent.Content[i]
+				parent.Content = append(parent.Content[:i], parent.Content[i+1:]...)
+				return value, nil
+			}
+		}
+	}
+
+	if p.strict {
+		return nil, fmt.Errorf("path does not exist")
+	}
+	return nil, nil
+}
+
+// parseJSONPointer splits an RFC 6901 JSON Pointer into unescaped reference tokens.
+func parseJSONPointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("invalid JSON pointer %q", pointer)
+	}
+
+	tokens := strings.Split(pointer[1:], "/")
+	for i := range tokens {
+		tokens[i] = strings.NewReplacer("~1", "/", "~0", "~").Replace(tokens[i])
+	}
+	return tokens, nil
+}
+
+// lookupJSONPointer returns the node at the specified path or nil if it does not exist.
+func lookupJSONPointer(node *yaml.Node, path []string) *yaml.Node {
+	for _, key := range path {
+		node = jsonPointerChild(node, key)
+		if node == nil {
+			return nil
+		}
+	}
+	return node
+}
+
+// createJSONPointer returns the node at the specified path, creating any missing containers.
+func createJSONPointer(node *yaml.Node, path []string) *yaml.Node {
+	for i, key := range path {
+		child := jsonPointerChild(node, key)
+		if child == nil {
+			if node.Kind != yaml.MappingNode {
+				return nil
+			}
+
+			child = &yaml.Node{Kind: yaml.MappingNode, Tag: yaml.NodeTagMap}
+			if i+1 < len(path) {
+				if _, err := strconv.Atoi(path[i+1]); err == nil || path[i+1] == "-" {
+					child = &yaml.Node{Kind: yaml.SequenceNode, Tag: yaml.NodeTagSeq}
+				}
+			}
+			node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: yaml.NodeTagString, Value: key}, child)
+		}
+		node = child
+	}
+	return node
+}
+
+// jsonPointerChild returns the child of a mapping or sequence node.
+func jsonPointerChild(node *yaml.Node, key string) *yaml.Node {
+	switch node.Kind {
+	case yaml.MappingNode:
+		for i := 0; i < len(node.Content)-1; i += 2 {
+			if node.Content[i].Value == key {
+				return node.Content[i+1]
+			}
+		}
+	case yaml.SequenceNode:
+		if i, err := sequenceIndex(key, len(node.Content)); err == nil {
+			return node.Content[i]
+		}
+	}
+	return nil
+}
+
+// sequenceIndex parses a sequence index, it must be less than the supplied limit.
+func sequenceIndex(key string, limit int) (int, error) {
+	i, err := strconv.Atoi(key)
+	if err != nil || i < 0 || i >= limit || (len(key) > 1 && key[0] == '0') {
+		return 0, fmt.Errorf("invalid index %q", key)
+	}
+	return i, nil
+}
+
+// replaceNode overwrites the target with the value, retaining the comments of the target.
+func replaceNode(target, value *yaml.Node) {
+	head, line, foot := target.HeadComment, target.LineComment, target.FootComment
+	*target = *value
+	if target.HeadComment == "" {
+		target.HeadComment = head
+	}
+	if target.LineComment == "" {
+		target.LineComment = line
+	}
+	if target.FootComment == "" {
+		target.FootComment = foot
+	}
+}
+
+// normalizeJSONValue converts all numbers in a decoded value to float64 so
+// values are compared the way JSON compares them (e.g. `1` is equal to `1.0`).
+func normalizeJSONValue(v any) any {
+	switch v := v.(type) {
+	case int:
+		return float64(v)
+	case int64:
+		return float64(v)
+	case uint64:
+		return float64(v)
+	case map[string]any:
+		for k, vv := range v {
+			v[k] = normalizeJSONValue(vv)
+		}
+	case map[any]any:
+		for k, vv := range v {
+			v[k] = normalizeJSONValue(vv)
+		}
+	case []any:
+		for i, vv := range v {
+			v[i] = normalizeJSONValue(vv)
+		}
+	}
+	return v
+}
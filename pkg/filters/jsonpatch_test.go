@@ -0,0 +1,228 @@
+/*
+Copyright 2022 GramLabs, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package filters
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"sigs.k8s.io/kustomize/kyaml/yaml"
+)
+
+func TestPatchFilter_JSONPatch(t *testing.T) {
+	input := `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: test # the name
+  labels:
+    app: test
+spec:
+  # replicas should be overridden
+  replicas: 1
+  template:
+    spec:
+      containers:
+      - name: app
+        image: "nginx"
+        args: [--verbose]
+      - name: sidecar
+        image: busybox
+`
+
+	cases := []struct {
+		desc     string
+		patch    string
+		strict   bool
+		expected string
+		err      string
+	}{
+		{
+			desc:  "replace preserves comments and order",
+			patch: `[{"op": "replace", "path": "/spec/replicas", "value": 3}, {"op": "replace", "path": "/metadata/name", "value": "other"}]`,
+			expected: `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: other # the name
+  labels:
+    app: test
+spec:
+  # replicas should be overridden
+  replicas: 3
+  template:
+    spec:
+      containers:
+      - name: app
+        image: "nginx"
+        args: [--verbose]
+      - name: sidecar
+        image: busybox
+`,
+		},
+		{
+			desc:  "add",
+			patch: `[{"op": "add", "path": "/metadata/labels/tier", "value": "web"}, {"op": "add", "path": "/spec/template/spec/containers/1", "value": {"name": "init", "image": "alpine"}}, {"op": "add", "path": "/spec/template/spec/containers/0/args/-", "value": "--debug"}]`,
+			expected: `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: test # the name
+  labels:
+    app: test
+    tier: web
+spec:
+  # replicas should be overridden
+  replicas: 1
+  template:
+    spec:
+      containers:
+      - name: app
+        image: "nginx"
+        args: [--verbose, --debug]
+      - name: init
+        image: alpine
+      - name: sidecar
+        image: busybox
+`,
+		},
+		{
+			desc: "yaml patch with quoted strings",
+			patch: `- op: add
+  path: /metadata/annotations
+  value:
+    version: "1"
+    a~1b: "true"
+`,
+			expected: `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: test # the name
+  labels:
+    app: test
+  annotations:
+    version: "1"
+    a~1b: "true"
+spec:
+  # replicas should be overridden
+  replicas: 1
+  template:
+    spec:
+      containers:
+      - name: app
+        image: "nginx"
+        args: [--verbose]
+      - name: sidecar
+        image: busybox
+`,
+		},
+		{
+			desc:  "remove",
+			patch: `[{"op": "remove", "path": "/metadata/labels"}, {"op": "remove", "path": "/spec/template/spec/containers/1"}, {"op": "remove", "path": "/spec/missing"}]`,
+			expected: `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: test # the name
+spec:
+  # replicas should be overridden
+  replicas: 1
+  template:
+    spec:
+      containers:
+      - name: app
+        image: "nginx"
+        args: [--verbose]
+`,
+		},
+		{
+			desc:  "move and copy",
+			patch: `[{"op": "move", "from": "/spec/template/spec/containers/1", "path": "/spec/template/spec/containers/0"}, {"op": "copy", "from": "/metadata/labels", "path": "/spec/template/metadata/labels"}]`,
+			expected: `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: test # the name
+  labels:
+    app: test
+spec:
+  # replicas should be overridden
+  replicas: 1
+  template:
+    spec:
+      containers:
+      - name: sidecar
+        image: busybox
+      - name: app
+        image: "nginx"
+        args: [--verbose]
+    metadata:
+      labels:
+        app: test
+`,
+		},
+		{
+			desc:     "test",
+			patch:    `[{"op": "test", "path": "/spec/template/spec/containers/0/image", "value": "nginx"}, {"op": "test", "path": "/spec/replicas", "value": 1}]`,
+			expected: input,
+		},
+		{
+			desc:     "test numbers",
+			patch:    `[{"op": "test", "path": "/spec/replicas", "value": 1.0}, {"op": "test", "path": "/spec/replicas", "value": 1e0}]`,
+			expected: input,
+		},
+		{
+			desc:  "test failure",
+			patch: `[{"op": "test", "path": "/spec/replicas", "value": 2}]`,
+			err:   "test failed",
+		},
+		{
+			desc:   "strict remove",
+			patch:  `[{"op": "remove", "path": "/spec/missing"}]`,
+			strict: true,
+			err:    "path does not exist",
+		},
+		{
+			desc:   "strict add",
+			patch:  `[{"op": "add", "path": "/spec/strategy/type", "value": "Recreate"}]`,
+			strict: true,
+			err:    "path does not exist",
+		},
+		{
+			desc:  "replace missing",
+			patch: `[{"op": "replace", "path": "/spec/missing", "value": 1}]`,
+			err:   "path does not exist",
+		},
+		{
+			desc:  "move into child",
+			patch: `[{"op": "move", "from": "/spec", "path": "/spec/template/spec"}]`,
+			err:   "cannot move",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.desc, func(t *testing.T) {
+			node := yaml.MustParse(input)
+			f := &PatchFilter{PatchType: "application/json-patch+json", PatchData: []byte(c.patch), StrictJSONPatch: c.strict}
+
+			_, err := f.Filter(node)
+			if c.err != "" {
+				assert.ErrorContains(t, err, c.err)
+				return
+			}
+			require.NoError(t, err)
+			actual, err := node.String()
+			require.NoError(t, err)
+			assert.Equal(t, c.expected, actual)
+		})
+	}
+}
@@ -0,0 +1,132 @@
+/*
+Copyright 2022 GramLabs, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package filters
+
+import (
+	"sigs.k8s.io/kustomize/kyaml/yaml"
+)
+
+// MatchFilter filters nodes using boolean combinations of resource metadata
+// terms. A node matches when it matches the inline term (if any), all of the
+// `allOf` filters, at least one of the `anyOf` filters (if any) and does not
+// match the `not` filter (if present).
+type MatchFilter struct {
+	// The resource metadata term to match.
+	ResourceMetaFilter `json:",inline" yaml:",inline"`
+	// Filters which must all match.
+	AllOf []MatchFilter `json:"allOf,omitempty" yaml:"allOf,omitempty"`
+	// Filters of which at least one must match.
+	AnyOf []MatchFilter `json:"anyOf,omitempty" yaml:"anyOf,omitempty"`
+	// Filter which must not match.
+	Not *MatchFilter `json:"not,omitempty" yaml:"not,omitempty"`
+}
+
+func (f *MatchFilter) Filter(nodes []*yaml.RNode) ([]*yaml.RNode, error) {
+	keep, err := f.matcher()
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*yaml.RNode, 0, len(nodes))
+	for _, n := range nodes {
+		if ok, err := keep(n); err != nil {
+			return nil, err
+		} else if ok {
+			result = append(result, n)
+		}
+	}
+
+	return result, nil
+}
+
+// Validate checks all the terms of the filter without matching any nodes.
+func (f *MatchFilter) Validate() error {
+	_, err := f.matcher()
+	return err
+}
+
+// matcher compiles the filter into a function that determines if a node should be kept.
+func (f *MatchFilter) matcher() (func(*yaml.RNode) (bool, error), error) {
+	term, err := f.ResourceMetaFilter.matcher()
+	if err != nil {
+		return nil, err
+	}
+
+	allOf := make([]func(*yaml.RNode) (bool, error), 0, len(f.AllOf))
+	for i := range f.AllOf {
+		m, err := f.AllOf[i].matcher()
+		if err != nil {
+			return nil, err
+		}
+		allOf = append(allOf, m)
+	}
+
+	anyOf := make([]func(*yaml.RNode) (bool, error), 0, len(f.AnyOf))
+	for i := range f.AnyOf {
+		m, err := f.AnyOf[i].matcher()
+		if err != nil {
+			return nil, err
+		}
+		anyOf = append(anyOf, m)
+	}
+
+	var not func(*yaml.RNode) (bool, error)
+	if f.Not != nil {
+		if not, err = f.Not.matcher(); err != nil {
+			return nil, err
+		}
+	}
+
+	return func(n *yaml.RNode) (bool, error) {
+		if term != nil {
+			if ok, err := term(n); err != nil || !ok {
+				return false, err
+			}
+		}
+
+		for _, m := range allOf {
+			if ok, err := m(n); err != nil || !ok {
+				return false, err
+			}
+		}
+
+		if len(anyOf) > 0 {
+			matched := false
+			for _, m := range anyOf {
+				ok, err := m(n)
+				if err != nil {
+					return false, err
+				}
+				if ok {
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				return false, nil
+			}
+		}
+
+		if not != nil {
+			if ok, err := not(n); err != nil || ok {
+				return false, err
+			}
+		}
+
+		return true, nil
+	}, nil
+}
@@ -0,0 +1,134 @@
+/*
+Copyright 2022 GramLabs, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package filters
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"sigs.k8s.io/kustomize/kyaml/kio"
+	"sigs.k8s.io/kustomize/kyaml/yaml"
+)
+
+func TestMatchFilter_Filter(t *testing.T) {
+	input, err := kio.FromBytes([]byte(`apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+  namespace: x
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: web
+  namespace: x
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: web
+  namespace: y
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: config
+  namespace: x
+  labels:
+    app: web
+`))
+	require.NoError(t, err)
+
+	cases := []struct {
+		desc     string
+		filter   string
+		expected []string
+	}{
+		{
+			desc:     "match all",
+			filter:   `{}`,
+			expected: []string{"Deployment/x/web", "Service/x/web", "Service/y/web", "ConfigMap/x/config"},
+		},
+		{
+			desc:     "inline term",
+			filter:   `namespace: "y"`,
+			expected: []string{"Service/y/web"},
+		},
+		{
+			desc: "any of",
+			filter: `
+namespace: x
+anyOf:
+- kind: Deployment
+- kind: Service
+`,
+			expected: []string{"Deployment/x/web", "Service/x/web"},
+		},
+		{
+			desc: "all of",
+			filter: `
+allOf:
+- kind: Service
+- namespace: x
+`,
+			expected: []string{"Service/x/web"},
+		},
+		{
+			desc: "not",
+			filter: `
+not:
+  anyOf:
+  - kind: Service
+  - labelSelector: app=web
+`,
+			expected: []string{"Deployment/x/web"},
+		},
+		{
+			desc: "nested",
+			filter: `
+anyOf:
+- kind: Deployment
+- allOf:
+  - kind: Service
+  - not:
+      namespace: x
+`,
+			expected: []string{"Deployment/x/web", "Service/y/web"},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.desc, func(t *testing.T) {
+			f := &MatchFilter{}
+			require.NoError(t, yaml.Unmarshal([]byte(c.filter), f))
+
+			actual, err := f.Filter(input)
+			if assert.NoError(t, err) {
+				var ids []string
+				for _, n := range actual {
+					ids = append(ids, n.GetKind()+"/"+n.GetNamespace()+"/"+n.GetName())
+				}
+				assert.Equal(t, c.expected, ids)
+			}
+		})
+	}
+}
+
+func TestMatchFilter_Validate(t *testing.T) {
+	f := &MatchFilter{AnyOf: []MatchFilter{{Not: &MatchFilter{ResourceMetaFilter: ResourceMetaFilter{Kind: "("}}}}}
+	assert.Error(t, f.Validate())
+}
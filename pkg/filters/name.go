@@ -0,0 +1,241 @@
+/*
+Copyright 2022 GramLabs, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package filters
+
+import (
+	"sigs.k8s.io/kustomize/kyaml/yaml"
+)
+
+// NameReference describes a field of one resource that refers to another resource by name.
+type NameReference struct {
+	// The kind of the referenced resource, used when the kind is not read from `KindField`.
+	Kind string `json:"kind,omitempty" yaml:"kind,omitempty"`
+	// The kind of the referring resource, an empty value matches any kind.
+	ReferrerKind string `json:"referrerKind,omitempty" yaml:"referrerKind,omitempty"`
+	// The path to the object containing the reference, may contain wildcards (e.g. `*` or `[kind=ServiceAccount]`).
+	Path []string `json:"path" yaml:"path"`
+	// The field of the referring object containing the name, defaults to "name".
+	NameField string `json:"nameField,omitempty" yaml:"nameField,omitempty"`
+	// The field of the referring object containing the kind of the referenced resource.
+	KindField string `json:"kindField,omitempty" yaml:"kindField,omitempty"`
+	// The field of the referring object containing the namespace of the referenced resource,
+	// defaults to the namespace of the referring resource.
+	NamespaceField string `json:"namespaceField,omitempty" yaml:"namespaceField,omitempty"`
+}
+
+// DefaultNameReferences returns the built-in table of name references.
+func DefaultNameReferences() []NameReference {
+	var refs []NameReference
+	for _, p := range podSpecPaths {
+		podRefs := []NameReference{
+			{Kind: "ConfigMap", Path: []string{"volumes", "*", "configMap"}},
+			{Kind: "ConfigMap", Path: []string{"volumes", "*", "projected", "sources", "*", "configMap"}},
+			{Kind: "Secret", Path: []string{"volumes", "*", "secret"}, NameField: "secretName"},
+			{Kind: "Secret", Path: []string{"volumes", "*", "projected", "sources", "*", "secret"}},
+			{Kind: "Secret", Path: []string{"imagePullSecrets", "*"}},
+			{Kind: "PersistentVolumeClaim", Path: []string{"volumes", "*", "persistentVolumeClaim"}, NameField: "claimName"},
+			{Kind: "ServiceAccount", Path: []string{}, NameField: "serviceAccountName"},
+			{Kind: "ServiceAccount", Path: []string{}, NameField: "serviceAccount"},
+		}
+		for _, c := range []string{"containers", "initContainers", "ephemeralContainers"} {
+			podRefs = append(podRefs,
+				NameReference{Kind: "ConfigMap", Path: []string{c, "*", "envFrom", "*", "configMapRef"}},
+				NameReference{Kind: "ConfigMap", Path: []string{c, "*", "env", "*", "valueFrom", "configMapKeyRef"}},
+				NameReference{Kind: "Secret", Path: []string{c, "*", "envFrom", "*", "secretRef"}},
+				NameReference{Kind: "Secret", Path: []string{c, "*", "env", "*", "valueFrom", "secretKeyRef"}},
+			)
+		}
+
+		for _, ref := range podRefs {
+			ref.Path = append(append([]string{}, p...), ref.Path...)
+			refs = append(refs, ref)
+		}
+	}
+
+	return append(refs,
+		NameReference{Kind: "Service", ReferrerKind: "StatefulSet", Path: []string{"spec"}, NameField: "serviceName"},
+		NameReference{Kind: "Service", ReferrerKind: "Ingress", Path: []string{"spec", "defaultBackend", "service"}},
+		NameReference{Kind: "Service", ReferrerKind: "Ingress", Path: []string{"spec", "rules", "*", "http", "paths", "*", "backend", "service"}},
+		NameReference{Kind: "Secret", ReferrerKind: "Ingress", Path: []string{"spec", "tls", "*"}, NameField: "secretName"},
+		NameReference{ReferrerKind: "RoleBinding", Path: []string{"roleRef"}, KindField: "kind"},
+		NameReference{ReferrerKind: "ClusterRoleBinding", Path: []string{"roleRef"}, KindField: "kind"},
+		NameReference{ReferrerKind: "RoleBinding", Path: []string{"subjects", "*"}, KindField: "kind", NamespaceField: "namespace"},
+		NameReference{ReferrerKind: "ClusterRoleBinding", Path: []string{"subjects", "*"}, KindField: "kind", NamespaceField: "namespace"},
+		NameReference{ReferrerKind: "HorizontalPodAutoscaler", Path: []string{"spec", "scaleTargetRef"}, KindField: "kind"},
+		NameReference{ReferrerKind: "VerticalPodAutoscaler", Path: []string{"spec", "targetRef"}, KindField: "kind"},
+		NameReference{Kind: "Deployment", ReferrerKind: "ScaledObject", Path: []string{"spec", "scaleTargetRef"}, KindField: "kind"},
+	)
+}
+
+// NameFilter adds a prefix and/or suffix to the names of resources and rewrites
+// the references to the renamed resources within the stream.
+type NameFilter struct {
+	// The prefix to add to resource names.
+	Prefix string `json:"prefix,omitempty" yaml:"prefix,omitempty"`
+	// The suffix to add to resource names.
+	Suffix string `json:"suffix,omitempty" yaml:"suffix,omitempty"`
+	// Selects the resources to rename, all resources are renamed by default.
+	Selector *MatchFilter `json:"selector,omitempty" yaml:"selector,omitempty"`
+	// Additional name references to rewrite, in addition to the `DefaultNameReferences`.
+	References []NameReference `json:"references,omitempty" yaml:"references,omitempty"`
+}
+
+// Filter renames the resources and updates references to them.
+func (f *NameFilter) Filter(nodes []*yaml.RNode) ([]*yaml.RNode, error) {
+	if f.Prefix == "" && f.Suffix == "" {
+		return nodes, nil
+	}
+
+	selected := nodes
+	if f.Selector != nil {
+		var err error
+		if selected, err = f.Selector.Filter(nodes); err != nil {
+			return nil, err
+		}
+	}
+
+	scopes := customResourceScopes(nodes)
+	names := make(resourceNames)
+	for _, node := range selected {
+		md, err := node.GetMeta()
+		if err != nil {
+			return nil, err
+		}
+
+		// These names have meaning and cannot be changed
+		switch md.Kind {
+		case "CustomResourceDefinition", "APIService", "Namespace":
+			continue
+		}
+
+		name := f.Prefix + md.Name + f.Suffix
+		if err := node.PipeE(yaml.SetK8sName(name)); err != nil {
+			return nil, err
+		}
+		names.add(md, name, scopes)
+	}
+
+	refs := append(DefaultNameReferences(), f.References...)
+	for _, node := range nodes {
+		if err := rewriteNameReferences(node, refs, names); err != nil {
+			return nil, err
+		}
+	}
+
+	return nodes, nil
+}
+
+// resourceName identifies a resource by kind, namespace and name.
+type resourceName struct {
+	kind          string
+	namespace     string
+	name          string
+	clusterScoped bool
+}
+
+// newResourceName returns the name of a resource, using the supplied custom resource scopes.
+func newResourceName(md yaml.ResourceMeta, scopes map[string]bool) resourceName {
+	key := resourceName{kind: md.Kind, namespace: md.Namespace, name: md.Name}
+	if !isNamespaceScoped(md.TypeMeta, scopes) {
+		key.namespace, key.clusterScoped = "", true
+	}
+	return key
+}
+
+// resourceNames maps the original names of resources to their new names.
+type resourceNames map[resourceName]string
+
+// add records the new name of a resource.
+func (n resourceNames) add(md yaml.ResourceMeta, newName string, scopes map[string]bool) {
+	n[newResourceName(md, scopes)] = newName
+}
+
+// lookup returns the new name of the referenced resource, falling back to cluster scoped resources.
+func (n resourceNames) lookup(kind, namespace, name string) (string, bool) {
+	if newName, ok := n[resourceName{kind: kind, namespace: namespace, name: name}]; ok {
+		return newName, true
+	}
+	newName, ok := n[resourceName{kind: kind, name: name, clusterScoped: true}]
+	return newName, ok
+}
+
+// rewriteNameReferences updates any references to renamed resources on the supplied node.
+func rewriteNameReferences(node *yaml.RNode, refs []NameReference, names resourceNames) error {
+	if len(names) == 0 {
+		return nil
+	}
+
+	return visitNameReferences(node, refs, func(kind, namespace string, name *yaml.Node) error {
+		if newName, ok := names.lookup(kind, namespace, name.Value); ok {
+			name.Value = newName
+		}
+		return nil
+	})
+}
+
+// visitNameReferences invokes the callback with the kind, namespace and name
+// node of every reference on the supplied node.
+func visitNameReferences(node *yaml.RNode, refs []NameReference, fn func(kind, namespace string, name *yaml.Node) error) error {
+	kind := node.GetKind()
+	namespace := node.GetNamespace()
+	for _, ref := range refs {
+		if ref.ReferrerKind != "" && ref.ReferrerKind != kind {
+			continue
+		}
+
+		visit := yaml.FilterFunc(func(rn *yaml.RNode) (*yaml.RNode, error) {
+			if rn.YNode().Kind != yaml.MappingNode {
+				return rn, nil
+			}
+
+			nameField := ref.NameField
+			if nameField == "" {
+				nameField = "name"
+			}
+			name := rn.Field(nameField)
+			if name == nil || name.Value.YNode().Kind != yaml.ScalarNode {
+				return rn, nil
+			}
+
+			refKind, refNamespace := ref.Kind, namespace
+			if ref.KindField != "" {
+				if k := rn.Field(ref.KindField); k != nil {
+					refKind = yaml.GetValue(k.Value)
+				}
+			}
+			if ref.NamespaceField != "" {
+				if ns := rn.Field(ref.NamespaceField); ns != nil {
+					refNamespace = yaml.GetValue(ns.Value)
+				}
+			}
+
+			return rn, fn(refKind, refNamespace, name.Value.YNode())
+		})
+
+		if len(ref.Path) == 0 {
+			if _, err := visit.Filter(node); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if err := node.PipeE(TeeMatched(yaml.PathMatcher{Path: ref.Path}, visit)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
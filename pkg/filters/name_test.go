@@ -0,0 +1,393 @@
+/*
+Copyright 2022 GramLabs, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package filters
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"sigs.k8s.io/kustomize/kyaml/kio"
+)
+
+func TestNameFilter_Filter(t *testing.T) {
+	cases := []struct {
+		desc     string
+		filter   NameFilter
+		input    string
+		expected string
+	}{
+		{
+			desc:   "references",
+			filter: NameFilter{Prefix: "blue-", Suffix: "-v2"},
+			input: `apiVersion: v1
+kind: ServiceAccount
+metadata:
+  name: app
+  namespace: test
+---
+apiVersion: v1
+kind: PersistentVolumeClaim
+metadata:
+  name: data
+  namespace: test
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+  namespace: test
+spec:
+  template:
+    spec:
+      serviceAccountName: app
+      volumes:
+      - name: data
+        persistentVolumeClaim:
+          claimName: data
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: app
+  namespace: test
+---
+apiVersion: networking.k8s.io/v1
+kind: Ingress
+metadata:
+  name: app
+  namespace: test
+spec:
+  rules:
+  - http:
+      paths:
+      - path: /
+        backend:
+          service:
+            name: app
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: Role
+metadata:
+  name: app
+  namespace: test
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: RoleBinding
+metadata:
+  name: app
+  namespace: test
+roleRef:
+  apiGroup: rbac.authorization.k8s.io
+  kind: Role
+  name: app
+subjects:
+- kind: ServiceAccount
+  name: app
+  namespace: test
+- kind: User
+  name: app
+---
+apiVersion: autoscaling/v2
+kind: HorizontalPodAutoscaler
+metadata:
+  name: app
+  namespace: test
+spec:
+  scaleTargetRef:
+    apiVersion: apps/v1
+    kind: Deployment
+    name: app
+---
+apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: widgets.example.com
+`,
+			expected: `apiVersion: v1
+kind: ServiceAccount
+metadata:
+  name: blue-app-v2
+  namespace: test
+---
+apiVersion: v1
+kind: PersistentVolumeClaim
+metadata:
+  name: blue-data-v2
+  namespace: test
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: blue-app-v2
+  namespace: test
+spec:
+  template:
+    spec:
+      serviceAccountName: blue-app-v2
+      volumes:
+      - name: data
+        persistentVolumeClaim:
+          claimName: blue-data-v2
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: blue-app-v2
+  namespace: test
+---
+apiVersion: networking.k8s.io/v1
+kind: Ingress
+metadata:
+  name: blue-app-v2
+  namespace: test
+spec:
+  rules:
+  - http:
+      paths:
+      - path: /
+        backend:
+          service:
+            name: blue-app-v2
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: Role
+metadata:
+  name: blue-app-v2
+  namespace: test
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: RoleBinding
+metadata:
+  name: blue-app-v2
+  namespace: test
+roleRef:
+  apiGroup: rbac.authorization.k8s.io
+  kind: Role
+  name: blue-app-v2
+subjects:
+- kind: ServiceAccount
+  name: blue-app-v2
+  namespace: test
+- kind: User
+  name: app
+---
+apiVersion: autoscaling/v2
+kind: HorizontalPodAutoscaler
+metadata:
+  name: blue-app-v2
+  namespace: test
+spec:
+  scaleTargetRef:
+    apiVersion: apps/v1
+    kind: Deployment
+    name: blue-app-v2
+---
+apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: widgets.example.com
+`,
+		},
+		{
+			desc: "selector and custom references",
+			filter: NameFilter{
+				Prefix:   "blue-",
+				Selector: &MatchFilter{ResourceMetaFilter: ResourceMetaFilter{Kind: "ConfigMap|ClusterRole"}},
+				References: []NameReference{
+					{Kind: "ConfigMap", ReferrerKind: "Widget", Path: []string{"spec", "configRef"}},
+				},
+			},
+			input: `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: config
+  namespace: test
+---
+apiVersion: example.com/v1
+kind: Widget
+metadata:
+  name: widget
+  namespace: test
+spec:
+  configRef:
+    name: config
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRole
+metadata:
+  name: reader
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: RoleBinding
+metadata:
+  name: reader
+  namespace: test
+roleRef:
+  apiGroup: rbac.authorization.k8s.io
+  kind: ClusterRole
+  name: reader
+`,
+			expected: `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: blue-config
+  namespace: test
+---
+apiVersion: example.com/v1
+kind: Widget
+metadata:
+  name: widget
+  namespace: test
+spec:
+  configRef:
+    name: blue-config
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRole
+metadata:
+  name: blue-reader
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: RoleBinding
+metadata:
+  name: reader
+  namespace: test
+roleRef:
+  apiGroup: rbac.authorization.k8s.io
+  kind: ClusterRole
+  name: blue-reader
+`,
+		},
+		{
+			desc: "cluster scoped custom resources",
+			filter: NameFilter{
+				Prefix:     "p-",
+				Selector:   &MatchFilter{ResourceMetaFilter: ResourceMetaFilter{Kind: "Gadget"}},
+				References: []NameReference{{Kind: "Gadget", ReferrerKind: "Widget", Path: []string{"spec", "gadgetRef"}}},
+			},
+			input: `apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: gadgets.example.com
+spec:
+  group: example.com
+  names:
+    kind: Gadget
+  scope: Cluster
+---
+apiVersion: example.com/v1
+kind: Gadget
+metadata:
+  name: gadget
+---
+apiVersion: example.com/v1
+kind: Widget
+metadata:
+  name: widget
+  namespace: test
+spec:
+  gadgetRef:
+    name: gadget
+`,
+			expected: `apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: gadgets.example.com
+spec:
+  group: example.com
+  names:
+    kind: Gadget
+  scope: Cluster
+---
+apiVersion: example.com/v1
+kind: Gadget
+metadata:
+  name: p-gadget
+---
+apiVersion: example.com/v1
+kind: Widget
+metadata:
+  name: widget
+  namespace: test
+spec:
+  gadgetRef:
+    name: p-gadget
+`,
+		},
+		{
+			desc:   "volume claim templates",
+			filter: NameFilter{Prefix: "p-"},
+			input: `apiVersion: v1
+kind: PersistentVolumeClaim
+metadata:
+  name: data
+---
+apiVersion: apps/v1
+kind: StatefulSet
+metadata:
+  name: db
+spec:
+  template:
+    spec:
+      containers:
+      - name: db
+        volumeMounts:
+        - name: data
+          mountPath: /data
+  volumeClaimTemplates:
+  - metadata:
+      name: data
+`,
+			expected: `apiVersion: v1
+kind: PersistentVolumeClaim
+metadata:
+  name: p-data
+---
+apiVersion: apps/v1
+kind: StatefulSet
+metadata:
+  name: p-db
+spec:
+  template:
+    spec:
+      containers:
+      - name: db
+        volumeMounts:
+        - name: data
+          mountPath: /data
+  volumeClaimTemplates:
+  - metadata:
+      name: data
+`,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.desc, func(t *testing.T) {
+			nodes, err := kio.FromBytes([]byte(c.input))
+			require.NoError(t, err)
+
+			actual, err := c.filter.Filter(nodes)
+			if assert.NoError(t, err) {
+				out, err := kio.StringAll(actual)
+				if assert.NoError(t, err) {
+					assert.Equal(t, c.expected, out)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,185 @@
+/*
+Copyright 2022 GramLabs, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package filters
+
+import (
+	"maps"
+	"slices"
+	"strings"
+
+	"sigs.k8s.io/kustomize/kyaml/openapi"
+	"sigs.k8s.io/kustomize/kyaml/yaml"
+)
+
+// NamespaceFilter sets the namespace of all namespace scoped resources. Unlike
+// `SetNamespace`, the scope of custom resources is learned from the definitions
+// present in the stream and known references to the original namespaces (e.g.
+// role binding subjects or webhook services) are updated.
+type NamespaceFilter struct {
+	// The namespace to set on all namespace scoped resources.
+	Namespace string `json:"namespace,omitempty" yaml:"namespace,omitempty"`
+	// Flag indicating that `Namespace` resources should be created for every
+	// namespace used that is not already present in the stream.
+	CreateNamespaces bool `json:"createNamespaces,omitempty" yaml:"createNamespaces,omitempty"`
+}
+
+// namespaceReferences are the paths of fields referencing namespaces, indexed by "Kind.group".
+var namespaceReferences = map[string][][]string{
+	"RoleBinding.rbac.authorization.k8s.io": {
+		{"subjects", "[kind=ServiceAccount]", "namespace"},
+	},
+	"ClusterRoleBinding.rbac.authorization.k8s.io": {
+		{"subjects", "[kind=ServiceAccount]", "namespace"},
+	},
+	"ValidatingWebhookConfiguration.admissionregistration.k8s.io": {
+		{"webhooks", "*", "clientConfig", "service", "namespace"},
+	},
+	"MutatingWebhookConfiguration.admissionregistration.k8s.io": {
+		{"webhooks", "*", "clientConfig", "service", "namespace"},
+	},
+	"APIService.apiregistration.k8s.io": {
+		{"spec", "service", "namespace"},
+	},
+	"CustomResourceDefinition.apiextensions.k8s.io": {
+		{"spec", "conversion", "webhook", "clientConfig", "service", "namespace"},
+		{"spec", "conversion", "webhookClientConfig", "service", "namespace"},
+	},
+}
+
+// Filter sets the namespace and updates references to the original namespaces.
+func (f *NamespaceFilter) Filter(nodes []*yaml.RNode) ([]*yaml.RNode, error) {
+	if f.Namespace == "" && !f.CreateNamespaces {
+		return nodes, nil
+	}
+
+	scopes := customResourceScopes(nodes)
+
+	original := make(map[string]bool)
+	used := make(map[string]bool)
+	existing := make(map[string]bool)
+	for _, node := range nodes {
+		md, err := node.GetMeta()
+		if err != nil {
+			return nil, err
+		}
+
+		if md.APIVersion == "v1" && md.Kind == "Namespace" {
+			existing[md.Name] = true
+		}
+
+		if !isNamespaceScoped(md.TypeMeta, scopes) {
+			continue
+		}
+
+		if f.Namespace != "" && md.Namespace != f.Namespace {
+			if err := node.SetNamespace(f.Namespace); err != nil {
+				return nil, err
+			}
+			if md.Namespace != "" {
+				original[md.Namespace] = true
+			}
+		}
+
+		if ns := node.GetNamespace(); ns != "" {
+			used[ns] = true
+		}
+	}
+
+	if len(original) > 0 {
+		for _, node := range nodes {
+			if err := f.rewriteNamespaceReferences(node, original); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if f.CreateNamespaces {
+		var namespaces []*yaml.RNode
+		for _, ns := range slices.Sorted(maps.Keys(used)) {
+			if existing[ns] {
+				continue
+			}
+
+			n := yaml.NewMapRNode(nil)
+			n.SetApiVersion("v1")
+			n.SetKind("Namespace")
+			if err := n.SetName(ns); err != nil {
+				return nil, err
+			}
+			namespaces = append(namespaces, n)
+		}
+		nodes = append(namespaces, nodes...)
+	}
+
+	return nodes, nil
+}
+
+// rewriteNamespaceReferences replaces any references to the original namespaces on the supplied node.
+func (f *NamespaceFilter) rewriteNamespaceReferences(node *yaml.RNode, original map[string]bool) error {
+	refs := namespaceReferences[groupKind(node.GetApiVersion(), node.GetKind())]
+	for _, ref := range refs {
+		if err := node.PipeE(TeeMatched(yaml.PathMatcher{Path: ref}, yaml.FilterFunc(func(rn *yaml.RNode) (*yaml.RNode, error) {
+			if original[rn.YNode().Value] {
+				rn.YNode().Value = f.Namespace
+			}
+			return rn, nil
+		}))); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// customResourceScopes returns the scope of custom resources defined in the
+// stream, indexed by "Kind.group". Incomplete definitions are ignored.
+func customResourceScopes(nodes []*yaml.RNode) map[string]bool {
+	scopes := make(map[string]bool)
+	for _, node := range nodes {
+		if node.GetKind() != "CustomResourceDefinition" || !strings.HasPrefix(node.GetApiVersion(), "apiextensions.k8s.io/") {
+			continue
+		}
+
+		kind, _ := node.GetString("spec.names.kind")
+		group, _ := node.GetString("spec.group")
+		if kind == "" || group == "" {
+			continue
+		}
+		scope, _ := node.GetString("spec.scope")
+		scopes[kind+"."+group] = scope != "Cluster"
+	}
+	return scopes
+}
+
+// isNamespaceScoped checks the scope of the supplied type, unknown types are assumed to be namespace scoped.
+func isNamespaceScoped(tm yaml.TypeMeta, scopes map[string]bool) bool {
+	if namespaced, ok := scopes[groupKind(tm.APIVersion, tm.Kind)]; ok {
+		return namespaced
+	}
+	if namespaced, found := openapi.IsNamespaceScoped(tm); found {
+		return namespaced
+	}
+	return true
+}
+
+// groupKind returns the "Kind.group" string for the supplied API version and kind.
+func groupKind(apiVersion, kind string) string {
+	group, _, found := strings.Cut(apiVersion, "/")
+	if !found {
+		return kind
+	}
+	return kind + "." + group
+}
@@ -0,0 +1,239 @@
+/*
+Copyright 2022 GramLabs, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package filters
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"sigs.k8s.io/kustomize/kyaml/kio"
+)
+
+func TestNamespaceFilter_Filter(t *testing.T) {
+	cases := []struct {
+		desc     string
+		filter   NamespaceFilter
+		input    string
+		expected string
+	}{
+		{
+			desc:   "custom resource scope",
+			filter: NamespaceFilter{Namespace: "test"},
+			input: `apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: widgets.example.com
+spec:
+  group: example.com
+  names:
+    kind: Widget
+  scope: Cluster
+---
+apiVersion: example.com/v1
+kind: Widget
+metadata:
+  name: cluster-widget
+---
+apiVersion: example.com/v1
+kind: Gadget
+metadata:
+  name: unknown-gadget
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRole
+metadata:
+  name: reader
+`,
+			expected: `apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: widgets.example.com
+spec:
+  group: example.com
+  names:
+    kind: Widget
+  scope: Cluster
+---
+apiVersion: example.com/v1
+kind: Widget
+metadata:
+  name: cluster-widget
+---
+apiVersion: example.com/v1
+kind: Gadget
+metadata:
+  name: unknown-gadget
+  namespace: test
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRole
+metadata:
+  name: reader
+`,
+		},
+		{
+			desc:   "references",
+			filter: NamespaceFilter{Namespace: "test"},
+			input: `apiVersion: v1
+kind: ServiceAccount
+metadata:
+  name: app
+  namespace: old
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRoleBinding
+metadata:
+  name: app
+subjects:
+- kind: ServiceAccount
+  name: app
+  namespace: old
+- kind: ServiceAccount
+  name: other
+  namespace: kube-system
+- kind: User
+  name: old
+roleRef:
+  apiGroup: rbac.authorization.k8s.io
+  kind: ClusterRole
+  name: reader
+---
+apiVersion: admissionregistration.k8s.io/v1
+kind: ValidatingWebhookConfiguration
+metadata:
+  name: app
+webhooks:
+- name: app.example.com
+  clientConfig:
+    service:
+      name: app
+      namespace: old
+---
+apiVersion: apiregistration.k8s.io/v1
+kind: APIService
+metadata:
+  name: v1.example.com
+spec:
+  service:
+    name: app
+    namespace: old
+`,
+			expected: `apiVersion: v1
+kind: ServiceAccount
+metadata:
+  name: app
+  namespace: test
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRoleBinding
+metadata:
+  name: app
+subjects:
+- kind: ServiceAccount
+  name: app
+  namespace: test
+- kind: ServiceAccount
+  name: other
+  namespace: kube-system
+- kind: User
+  name: old
+roleRef:
+  apiGroup: rbac.authorization.k8s.io
+  kind: ClusterRole
+  name: reader
+---
+apiVersion: admissionregistration.k8s.io/v1
+kind: ValidatingWebhookConfiguration
+metadata:
+  name: app
+webhooks:
+- name: app.example.com
+  clientConfig:
+    service:
+      name: app
+      namespace: test
+---
+apiVersion: apiregistration.k8s.io/v1
+kind: APIService
+metadata:
+  name: v1.example.com
+spec:
+  service:
+    name: app
+    namespace: test
+`,
+		},
+		{
+			desc:   "create namespaces",
+			filter: NamespaceFilter{CreateNamespaces: true},
+			input: `apiVersion: v1
+kind: Namespace
+metadata:
+  name: a
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: test
+  namespace: a
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: test
+  namespace: b
+`,
+			expected: `apiVersion: v1
+kind: Namespace
+metadata:
+  name: b
+---
+apiVersion: v1
+kind: Namespace
+metadata:
+  name: a
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: test
+  namespace: a
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: test
+  namespace: b
+`,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.desc, func(t *testing.T) {
+			nodes, err := kio.FromBytes([]byte(c.input))
+			require.NoError(t, err)
+
+			actual, err := c.filter.Filter(nodes)
+			if assert.NoError(t, err) {
+				out, err := kio.StringAll(actual)
+				if assert.NoError(t, err) {
+					assert.Equal(t, c.expected, out)
+				}
+			}
+		})
+	}
+}
@@ -1,7 +1,11 @@
 package filters
 
 import (
+	"cmp"
+	"fmt"
+	"slices"
 	"sort"
+	"strconv"
 
 	"sigs.k8s.io/kustomize/kyaml/kio"
 	"sigs.k8s.io/kustomize/kyaml/yaml"
@@ -11,43 +15,46 @@ import (
 // should be created in a cluster. This uses the Helm ordering which is more
 // complete than the Kustomize ordering.
 func InstallOrder() kio.Filter {
-	return SortByKind([]string{
-		"Namespace",
-		"NetworkPolicy",
-		"ResourceQuota",
-		"LimitRange",
-		"PodSecurityPolicy",
-		"PodDisruptionBudget",
-		"ServiceAccount",
-		"Secret",
-		"SecretList",
-		"ConfigMap",
-		"StorageClass",
-		"PersistentVolume",
-		"PersistentVolumeClaim",
-		"CustomResourceDefinition",
-		"ClusterRole",
-		"ClusterRoleList",
-		"ClusterRoleBinding",
-		"ClusterRoleBindingList",
-		"Role",
-		"RoleList",
-		"RoleBinding",
-		"RoleBindingList",
-		"Service",
-		"DaemonSet",
-		"Pod",
-		"ReplicationController",
-		"ReplicaSet",
-		"Deployment",
-		"HorizontalPodAutoscaler",
-		"StatefulSet",
-		"Job",
-		"CronJob",
-		"IngressClass",
-		"Ingress",
-		"APIService",
-	})
+	return SortByKind(installOrder)
+}
+
+// installOrder is the Helm installation order of built-in kinds.
+var installOrder = []string{
+	"Namespace",
+	"NetworkPolicy",
+	"ResourceQuota",
+	"LimitRange",
+	"PodSecurityPolicy",
+	"PodDisruptionBudget",
+	"ServiceAccount",
+	"Secret",
+	"SecretList",
+	"ConfigMap",
+	"StorageClass",
+	"PersistentVolume",
+	"PersistentVolumeClaim",
+	"CustomResourceDefinition",
+	"ClusterRole",
+	"ClusterRoleList",
+	"ClusterRoleBinding",
+	"ClusterRoleBindingList",
+	"Role",
+	"RoleList",
+	"RoleBinding",
+	"RoleBindingList",
+	"Service",
+	"DaemonSet",
+	"Pod",
+	"ReplicationController",
+	"ReplicaSet",
+	"Deployment",
+	"HorizontalPodAutoscaler",
+	"StatefulSet",
+	"Job",
+	"CronJob",
+	"IngressClass",
+	"Ingress",
+	"APIService",
 }
 
 // UninstallOrder returns a filter that sorts nodes in the order in which they
@@ -112,3 +119,233 @@ func SortByKind(priority []string) kio.Filter {
 		return nodes, nil
 	})
 }
+
+// OrderAnnotation is the annotation used to explicitly order resources when
+// using a `DependencyOrderFilter`. The value is an integer, resources with lower
+// values are installed first (the default value is 0).
+const OrderAnnotation = "konjure.stormforge.io/order"
+
+// lateKinds are installed after all other kinds since they can intercept or
+// block requests for other resources.
+var lateKinds = []string{
+	"APIService",
+	"MutatingWebhookConfiguration",
+	"ValidatingWebhookConfiguration",
+	"ValidatingAdmissionPolicy",
+	"ValidatingAdmissionPolicyBinding",
+}
+
+// DependencyOrder returns a filter that sorts nodes in the order in which they
+// should be created in a cluster.
+func DependencyOrder() kio.Filter {
+	return &DependencyOrderFilter{}
+}
+
+// DependencyOrderFilter sorts nodes so that resources are installed after the
+// resources they depend on: custom resource definitions come before instances
+// of their kinds, namespaces come before their contents, roles come before their
+// bindings and service accounts (and their bindings) come before the workloads
+// that use them. Admission webhooks and API services
+// are always installed last. Independent resources are ordered by `OrderAnnotation`
+// and then by kind (using the same order as `InstallOrder`, with custom
+// resources following the built-in kinds).
+type DependencyOrderFilter struct {
+	// Flag indicating the nodes should be sorted in the order in which they
+	// should be deleted from a cluster (i.e. dependents first).
+	Reverse bool
+}
+
+// Filter sorts the nodes.
+func (f *DependencyOrderFilter) Filter(nodes []*yaml.RNode) ([]*yaml.RNode, error) {
+	type item struct {
+		index  int
+		order  int
+		rank   int
+		kind   string
+		deps   int
+		before []int
+	}
+
+	rank := make(map[string]int, len(installOrder)+len(lateKinds))
+	for i, k := range installOrder {
+		rank[k] = i
+	}
+	for i, k := range lateKinds {
+		rank[k] = len(installOrder) + 1 + i // See `late` below
+	}
+
+	items := make([]*item, len(nodes))
+	for i, node := range nodes {
+		items[i] = &item{index: i, kind: node.GetKind()}
+		if r, ok := rank[items[i].kind]; ok {
+			items[i].rank = r
+		} else {
+			items[i].rank = len(installOrder)
+		}
+		if v, ok := node.GetAnnotations()[OrderAnnotation]; ok {
+			order, err := strconv.Atoi(v)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s annotation on %s %q: %w", OrderAnnotation, items[i].kind, node.GetName(), err)
+			}
+			items[i].order = order
+		}
+	}
+
+	edges, err := dependencies(nodes)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range edges {
+		items[e[0]].before = append(items[e[0]].before, e[1])
+		items[e[1]].deps++
+	}
+
+	late := len(installOrder) + 1
+	less := func(a, b *item) bool {
+		switch {
+		case (a.rank >= late) != (b.rank >= late):
+			return a.rank < late
+		case a.order != b.order:
+			return a.order < b.order
+		case a.rank != b.rank:
+			return a.rank < b.rank
+		case a.kind != b.kind:
+			return a.kind < b.kind
+		}
+		return a.index < b.index
+	}
+
+	// Repeatedly select the first available item, ignoring dependencies if there is a cycle
+	result := make([]*yaml.RNode, 0, len(nodes))
+	remaining := slices.Clone(items)
+	for len(remaining) > 0 {
+		next := -1
+		for i, it := range remaining {
+			if it.deps == 0 && (next < 0 || less(it, remaining[next])) {
+				next = i
+			}
+		}
+		if next < 0 {
+			next = 0
+			for i, it := range remaining {
+				if less(it, remaining[next]) {
+					next = i
+				}
+			}
+		}
+
+		it := remaining[next]
+		remaining = slices.Delete(remaining, next, next+1)
+		for _, j := range it.before {
+			items[j].deps--
+		}
+		result = append(result, nodes[it.index])
+	}
+
+	if f.Reverse {
+		slices.Reverse(result)
+	}
+	return result, nil
+}
+
+// dependencies returns pairs of node indices where the first node must be installed before the second.
+func dependencies(nodes []*yaml.RNode) ([][2]int, error) {
+	crds := make(map[string]int)
+	namespaces := make(map[string]int)
+	serviceAccounts := make(map[string]int)
+	roles := make(map[string]int)
+	for i, node := range nodes {
+		switch gk := groupKind(node.GetApiVersion(), node.GetKind()); gk {
+		case "CustomResourceDefinition.apiextensions.k8s.io":
+			group, _ := node.GetString("spec.group")
+			kind, _ := node.GetString("spec.names.kind")
+			crds[kind+"."+group] = i
+		case "Namespace":
+			namespaces[node.GetName()] = i
+		case "ServiceAccount":
+			serviceAccounts[node.GetNamespace()+"/"+node.GetName()] = i
+		case "Role.rbac.authorization.k8s.io":
+			roles["Role:"+node.GetNamespace()+"/"+node.GetName()] = i
+		case "ClusterRole.rbac.authorization.k8s.io":
+			roles["ClusterRole:/"+node.GetName()] = i
+		}
+	}
+
+	var result [][2]int
+
+	// Bindings come after the roles and service accounts they bind
+	bindings := make(map[string][]int)
+	for i, node := range nodes {
+		switch groupKind(node.GetApiVersion(), node.GetKind()) {
+		case "RoleBinding.rbac.authorization.k8s.io", "ClusterRoleBinding.rbac.authorization.k8s.io":
+		default:
+			continue
+		}
+
+		roleKind, _ := node.GetString("roleRef.kind")
+		roleName, _ := node.GetString("roleRef.name")
+		roleNamespace := node.GetNamespace()
+		if roleKind == "ClusterRole" {
+			roleNamespace = ""
+		}
+		if j, ok := roles[roleKind+":"+roleNamespace+"/"+roleName]; ok {
+			result = append(result, [2]int{j, i})
+		}
+
+		subjects, err := node.Pipe(yaml.Lookup("subjects"))
+		if err != nil {
+			return nil, err
+		}
+		for _, subject := range subjects.Content() {
+			field := func(name string) string {
+				value, _ := yaml.NewRNode(subject).Pipe(yaml.Get(name))
+				return yaml.GetValue(value)
+			}
+			if field("kind") != "ServiceAccount" {
+				continue
+			}
+			key := cmp.Or(field("namespace"), node.GetNamespace()) + "/" + field("name")
+			if j, ok := serviceAccounts[key]; ok {
+				result = append(result, [2]int{j, i})
+			}
+			bindings[key] = append(bindings[key], i)
+		}
+	}
+
+	for i, node := range nodes {
+		if j, ok := crds[groupKind(node.GetApiVersion(), node.GetKind())]; ok {
+			result = append(result, [2]int{j, i})
+		}
+
+		if j, ok := namespaces[node.GetNamespace()]; ok && i != j {
+			result = append(result, [2]int{j, i})
+		}
+
+		for _, p := range podSpecPaths {
+			podSpec, err := node.Pipe(yaml.Lookup(p...))
+			if err != nil {
+				return nil, err
+			}
+			if podSpec == nil {
+				continue
+			}
+
+			// Also check the deprecated alias of the service account name
+			saName, _ := podSpec.GetString("serviceAccountName")
+			saAlias, _ := podSpec.GetString("serviceAccount")
+			sa := cmp.Or(saName, saAlias)
+			if sa == "" {
+				continue
+			}
+			key := node.GetNamespace() + "/" + sa
+			if j, ok := serviceAccounts[key]; ok {
+				result = append(result, [2]int{j, i})
+			}
+			for _, j := range bindings[key] {
+				result = append(result, [2]int{j, i})
+			}
+		}
+	}
+
+	return result, nil
+}
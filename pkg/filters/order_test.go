@@ -1,9 +1,11 @@
 package filters
 
 import (
+	"slices"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"sigs.k8s.io/kustomize/kyaml/kio"
 	"sigs.k8s.io/kustomize/kyaml/yaml"
 )
@@ -88,3 +90,200 @@ func kindAndName(kind, name string) yaml.ResourceMeta {
 	md.Name = name
 	return md
 }
+
+func TestDependencyOrder(t *testing.T) {
+	nodes, err := kio.FromBytes([]byte(`apiVersion: admissionregistration.k8s.io/v1
+kind: ValidatingWebhookConfiguration
+metadata:
+  name: webhook
+---
+apiVersion: example.com/v1
+kind: Widget
+metadata:
+  name: widget
+  namespace: test
+  annotations:
+    konjure.stormforge.io/order: "-1"
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+  namespace: test
+  annotations:
+    konjure.stormforge.io/order: "-2"
+spec:
+  template:
+    spec:
+      serviceAccountName: app
+---
+apiVersion: v1
+kind: ServiceAccount
+metadata:
+  name: app
+  namespace: test
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: config
+  namespace: other
+  annotations:
+    konjure.stormforge.io/order: "-3"
+---
+apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: widgets.example.com
+spec:
+  group: example.com
+  names:
+    kind: Widget
+---
+apiVersion: v1
+kind: Namespace
+metadata:
+  name: test
+  annotations:
+    konjure.stormforge.io/order: "5"
+`))
+	require.NoError(t, err)
+
+	names := func(nodes []*yaml.RNode) []string {
+		var result []string
+		for _, n := range nodes {
+			result = append(result, n.GetName())
+		}
+		return result
+	}
+
+	actual, err := DependencyOrder().Filter(slices.Clone(nodes))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"config", "widgets.example.com", "test", "widget", "app", "app", "webhook"}, names(actual))
+	assert.Equal(t, "ServiceAccount", actual[4].GetKind())
+
+	actual, err = (&DependencyOrderFilter{Reverse: true}).Filter(slices.Clone(nodes))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"webhook", "app", "app", "widget", "test", "widgets.example.com", "config"}, names(actual))
+
+	nodes[0].SetAnnotations(map[string]string{OrderAnnotation: "first"})
+	_, err = DependencyOrder().Filter(nodes)
+	assert.ErrorContains(t, err, "invalid konjure.stormforge.io/order annotation")
+}
+
+func TestDependencyOrder_RBAC(t *testing.T) {
+	nodes, err := kio.FromBytes([]byte(`apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+  namespace: test
+  annotations:
+    konjure.stormforge.io/order: "-1"
+spec:
+  template:
+    spec:
+      serviceAccountName: app
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: RoleBinding
+metadata:
+  name: app-binding
+  namespace: test
+roleRef:
+  apiGroup: rbac.authorization.k8s.io
+  kind: Role
+  name: app-role
+subjects:
+- kind: ServiceAccount
+  name: app
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRoleBinding
+metadata:
+  name: app-cluster-binding
+roleRef:
+  apiGroup: rbac.authorization.k8s.io
+  kind: ClusterRole
+  name: app-cluster-role
+subjects:
+- kind: ServiceAccount
+  name: app
+  namespace: test
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRole
+metadata:
+  name: app-cluster-role
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: Role
+metadata:
+  name: app-role
+  namespace: test
+---
+apiVersion: v1
+kind: ServiceAccount
+metadata:
+  name: app
+  namespace: test
+`))
+	require.NoError(t, err)
+
+	actual, err := DependencyOrder().Filter(nodes)
+	require.NoError(t, err)
+
+	var names []string
+	for _, n := range actual {
+		names = append(names, n.GetName())
+	}
+	assert.Equal(t, []string{"app", "app-cluster-role", "app-cluster-binding", "app-role", "app-binding", "app"}, names)
+	assert.Equal(t, "Deployment", actual[len(actual)-1].GetKind())
+}
+
+func TestDependencyOrder_Reverse(t *testing.T) {
+	nodes, err := kio.FromBytes([]byte(`apiVersion: networking.k8s.io/v1
+kind: Ingress
+metadata:
+  name: web
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  template:
+    spec:
+      serviceAccount: web
+---
+apiVersion: v1
+kind: ServiceAccount
+metadata:
+  name: web
+  annotations:
+    konjure.stormforge.io/order: "1"
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: web
+`))
+	require.NoError(t, err)
+
+	kinds := func(nodes []*yaml.RNode) []string {
+		var result []string
+		for _, n := range nodes {
+			result = append(result, n.GetKind())
+		}
+		return result
+	}
+
+	// The deprecated service account field is still a dependency
+	actual, err := DependencyOrder().Filter(slices.Clone(nodes))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Service", "Ingress", "ServiceAccount", "Deployment"}, kinds(actual))
+
+	// The reverse is the exact reverse of the install order, not the `UninstallOrder`
+	actual, err = (&DependencyOrderFilter{Reverse: true}).Filter(slices.Clone(nodes))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Deployment", "ServiceAccount", "Ingress", "Service"}, kinds(actual))
+}
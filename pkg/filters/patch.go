@@ -3,11 +3,10 @@ package filters
 import (
 	"bytes"
 	"fmt"
+	"regexp"
+	"strings"
 
-	jsonpatch "github.com/evanphx/json-patch/v5"
 	"sigs.k8s.io/kustomize/kyaml/yaml"
-	"sigs.k8s.io/kustomize/kyaml/yaml/merge2"
-	yaml2 "sigs.k8s.io/yaml"
 )
 
 // UnsupportedPatchError is raised when a patch format is not recognized.
@@ -28,6 +27,9 @@ type PatchFilter struct {
 	// Flag that enables strict JSON Patch processing. Default behavior is to allow
 	// missing remove paths and create missing add paths.
 	StrictJSONPatch bool
+	// The schemas used to determine how lists are merged by a strategic merge
+	// patch, defaults to the built-in Kubernetes schemas.
+	Schemas *ResourceSchemas
 }
 
 // Filter applies the configured patch.
@@ -41,49 +43,122 @@ func (f *PatchFilter) Filter(node *yaml.RNode) (*yaml.RNode, error) {
 		}
 		_ = patchNode.PipeE(ResetStyle())
 
-		// Strategic Merge/Merge Patch is just the merge2 logic
-		opts := yaml.MergeOptions{
-			ListIncreaseDirection: yaml.MergeOptionsListPrepend,
+		// A JSON Merge Patch is just a strategic merge patch without directives or list merging
+		m := &strategicMerge{strategic: f.PatchType != "application/merge-patch+json" && f.PatchType != "merge", prepend: true}
+		rs := f.Schemas.SchemaForResourceType(yaml.TypeMeta{APIVersion: node.GetApiVersion(), Kind: node.GetKind()})
+		result, err := m.merge(node.YNode(), patchNode.YNode(), rs)
+		if err != nil || result == nil {
+			return nil, err
+		}
+		if result != node.YNode() {
+			node.SetYNode(result)
 		}
-		return merge2.Merge(patchNode, node, opts)
+		return node, nil
 
 	case "application/json-patch+json", "json":
-		// The patch is likely JSON, but might be YAML that needs to be converted to JSON
-		patchData := f.PatchData
-		if !bytes.HasPrefix(patchData, []byte("[")) {
-			jsonData, err := yaml2.YAMLToJSON(patchData)
-			if err != nil {
-				return nil, err
-			}
-			patchData = jsonData
-		}
-		jsonPatch, err := jsonpatch.DecodePatch(patchData)
+		// The patch is likely JSON, but might be YAML (which is a superset of JSON)
+		ops, err := decodeJSONPatch(f.PatchData)
 		if err != nil {
 			return nil, err
 		}
 
-		// Adjust strict interpretation of JSON Patch
-		jsonPatchOptions := jsonpatch.NewApplyOptions()
-		jsonPatchOptions.AllowMissingPathOnRemove = !f.StrictJSONPatch
-		jsonPatchOptions.EnsurePathExistsOnAdd = !f.StrictJSONPatch
+		// Apply the patch directly to the node to preserve ordering/comments/etc.
+		p := &jsonPatch{strict: f.StrictJSONPatch}
+		if err := p.apply(node.YNode(), ops); err != nil {
+			return nil, err
+		}
+		return node, nil
 
-		// This is going to butcher the YAML ordering/comments/etc.
-		jsonData, err := node.MarshalJSON()
-		if err != nil {
+	default:
+		// This patch type is not supported
+		return nil, &UnsupportedPatchError{PatchType: f.PatchType}
+	}
+}
+
+// TargetedPatchFilter is used to apply a patch to the resources matching a target.
+type TargetedPatchFilter struct {
+	// Selects the resources to patch, all resources are patched by default.
+	Target *ResourceMetaFilter
+	// The patch to apply to each selected resource.
+	Patch PatchFilter
+}
+
+// Filter applies the patch to the selected nodes, nodes deleted by the patch are removed.
+func (f *TargetedPatchFilter) Filter(nodes []*yaml.RNode) ([]*yaml.RNode, error) {
+	selected := nodes
+	if f.Target != nil {
+		var err error
+		if selected, err = f.Target.Filter(nodes); err != nil {
 			return nil, err
 		}
-		jsonData, err = jsonPatch.ApplyWithOptions(jsonData, jsonPatchOptions)
-		if err != nil {
+	}
+
+	// Allow strategic merge patches to use custom resource definitions from the stream
+	p := f.Patch
+	if p.Schemas == nil {
+		var err error
+		if p.Schemas, err = NewResourceSchemas(nodes); err != nil {
 			return nil, err
 		}
-		err = node.UnmarshalJSON(jsonData)
+	}
+
+	deleted := make(map[*yaml.RNode]bool)
+	for _, node := range selected {
+		result, err := p.Filter(node)
 		if err != nil {
-			return nil, err
+			return nil, fmt.Errorf("unable to patch %s %q: %w", node.GetKind(), node.GetName(), err)
 		}
-		return node, nil
+		if result == nil {
+			deleted[node] = true
+		}
+	}
 
-	default:
-		// This patch type is not supported
-		return nil, &UnsupportedPatchError{PatchType: f.PatchType}
+	if len(deleted) == 0 {
+		return nodes, nil
 	}
+
+	result := make([]*yaml.RNode, 0, len(nodes))
+	for _, node := range nodes {
+		if !deleted[node] {
+			result = append(result, node)
+		}
+	}
+	return result, nil
+}
+
+// ParsePatch returns a targeted patch filter for the supplied YAML or JSON
+// patch. JSON Patches (a list of operations) are applied to all resources,
+// otherwise the patch is a strategic merge patch whose target is taken from
+// the type and metadata included in the patch itself (if any).
+func ParsePatch(data []byte) (*TargetedPatchFilter, error) {
+	if trimmed := bytes.TrimSpace(data); bytes.HasPrefix(trimmed, []byte("[")) || bytes.HasPrefix(trimmed, []byte("- ")) {
+		return &TargetedPatchFilter{Patch: PatchFilter{PatchType: "json", PatchData: data}}, nil
+	}
+
+	node, err := yaml.Parse(string(data))
+	if err != nil {
+		return nil, err
+	}
+
+	f := &TargetedPatchFilter{Patch: PatchFilter{PatchType: "strategic", PatchData: data}}
+	target := ResourceMetaFilter{
+		Kind:      regexp.QuoteMeta(node.GetKind()),
+		Name:      regexp.QuoteMeta(node.GetName()),
+		Namespace: regexp.QuoteMeta(node.GetNamespace()),
+	}
+	if gv := node.GetApiVersion(); gv != "" {
+		group, version, ok := strings.Cut(gv, "/")
+		if !ok {
+			group, version = "", group
+		}
+		target.Version = regexp.QuoteMeta(version)
+		if target.Group = regexp.QuoteMeta(group); group == "" {
+			target.Group = "^$" // The core group must be matched explicitly
+		}
+	}
+	if target != (ResourceMetaFilter{}) {
+		f.Target = &target
+	}
+
+	return f, nil
 }
@@ -0,0 +1,214 @@
+/*
+Copyright 2022 GramLabs, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package filters
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"sigs.k8s.io/kustomize/kyaml/yaml"
+)
+
+// ReplacementSource selects the value to copy into other resources.
+type ReplacementSource struct {
+	// Selects the source resource, exactly one resource must match.
+	ResourceMetaFilter `json:",inline" yaml:",inline"`
+	// The "/" separated path template of the source value, defaults to "metadata/name".
+	FieldPath string `json:"fieldPath,omitempty" yaml:"fieldPath,omitempty"`
+	// Options for using only part of the source value.
+	Options *ReplacementOptions `json:"options,omitempty" yaml:"options,omitempty"`
+}
+
+// ReplacementTarget selects the fields to copy a value into.
+type ReplacementTarget struct {
+	// Selects the target resources.
+	ResourceMetaFilter `json:",inline" yaml:",inline"`
+	// The "/" separated path templates of the target fields.
+	FieldPaths []string `json:"fieldPaths" yaml:"fieldPaths"`
+	// Options for replacing only part of the target values.
+	Options *ReplacementOptions `json:"options,omitempty" yaml:"options,omitempty"`
+}
+
+// ReplacementOptions are used to select part of a delimited string value.
+type ReplacementOptions struct {
+	// The delimiter used to split the value.
+	Delimiter string `json:"delimiter,omitempty" yaml:"delimiter,omitempty"`
+	// The index of the part of the value, ignored if there is no delimiter.
+	Index int `json:"index,omitempty" yaml:"index,omitempty"`
+	// Flag indicating that missing target fields should be created.
+	Create bool `json:"create,omitempty" yaml:"create,omitempty"`
+}
+
+// ReplacementFilter copies a value from one resource into fields of other
+// resources. Field paths are templates evaluated against the metadata of the
+// resource they are applied to (e.g. `{.name}`, `{.namespace}` or `{.kind}`).
+type ReplacementFilter struct {
+	// The source of the value to copy.
+	Source ReplacementSource `json:"source" yaml:"source"`
+	// The targets the value is copied into.
+	Targets []ReplacementTarget `json:"targets" yaml:"targets"`
+}
+
+// Filter copies the source value into the targets.
+func (f *ReplacementFilter) Filter(nodes []*yaml.RNode) ([]*yaml.RNode, error) {
+	value, err := f.sourceValue(nodes)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range f.Targets {
+		t := &f.Targets[i]
+		targets, err := t.ResourceMetaFilter.Filter(nodes)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, node := range targets {
+			if err := t.apply(node, value); err != nil {
+				return nil, fmt.Errorf("unable to replace value in %s %q: %w", node.GetKind(), node.GetName(), err)
+			}
+		}
+	}
+
+	return nodes, nil
+}
+
+// sourceValue returns the value selected by the source.
+func (f *ReplacementFilter) sourceValue(nodes []*yaml.RNode) (*yaml.RNode, error) {
+	sources, err := f.Source.ResourceMetaFilter.Filter(nodes)
+	if err != nil {
+		return nil, err
+	}
+	if len(sources) != 1 {
+		return nil, fmt.Errorf("replacement source must match exactly one resource, matched %d", len(sources))
+	}
+
+	fieldPath := f.Source.FieldPath
+	if fieldPath == "" {
+		fieldPath = "metadata/name"
+	}
+
+	path, err := FieldPath(fieldPath, pathData(sources[0]))
+	if err != nil {
+		return nil, err
+	}
+
+	value, err := sources[0].Pipe(yaml.Lookup(path...))
+	if err != nil {
+		return nil, err
+	}
+	if value == nil {
+		return nil, fmt.Errorf("replacement source field %q not found", fieldPath)
+	}
+
+	if opts := f.Source.Options; opts != nil && opts.Delimiter != "" {
+		if value.YNode().Kind != yaml.ScalarNode {
+			return nil, fmt.Errorf("replacement source field %q is not a string", fieldPath)
+		}
+		parts := strings.Split(value.YNode().Value, opts.Delimiter)
+		if opts.Index < 0 || opts.Index >= len(parts) {
+			return nil, fmt.Errorf("replacement source index %d is out of range", opts.Index)
+		}
+		return yaml.NewStringRNode(parts[opts.Index]), nil
+	}
+
+	return yaml.NewRNode(yaml.CopyYNode(value.YNode())), nil
+}
+
+// apply sets the value on each of the target fields of the supplied node.
+func (t *ReplacementTarget) apply(node *yaml.RNode, value *yaml.RNode) error {
+	opts := t.Options
+	if opts == nil {
+		opts = &ReplacementOptions{}
+	}
+
+	for _, fieldPath := range t.FieldPaths {
+		path, err := FieldPath(fieldPath, pathData(node))
+		if err != nil {
+			return err
+		}
+
+		// Wildcard paths follow the same rules for creating fields as `SetPath`
+		if slices.ContainsFunc(path, isPathWildcard) {
+			if opts.Delimiter != "" {
+				return fmt.Errorf("%s: partial replacement is not supported with wildcards", fieldPath)
+			}
+			if err := node.PipeE(setPath(path, value)); err != nil {
+				return err
+			}
+			continue
+		}
+
+		existing, err := node.Pipe(yaml.Lookup(path...))
+		if err != nil {
+			return err
+		}
+		if existing == nil && !opts.Create {
+			continue
+		}
+
+		v := yaml.NewRNode(yaml.CopyYNode(value.YNode()))
+		if opts.Delimiter != "" {
+			if v, err = replacePart(existing, value, opts); err != nil {
+				return fmt.Errorf("%s: %w", fieldPath, err)
+			}
+		}
+
+		if err := node.PipeE(setPath(path, v)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// replacePart returns a copy of the existing string value with the delimited part replaced.
+func replacePart(existing, value *yaml.RNode, opts *ReplacementOptions) (*yaml.RNode, error) {
+	if value.YNode().Kind != yaml.ScalarNode {
+		return nil, fmt.Errorf("cannot replace part of a value with a non-string value")
+	}
+
+	var parts []string
+	if existing != nil {
+		if existing.YNode().Kind != yaml.ScalarNode {
+			return nil, fmt.Errorf("cannot replace part of a non-string value")
+		}
+		parts = strings.Split(existing.YNode().Value, opts.Delimiter)
+	}
+
+	switch {
+	case opts.Index < 0 || opts.Index > len(parts):
+		return nil, fmt.Errorf("index %d is out of range", opts.Index)
+	case opts.Index == len(parts):
+		parts = append(parts, value.YNode().Value)
+	default:
+		parts[opts.Index] = value.YNode().Value
+	}
+
+	return yaml.NewStringRNode(strings.Join(parts, opts.Delimiter)), nil
+}
+
+// pathData returns the data used to evaluate field path templates against a node.
+func pathData(node *yaml.RNode) map[string]string {
+	return map[string]string{
+		"apiVersion": node.GetApiVersion(),
+		"kind":       node.GetKind(),
+		"name":       node.GetName(),
+		"namespace":  node.GetNamespace(),
+	}
+}
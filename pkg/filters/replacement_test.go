@@ -0,0 +1,253 @@
+/*
+Copyright 2022 GramLabs, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package filters
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"sigs.k8s.io/kustomize/kyaml/kio"
+)
+
+func TestReplacementFilter(t *testing.T) {
+	input := `apiVersion: v1
+kind: Service
+metadata:
+  name: backend-svc
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: frontend
+spec:
+  template:
+    spec:
+      containers:
+      - name: frontend
+        image: example.com/frontend:1.0
+        env:
+        - name: BACKEND_ADDR
+          value: backend:8080
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: backend
+spec:
+  template:
+    spec:
+      containers:
+      - name: backend
+        image: example.com/backend:2.1
+`
+
+	cases := []struct {
+		desc     string
+		filter   ReplacementFilter
+		expected string
+		err      string
+	}{
+		{
+			desc: "partial string",
+			filter: ReplacementFilter{
+				Source: ReplacementSource{ResourceMetaFilter: ResourceMetaFilter{Kind: "Service"}},
+				Targets: []ReplacementTarget{{
+					ResourceMetaFilter: ResourceMetaFilter{Kind: "Deployment", Name: "frontend"},
+					FieldPaths:         []string{"spec/template/spec/containers/[name=frontend]/env/[name=BACKEND_ADDR]/value"},
+					Options:            &ReplacementOptions{Delimiter: ":"},
+				}},
+			},
+			expected: `apiVersion: v1
+kind: Service
+metadata:
+  name: backend-svc
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: frontend
+spec:
+  template:
+    spec:
+      containers:
+      - name: frontend
+        image: example.com/frontend:1.0
+        env:
+        - name: BACKEND_ADDR
+          value: backend-svc:8080
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: backend
+spec:
+  template:
+    spec:
+      containers:
+      - name: backend
+        image: example.com/backend:2.1
+`,
+		},
+		{
+			desc: "image tag with templated path",
+			filter: ReplacementFilter{
+				Source: ReplacementSource{
+					ResourceMetaFilter: ResourceMetaFilter{Name: "backend"},
+					FieldPath:          "spec/template/spec/containers/[name={.name}]/image",
+					Options:            &ReplacementOptions{Delimiter: ":", Index: 1},
+				},
+				Targets: []ReplacementTarget{
+					{
+						ResourceMetaFilter: ResourceMetaFilter{Kind: "Deployment", Name: "frontend"},
+						FieldPaths:         []string{"spec/template/spec/containers/[name={.name}]/image"},
+						Options:            &ReplacementOptions{Delimiter: ":", Index: 1},
+					},
+					{
+						ResourceMetaFilter: ResourceMetaFilter{Kind: "Deployment"},
+						FieldPaths:         []string{"metadata/labels/version", "metadata/annotations/missing"},
+						Options:            &ReplacementOptions{Create: true},
+					},
+				},
+			},
+			expected: `apiVersion: v1
+kind: Service
+metadata:
+  name: backend-svc
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: frontend
+  labels:
+    version: "2.1"
+  annotations:
+    missing: "2.1"
+spec:
+  template:
+    spec:
+      containers:
+      - name: frontend
+        image: example.com/frontend:2.1
+        env:
+        - name: BACKEND_ADDR
+          value: backend:8080
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: backend
+  labels:
+    version: "2.1"
+  annotations:
+    missing: "2.1"
+spec:
+  template:
+    spec:
+      containers:
+      - name: backend
+        image: example.com/backend:2.1
+`,
+		},
+		{
+			desc: "wildcard target",
+			filter: ReplacementFilter{
+				Source: ReplacementSource{ResourceMetaFilter: ResourceMetaFilter{Kind: "Service"}},
+				Targets: []ReplacementTarget{{
+					ResourceMetaFilter: ResourceMetaFilter{Kind: "Deployment"},
+					FieldPaths:         []string{"spec/template/spec/containers[*]/env[name=SERVICE]/value"},
+				}},
+			},
+			expected: `apiVersion: v1
+kind: Service
+metadata:
+  name: backend-svc
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: frontend
+spec:
+  template:
+    spec:
+      containers:
+      - name: frontend
+        image: example.com/frontend:1.0
+        env:
+        - name: BACKEND_ADDR
+          value: backend:8080
+        - name: SERVICE
+          value: backend-svc
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: backend
+spec:
+  template:
+    spec:
+      containers:
+      - name: backend
+        image: example.com/backend:2.1
+        env:
+        - name: SERVICE
+          value: backend-svc
+`,
+		},
+		{
+			desc: "missing target without create",
+			filter: ReplacementFilter{
+				Source: ReplacementSource{ResourceMetaFilter: ResourceMetaFilter{Kind: "Service"}},
+				Targets: []ReplacementTarget{{
+					ResourceMetaFilter: ResourceMetaFilter{Kind: "Deployment"},
+					FieldPaths:         []string{"spec/serviceName"},
+				}},
+			},
+			expected: input,
+		},
+		{
+			desc: "ambiguous source",
+			filter: ReplacementFilter{
+				Source: ReplacementSource{ResourceMetaFilter: ResourceMetaFilter{Kind: "Deployment"}},
+			},
+			err: "matched 2",
+		},
+		{
+			desc: "missing source field",
+			filter: ReplacementFilter{
+				Source: ReplacementSource{ResourceMetaFilter: ResourceMetaFilter{Kind: "Service"}, FieldPath: "spec/clusterIP"},
+			},
+			err: `"spec/clusterIP" not found`,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.desc, func(t *testing.T) {
+			nodes, err := kio.FromBytes([]byte(input))
+			require.NoError(t, err)
+
+			nodes, err = c.filter.Filter(nodes)
+			if c.err != "" {
+				assert.ErrorContains(t, err, c.err)
+				return
+			}
+			require.NoError(t, err)
+			actual, err := kio.StringAll(nodes)
+			require.NoError(t, err)
+			assert.Equal(t, c.expected, actual)
+		})
+	}
+}
@@ -17,9 +17,12 @@ limitations under the License.
 package filters
 
 import (
+	"fmt"
 	"regexp"
 	"strings"
 
+	"cel.dev/cel-go/cel"
+	"cel.dev/cel-go/ext"
 	"sigs.k8s.io/kustomize/kyaml/openapi"
 	"sigs.k8s.io/kustomize/kyaml/yaml"
 )
@@ -41,51 +44,95 @@ type ResourceMetaFilter struct {
 	LabelSelector string `json:"labelSelector,omitempty" yaml:"labelSelector,omitempty"`
 	// Kubernetes selector matching annotations
 	AnnotationSelector string `json:"annotationSelector,omitempty" yaml:"annotationSelector,omitempty"`
+	// CEL expression evaluated against each resource (available as `object`).
+	// Resources for which the expression cannot be evaluated (e.g. due to a
+	// missing field) do not match.
+	Expression string `json:"expression,omitempty" yaml:"expression,omitempty"`
 
 	// Invert the matching behavior (i.e. keep non-matching nodes).
 	InvertMatch bool `json:"invertMatch,omitempty" yaml:"invertMatch,omitempty"`
 }
 
 func (f *ResourceMetaFilter) Filter(nodes []*yaml.RNode) ([]*yaml.RNode, error) {
-	m, err := newMetaMatcher(f)
+	keep, err := f.matcher()
 	if err != nil {
 		return nil, err
 	}
 
-	if m == nil && f.LabelSelector == "" && f.AnnotationSelector == "" {
+	if keep == nil {
 		return nodes, nil
 	}
 
 	result := make([]*yaml.RNode, 0, len(nodes))
 	for _, n := range nodes {
+		if ok, err := keep(n); err != nil {
+			return nil, err
+		} else if ok {
+			result = append(result, n)
+		}
+	}
+
+	return result, nil
+}
+
+// matcher returns a function that determines if a node should be kept, or nil if all nodes should be kept.
+func (f *ResourceMetaFilter) matcher() (func(*yaml.RNode) (bool, error), error) {
+	m, err := newMetaMatcher(f)
+	if err != nil {
+		return nil, err
+	}
+
+	prg, err := compileExpression(f.Expression)
+	if err != nil {
+		return nil, err
+	}
+
+	if m == nil && prg == nil && f.LabelSelector == "" && f.AnnotationSelector == "" {
+		return nil, nil
+	}
+
+	return func(n *yaml.RNode) (bool, error) {
 		if m != nil {
 			if meta, err := n.GetMeta(); err != nil {
-				return nil, err
+				return false, err
 			} else if m.matchesMeta(meta) == f.InvertMatch {
-				continue
+				return false, nil
 			}
 		}
 
 		if f.LabelSelector != "" {
 			if matched, err := n.MatchesLabelSelector(f.LabelSelector); err != nil {
-				return nil, err
+				return false, err
 			} else if matched == f.InvertMatch {
-				continue
+				return false, nil
 			}
 		}
 
 		if f.AnnotationSelector != "" {
 			if matched, err := n.MatchesAnnotationSelector(f.AnnotationSelector); err != nil {
-				return nil, err
+				return false, err
 			} else if matched == f.InvertMatch {
-				continue
+				return false, nil
 			}
 		}
 
-		result = append(result, n)
-	}
+		if prg != nil {
+			if matched, err := matchesExpression(prg, n); err != nil {
+				return false, err
+			} else if matched == f.InvertMatch {
+				return false, nil
+			}
+		}
 
-	return result, nil
+		return true, nil
+	}, nil
+}
+
+// Validate checks the regular expressions and CEL expression of the filter
+// without matching any nodes.
+func (f *ResourceMetaFilter) Validate() error {
+	_, err := f.matcher()
+	return err
 }
 
 type metaMatcher struct {
@@ -173,6 +220,54 @@ func compileAnchored(pattern string) (*regexp.Regexp, error) {
 	return regexp.Compile("^(?:" + pattern + ")$")
 }
 
+// compileExpression returns a CEL program for the supplied expression.
+func compileExpression(expr string) (cel.Program, error) {
+	if expr == "" {
+		return nil, nil
+	}
+
+	env, err := cel.NewEnv(
+		cel.Variable("object", cel.MapType(cel.StringType, cel.DynType)),
+		ext.Strings(),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	ast, iss := env.Compile(expr)
+	if iss.Err() != nil {
+		return nil, fmt.Errorf("invalid expression %q: %w", expr, iss.Err())
+	}
+	if t := ast.OutputType(); !t.IsExactType(cel.BoolType) && !t.IsExactType(cel.DynType) {
+		return nil, fmt.Errorf("invalid expression %q: must evaluate to a bool, got %s", expr, t)
+	}
+
+	return env.Program(ast)
+}
+
+// matchesExpression evaluates the CEL program against the node.
+func matchesExpression(prg cel.Program, n *yaml.RNode) (bool, error) {
+	obj, err := n.Map()
+	if err != nil {
+		return false, err
+	}
+
+	out, _, err := prg.Eval(map[string]any{"object": obj})
+	if err != nil {
+		// Treat missing fields as a non-match, any other error is a problem with the expression
+		if strings.HasPrefix(err.Error(), "no such key") {
+			return false, nil
+		}
+		return false, fmt.Errorf("unable to evaluate expression: %w", err)
+	}
+
+	matched, ok := out.Value().(bool)
+	if !ok {
+		return false, fmt.Errorf("expression must evaluate to a bool, got %s", out.Type().TypeName())
+	}
+	return matched, nil
+}
+
 // SetNamespace is like `SetK8sNamespace` except it skips cluster scoped resources.
 func SetNamespace(namespace string) yaml.Filter {
 	return yaml.FilterFunc(func(node *yaml.RNode) (*yaml.RNode, error) {
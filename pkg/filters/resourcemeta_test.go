@@ -92,6 +92,34 @@ func TestResourceMetaFilter_Filter(t *testing.T) {
 				rmNode("test", nil, nil),
 			},
 		},
+		{
+			desc: "match expression",
+			filter: ResourceMetaFilter{
+				Expression: `object.metadata.name.startsWith("foo") && object.metadata.labels.app == "web"`,
+			},
+			input: []*yaml.RNode{
+				rmNode("foo", nil, nil),
+				rmNode("foobar", map[string]string{"app": "web"}, nil),
+				rmNode("barfoo", map[string]string{"app": "web"}, nil),
+			},
+			expected: []*yaml.RNode{
+				rmNode("foobar", map[string]string{"app": "web"}, nil),
+			},
+		},
+		{
+			desc: "match expression negate",
+			filter: ResourceMetaFilter{
+				Expression:  `object.metadata.labels.app == "web"`,
+				InvertMatch: true,
+			},
+			input: []*yaml.RNode{
+				rmNode("foo", nil, nil),
+				rmNode("foobar", map[string]string{"app": "web"}, nil),
+			},
+			expected: []*yaml.RNode{
+				rmNode("foo", nil, nil),
+			},
+		},
 	}
 	for _, c := range cases {
 		t.Run(c.desc, func(t *testing.T) {
@@ -103,6 +131,49 @@ func TestResourceMetaFilter_Filter(t *testing.T) {
 	}
 }
 
+func TestResourceMetaFilter_Validate(t *testing.T) {
+	cases := []struct {
+		desc   string
+		filter ResourceMetaFilter
+		err    string
+	}{
+		{
+			desc:   "valid expression",
+			filter: ResourceMetaFilter{Expression: `object.spec.replicas > 1`},
+		},
+		{
+			desc:   "syntax error",
+			filter: ResourceMetaFilter{Expression: `object.spec.replicas >`},
+			err:    "invalid expression",
+		},
+		{
+			desc:   "undeclared reference",
+			filter: ResourceMetaFilter{Expression: `obj.spec.replicas > 1`},
+			err:    "undeclared reference",
+		},
+		{
+			desc:   "not a bool",
+			filter: ResourceMetaFilter{Expression: `object.metadata.name + "x"`},
+			err:    "must evaluate to a bool",
+		},
+		{
+			desc:   "invalid regular expression",
+			filter: ResourceMetaFilter{Name: `foo(`},
+			err:    "missing closing )",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.desc, func(t *testing.T) {
+			err := c.filter.Validate()
+			if c.err == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorContains(t, err, c.err)
+			}
+		})
+	}
+}
+
 // node returns an RNode representing the supplied resource metadata.
 func rmNode(name string, labels, annotations map[string]string) *yaml.RNode {
 	data, err := yaml.Marshal(&yaml.ResourceMeta{
@@ -120,3 +191,33 @@ func rmNode(name string, labels, annotations map[string]string) *yaml.RNode {
 	}
 	return yaml.MustParse(string(data))
 }
+
+func TestResourceMetaFilter_FilterError(t *testing.T) {
+	cases := []struct {
+		desc   string
+		filter ResourceMetaFilter
+		err    string
+	}{
+		{
+			desc:   "invalid expression negate",
+			filter: ResourceMetaFilter{Expression: `object.metadata.name ==`, InvertMatch: true},
+			err:    "invalid expression",
+		},
+		{
+			desc:   "evaluation error negate",
+			filter: ResourceMetaFilter{Expression: `int(object.metadata.name) > 1`, InvertMatch: true},
+			err:    "unable to evaluate expression",
+		},
+		{
+			desc:   "non-bool result negate",
+			filter: ResourceMetaFilter{Expression: `object.metadata.name`, InvertMatch: true},
+			err:    "must evaluate to a bool",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.desc, func(t *testing.T) {
+			_, err := c.filter.Filter([]*yaml.RNode{rmNode("foo", nil, nil)})
+			assert.ErrorContains(t, err, c.err)
+		})
+	}
+}
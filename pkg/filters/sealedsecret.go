@@ -0,0 +1,298 @@
+/*
+Copyright 2022 GramLabs, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package filters
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"sigs.k8s.io/kustomize/kyaml/yaml"
+)
+
+const (
+	// SealedSecretNamespaceWideAnnotation indicates a sealed secret can be renamed within its namespace.
+	SealedSecretNamespaceWideAnnotation = "sealedsecrets.bitnami.com/namespace-wide"
+	// SealedSecretClusterWideAnnotation indicates a sealed secret can be renamed and moved to any namespace.
+	SealedSecretClusterWideAnnotation = "sealedsecrets.bitnami.com/cluster-wide"
+
+	// SealedSecretScopeStrict requires the sealed secret name and namespace to remain unchanged.
+	SealedSecretScopeStrict = "strict"
+	// SealedSecretScopeNamespaceWide allows the sealed secret to be renamed within its namespace.
+	SealedSecretScopeNamespaceWide = "namespace-wide"
+	// SealedSecretScopeClusterWide allows the sealed secret to be renamed and moved to any namespace.
+	SealedSecretScopeClusterWide = "cluster-wide"
+)
+
+// SealedSecretFilter converts Secrets into Bitnami SealedSecrets using the
+// public key of the sealed secrets controller. Unlike `kubeseal`, the
+// conversion happens entirely offline.
+type SealedSecretFilter struct {
+	// The public key of the sealed secrets controller.
+	PublicKey *rsa.PublicKey
+	// The scope of the sealed secrets. If empty, the scope is determined by the
+	// annotations on each secret (defaulting to strict).
+	Scope string
+}
+
+// Filter replaces each secret with an equivalent sealed secret.
+func (f *SealedSecretFilter) Filter(nodes []*yaml.RNode) ([]*yaml.RNode, error) {
+	if f.PublicKey == nil {
+		return nil, errors.New("sealed secrets controller public key is required")
+	}
+
+	for i := range nodes {
+		if nodes[i].GetApiVersion() != "v1" || nodes[i].GetKind() != "Secret" {
+			continue
+		}
+
+		n, err := f.seal(nodes[i])
+		if err != nil {
+			return nil, err
+		}
+		nodes[i] = n
+	}
+
+	return nodes, nil
+}
+
+// seal produces a sealed secret from the supplied secret node.
+func (f *SealedSecretFilter) seal(secret *yaml.RNode) (*yaml.RNode, error) {
+	md, err := secret.GetMeta()
+	if err != nil {
+		return nil, err
+	}
+
+	scope := f.Scope
+	if scope == "" {
+		scope = sealedSecretScope(md.Annotations)
+	}
+
+	var label string
+	switch scope {
+	case SealedSecretScopeStrict:
+		label = md.Namespace + "/" + md.Name
+	case SealedSecretScopeNamespaceWide:
+		label = md.Namespace
+	case SealedSecretScopeClusterWide:
+		label = ""
+	default:
+		return nil, fmt.Errorf("invalid sealed secret scope %q", scope)
+	}
+	if md.Namespace == "" && scope != SealedSecretScopeClusterWide {
+		return nil, fmt.Errorf("namespace is required to seal secret %q using the %s scope", md.Name, scope)
+	}
+
+	// Collect the plain text data (string data takes precedence)
+	data := make(map[string][]byte)
+	for k, v := range secret.GetDataMap() {
+		if data[k], err = base64.StdEncoding.DecodeString(strings.Join(strings.Fields(v), "")); err != nil {
+			return nil, fmt.Errorf("invalid data for key %q of secret %q: %w", k, md.Name, err)
+		}
+	}
+	if stringData, err := secret.Pipe(yaml.Lookup("stringData")); err != nil {
+		return nil, err
+	} else if stringData != nil {
+		if err := stringData.VisitFields(func(node *yaml.MapNode) error {
+			data[node.Key.YNode().Value] = []byte(node.Value.YNode().Value)
+			return nil
+		}); err != nil {
+			return nil, err
+		}
+	}
+
+	encryptedData := yaml.NewMapRNode(nil)
+	for _, k := range slices.Sorted(maps.Keys(data)) {
+		ciphertext, err := sealedSecretEncrypt(f.PublicKey, data[k], []byte(label))
+		if err != nil {
+			return nil, err
+		}
+		if err := encryptedData.PipeE(yaml.SetField(k, yaml.NewStringRNode(base64.StdEncoding.EncodeToString(ciphertext)))); err != nil {
+			return nil, err
+		}
+	}
+
+	// Build the sealed secret, retaining the metadata of the original secret
+	ss, err := yaml.FromMap(map[string]any{
+		"apiVersion": "bitnami.com/v1alpha1",
+		"kind":       "SealedSecret",
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := ss.PipeE(yaml.SetField(yaml.MetadataField, secret.Field(yaml.MetadataField).Value.Copy())); err != nil {
+		return nil, err
+	}
+	if err := ss.PipeE(yaml.Tee(yaml.ClearAnnotation(SealedSecretNamespaceWideAnnotation))); err != nil {
+		return nil, err
+	}
+	if err := ss.PipeE(yaml.Tee(yaml.ClearAnnotation(SealedSecretClusterWideAnnotation))); err != nil {
+		return nil, err
+	}
+	switch scope {
+	case SealedSecretScopeNamespaceWide:
+		err = ss.PipeE(yaml.SetAnnotation(SealedSecretNamespaceWideAnnotation, "true"))
+	case SealedSecretScopeClusterWide:
+		err = ss.PipeE(yaml.SetAnnotation(SealedSecretClusterWideAnnotation, "true"))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// The template only includes the metadata that will be copied to the unsealed secret
+	template := yaml.NewMapRNode(nil)
+	if err := template.SetName(md.Name); err != nil {
+		return nil, err
+	}
+	if md.Namespace != "" {
+		if err := template.SetNamespace(md.Namespace); err != nil {
+			return nil, err
+		}
+	}
+	if err := template.SetLabels(md.Labels); err != nil {
+		return nil, err
+	}
+	if err := template.SetAnnotations(templateAnnotations(md.Annotations)); err != nil {
+		return nil, err
+	}
+	for _, field := range []string{"type", "immutable"} {
+		if value := secret.Field(field); value != nil {
+			if err := template.PipeE(yaml.SetField(field, value.Value.Copy())); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	spec := yaml.NewMapRNode(nil)
+	if len(data) > 0 {
+		if err := spec.PipeE(yaml.SetField("encryptedData", encryptedData)); err != nil {
+			return nil, err
+		}
+	}
+	if err := spec.PipeE(yaml.SetField("template", template)); err != nil {
+		return nil, err
+	}
+	if err := ss.PipeE(yaml.SetField("spec", spec)); err != nil {
+		return nil, err
+	}
+
+	return ss, nil
+}
+
+// ParseSealedSecretsPublicKey parses the PEM encoded certificate (or public key) of the sealed secrets controller.
+func ParseSealedSecretsPublicKey(data []byte) (*rsa.PublicKey, error) {
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			return nil, errors.New("no sealed secrets controller certificate found")
+		}
+
+		var key any
+		switch block.Type {
+		case "CERTIFICATE":
+			cert, err := x509.ParseCertificate(block.Bytes)
+			if err != nil {
+				return nil, err
+			}
+			key = cert.PublicKey
+		case "PUBLIC KEY":
+			var err error
+			if key, err = x509.ParsePKIXPublicKey(block.Bytes); err != nil {
+				return nil, err
+			}
+		case "RSA PUBLIC KEY":
+			var err error
+			if key, err = x509.ParsePKCS1PublicKey(block.Bytes); err != nil {
+				return nil, err
+			}
+		default:
+			continue
+		}
+
+		if pub, ok := key.(*rsa.PublicKey); ok {
+			return pub, nil
+		}
+		return nil, fmt.Errorf("unsupported sealed secrets controller key type %T", key)
+	}
+}
+
+// sealedSecretScope returns the scope indicated by the supplied annotations.
+func sealedSecretScope(annotations map[string]string) string {
+	switch {
+	case annotations[SealedSecretClusterWideAnnotation] == "true":
+		return SealedSecretScopeClusterWide
+	case annotations[SealedSecretNamespaceWideAnnotation] == "true":
+		return SealedSecretScopeNamespaceWide
+	default:
+		return SealedSecretScopeStrict
+	}
+}
+
+// templateAnnotations returns the annotations to include on the template of the sealed secret.
+func templateAnnotations(annotations map[string]string) map[string]string {
+	result := make(map[string]string, len(annotations))
+	for k, v := range annotations {
+		switch {
+		case k == SealedSecretNamespaceWideAnnotation, k == SealedSecretClusterWideAnnotation:
+		case strings.HasPrefix(k, "config.kubernetes.io/"), strings.HasPrefix(k, "internal.config.kubernetes.io/"):
+		default:
+			result[k] = v
+		}
+	}
+	return result
+}
+
+// sealedSecretEncrypt performs the hybrid (RSA-OAEP and AES-GCM) encryption used by sealed secrets.
+func sealedSecretEncrypt(pub *rsa.PublicKey, plaintext, label []byte) ([]byte, error) {
+	sessionKey := make([]byte, 32)
+	if _, err := rand.Read(sessionKey); err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(sessionKey)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	rsaCiphertext, err := rsa.EncryptOAEP(sha256.New(), rand.Reader, pub, sessionKey, label)
+	if err != nil {
+		return nil, err
+	}
+
+	// The first two bytes are the length of the encrypted session key
+	ciphertext := binary.BigEndian.AppendUint16(nil, uint16(len(rsaCiphertext)))
+	ciphertext = append(ciphertext, rsaCiphertext...)
+
+	// The session key is only used once so a zero nonce is safe
+	return aead.Seal(ciphertext, make([]byte, aead.NonceSize()), plaintext, nil), nil
+}
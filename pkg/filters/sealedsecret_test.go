@@ -0,0 +1,170 @@
+/*
+Copyright 2022 GramLabs, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package filters
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"sigs.k8s.io/kustomize/kyaml/yaml"
+)
+
+func TestSealedSecretFilter_Filter(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "sealed-secret"},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	pub, err := ParseSealedSecretsPublicKey(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))
+	require.NoError(t, err)
+
+	cases := []struct {
+		desc               string
+		scope              string
+		secret             string
+		expectedLabel      string
+		expectedAnnotation string
+		expectedErr        string
+	}{
+		{
+			desc: "strict",
+			secret: `apiVersion: v1
+kind: Secret
+metadata:
+  name: test
+  namespace: default
+stringData:
+  foo: bar
+`,
+			expectedLabel: "default/test",
+		},
+		{
+			desc:  "namespace wide",
+			scope: SealedSecretScopeNamespaceWide,
+			secret: `apiVersion: v1
+kind: Secret
+metadata:
+  name: test
+  namespace: default
+data:
+  foo: YmFy
+`,
+			expectedLabel:      "default",
+			expectedAnnotation: SealedSecretNamespaceWideAnnotation,
+		},
+		{
+			desc: "cluster wide annotation",
+			secret: `apiVersion: v1
+kind: Secret
+metadata:
+  name: test
+  annotations:
+    sealedsecrets.bitnami.com/cluster-wide: "true"
+data:
+  foo: YmFy
+`,
+			expectedLabel:      "",
+			expectedAnnotation: SealedSecretClusterWideAnnotation,
+		},
+		{
+			desc: "missing namespace",
+			secret: `apiVersion: v1
+kind: Secret
+metadata:
+  name: test
+data:
+  foo: YmFy
+`,
+			expectedErr: `namespace is required to seal secret "test" using the strict scope`,
+		},
+		{
+			desc:  "invalid scope",
+			scope: "galaxy-wide",
+			secret: `apiVersion: v1
+kind: Secret
+metadata:
+  name: test
+`,
+			expectedErr: `invalid sealed secret scope "galaxy-wide"`,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.desc, func(t *testing.T) {
+			secret := yaml.MustParse(c.secret)
+			nodes, err := (&SealedSecretFilter{PublicKey: pub, Scope: c.scope}).Filter([]*yaml.RNode{secret})
+			if c.expectedErr != "" {
+				assert.EqualError(t, err, c.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Len(t, nodes, 1)
+
+			ss := nodes[0]
+			assert.Equal(t, "bitnami.com/v1alpha1", ss.GetApiVersion())
+			assert.Equal(t, "SealedSecret", ss.GetKind())
+			assert.Equal(t, secret.GetName(), ss.GetName())
+			if c.expectedAnnotation != "" {
+				assert.Equal(t, map[string]string{c.expectedAnnotation: "true"}, ss.GetAnnotations())
+			} else {
+				assert.Empty(t, ss.GetAnnotations())
+			}
+
+			name, err := ss.GetString("spec.template.metadata.name")
+			require.NoError(t, err)
+			assert.Equal(t, secret.GetName(), name)
+
+			encrypted, err := ss.GetString("spec.encryptedData.foo")
+			require.NoError(t, err)
+			ciphertext, err := base64.StdEncoding.DecodeString(encrypted)
+			require.NoError(t, err)
+			assert.Equal(t, "bar", sealedSecretDecrypt(t, key, ciphertext, []byte(c.expectedLabel)))
+		})
+	}
+}
+
+func sealedSecretDecrypt(t *testing.T, key *rsa.PrivateKey, ciphertext, label []byte) string {
+	rsaLen := int(binary.BigEndian.Uint16(ciphertext))
+	sessionKey, err := rsa.DecryptOAEP(sha256.New(), rand.Reader, key, ciphertext[2:rsaLen+2], label)
+	require.NoError(t, err)
+
+	block, err := aes.NewCipher(sessionKey)
+	require.NoError(t, err)
+	aead, err := cipher.NewGCM(block)
+	require.NoError(t, err)
+
+	plaintext, err := aead.Open(nil, make([]byte, aead.NonceSize()), ciphertext[rsaLen+2:], nil)
+	require.NoError(t, err)
+	return string(plaintext)
+}
@@ -0,0 +1,524 @@
+/*
+Copyright 2022 GramLabs, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package filters
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"k8s.io/kube-openapi/pkg/validation/spec"
+	"sigs.k8s.io/kustomize/kyaml/openapi"
+	"sigs.k8s.io/kustomize/kyaml/yaml"
+)
+
+const (
+	smpPatchDirective        = "$patch"
+	smpRetainKeysDirective   = "$retainKeys"
+	smpSetElementOrderPrefix = "$setElementOrder/"
+	smpDeleteFromListPrefix  = "$deleteFromPrimitiveList/"
+)
+
+// ResourceSchemas provides the OpenAPI schemas used to merge resources. In
+// addition to the built-in Kubernetes schemas, the schemas of any custom
+// resources defined by a `CustomResourceDefinition` are also available.
+type ResourceSchemas struct {
+	custom map[yaml.TypeMeta]*spec.Schema
+}
+
+// NewResourceSchemas returns the schemas for the custom resource definitions found in the supplied nodes.
+func NewResourceSchemas(nodes []*yaml.RNode) (*ResourceSchemas, error) {
+	s := &ResourceSchemas{custom: make(map[yaml.TypeMeta]*spec.Schema)}
+	for _, node := range nodes {
+		if node.GetKind() != "CustomResourceDefinition" || !strings.HasPrefix(node.GetApiVersion(), "apiextensions.k8s.io/") {
+			continue
+		}
+		if err := s.addCustomResourceDefinition(node); err != nil {
+			return nil, fmt.Errorf("invalid custom resource definition %q: %w", node.GetName(), err)
+		}
+	}
+	return s, nil
+}
+
+// SchemaForResourceType returns the schema for the supplied type or nil if the type is unknown.
+func (s *ResourceSchemas) SchemaForResourceType(tm yaml.TypeMeta) *openapi.ResourceSchema {
+	if s != nil {
+		if schema, ok := s.custom[tm]; ok {
+			return &openapi.ResourceSchema{Schema: schema}
+		}
+	}
+	return openapi.SchemaForResourceType(tm)
+}
+
+func (s *ResourceSchemas) addCustomResourceDefinition(node *yaml.RNode) error {
+	group, err := node.GetString("spec.group")
+	if err != nil {
+		return err
+	}
+	kind, err := node.GetString("spec.names.kind")
+	if err != nil {
+		return err
+	}
+
+	// The v1beta1 API allows a single schema for all versions
+	var commonSchema *spec.Schema
+	if n, err := node.Pipe(yaml.Lookup("spec", "validation", "openAPIV3Schema")); err != nil {
+		return err
+	} else if n != nil {
+		if commonSchema, err = unmarshalSchema(n); err != nil {
+			return err
+		}
+	}
+
+	versions, err := node.Pipe(yaml.Lookup("spec", "versions"))
+	if err != nil {
+		return err
+	}
+	if versions == nil {
+		if version, _ := node.GetString("spec.version"); version != "" && commonSchema != nil {
+			s.custom[yaml.TypeMeta{APIVersion: group + "/" + version, Kind: kind}] = commonSchema
+		}
+		return nil
+	}
+
+	return versions.VisitElements(func(v *yaml.RNode) error {
+		name, err := v.GetString("name")
+		if err != nil {
+			return err
+		}
+
+		schema := commonSchema
+		if n, err := v.Pipe(yaml.Lookup("schema", "openAPIV3Schema")); err != nil {
+			return err
+		} else if n != nil {
+			if schema, err = unmarshalSchema(n); err != nil {
+				return err
+			}
+		}
+
+		if schema != nil {
+			s.custom[yaml.TypeMeta{APIVersion: group + "/" + name, Kind: kind}] = schema
+		}
+		return nil
+	})
+}
+
+// unmarshalSchema converts a YAML node into an OpenAPI schema.
+func unmarshalSchema(node *yaml.RNode) (*spec.Schema, error) {
+	data, err := node.MarshalJSON()
+	if err != nil {
+		return nil, err
+	}
+	schema := &spec.Schema{}
+	if err := schema.UnmarshalJSON(data); err != nil {
+		return nil, err
+	}
+	return schema, nil
+}
+
+// strategicMerge merges a patch into a YAML node tree. The tree is modified in
+// place so comments, field order and styles are preserved.
+type strategicMerge struct {
+	// Flag indicating that strategic merge patch directives (e.g. `$patch`) and
+	// list merge strategies should be honored, otherwise this is a JSON Merge Patch.
+	strategic bool
+	// Flag indicating that list elements which are not already present in the
+	// destination are added to the front of the list instead of the end.
+	prepend bool
+}
+
+// merge returns the result of merging the patch into the destination, nil indicates the value should be deleted.
+func (m *strategicMerge) merge(dest, patch *yaml.Node, rs *openapi.ResourceSchema) (*yaml.Node, error) {
+	switch {
+	case dest != nil && dest.Kind == yaml.MappingNode && patch.Kind == yaml.MappingNode:
+		return m.mergeMap(dest, patch, rs)
+	case dest != nil && dest.Kind == yaml.SequenceNode && patch.Kind == yaml.SequenceNode:
+		return m.mergeList(dest, patch, rs)
+	default:
+		return m.replace(dest, patch), nil
+	}
+}
+
+// mergeMap merges the fields of the patch into the destination.
+func (m *strategicMerge) mergeMap(dest, patch *yaml.Node, rs *openapi.ResourceSchema) (*yaml.Node, error) {
+	var retainKeys []string
+	var hasRetainKeys bool
+	orders := make(map[string]*yaml.Node)
+	deletes := make(map[string]*yaml.Node)
+	if m.strategic {
+		for i := 0; i < len(patch.Content)-1; i += 2 {
+			key, value := patch.Content[i].Value, patch.Content[i+1]
+			switch {
+			case key == smpPatchDirective:
+				switch value.Value {
+				case "delete":
+					return nil, nil
+				case "replace":
+					return m.replace(dest, patch), nil
+				case "merge":
+				default:
+					return nil, fmt.Errorf("unknown patch strategy %q", value.Value)
+				}
+			case key == smpRetainKeysDirective:
+				hasRetainKeys = true
+				for _, k := range value.Content {
+					retainKeys = append(retainKeys, k.Value)
+				}
+			case strings.HasPrefix(key, smpSetElementOrderPrefix):
+				orders[strings.TrimPrefix(key, smpSetElementOrderPrefix)] = value
+			case strings.HasPrefix(key, smpDeleteFromListPrefix):
+				deletes[strings.TrimPrefix(key, smpDeleteFromListPrefix)] = value
+			}
+		}
+	}
+
+	copyComments(dest, patch)
+	for i := 0; i < len(patch.Content)-1; i += 2 {
+		key, value := patch.Content[i].Value, patch.Content[i+1]
+		if m.isDirective(key) {
+			continue
+		}
+
+		// A null value removes the field
+		if value.Tag == yaml.NodeTagNull {
+			removeField(dest, key)
+			continue
+		}
+
+		existing := fieldValue(dest, key)
+		if existing == nil {
+			if v := m.clean(value); v != nil {
+				dest.Content = append(dest.Content, yaml.CopyYNode(patch.Content[i]), v)
+			}
+			continue
+		}
+
+		merged, err := m.merge(existing, value, schemaField(rs, key))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", key, err)
+		}
+		if merged == nil {
+			removeField(dest, key)
+		} else {
+			setFieldValue(dest, key, merged)
+		}
+	}
+
+	for field, values := range deletes {
+		if list := fieldValue(dest, field); list != nil && list.Kind == yaml.SequenceNode {
+			list.Content = slices.DeleteFunc(list.Content, func(n *yaml.Node) bool {
+				return slices.ContainsFunc(values.Content, func(v *yaml.Node) bool { return v.Value == n.Value })
+			})
+		}
+	}
+
+	for field, order := range orders {
+		if list := fieldValue(dest, field); list != nil && list.Kind == yaml.SequenceNode {
+			_, keys := listStrategy(schemaField(rs, field))
+			setElementOrder(list, order, keys)
+		}
+	}
+
+	if hasRetainKeys {
+		for i := len(dest.Content) - 2; i >= 0; i -= 2 {
+			if !slices.Contains(retainKeys, dest.Content[i].Value) {
+				dest.Content = append(dest.Content[:i], dest.Content[i+2:]...)
+			}
+		}
+	}
+
+	return dest, nil
+}
+
+// mergeList merges the elements of the patch into the destination.
+func (m *strategicMerge) mergeList(dest, patch *yaml.Node, rs *openapi.ResourceSchema) (*yaml.Node, error) {
+	isMerge, keys := listStrategy(rs)
+	if !m.strategic || !isMerge {
+		return m.replace(dest, patch), nil
+	}
+
+	// Check for a directive that applies to the whole list
+	elements := make([]*yaml.Node, 0, len(patch.Content))
+	for _, e := range patch.Content {
+		if directive, ok := listDirective(e); ok {
+			switch directive {
+			case "replace":
+				return m.replace(dest, patch), nil
+			case "delete":
+				return nil, nil
+			case "merge":
+				continue
+			default:
+				return nil, fmt.Errorf("unknown patch strategy %q", directive)
+			}
+		}
+		elements = append(elements, e)
+	}
+
+	copyComments(dest, patch)
+
+	// Lists of primitives are merged as a set
+	var added []*yaml.Node
+	if len(keys) == 0 {
+		for _, e := range elements {
+			if e.Kind != yaml.ScalarNode || !slices.ContainsFunc(dest.Content, func(n *yaml.Node) bool { return n.Value == e.Value }) {
+				if v := m.clean(e); v != nil {
+					added = append(added, v)
+				}
+			}
+		}
+		m.addElements(dest, added)
+		return dest, nil
+	}
+
+	for _, e := range elements {
+		if e.Kind != yaml.MappingNode {
+			return nil, fmt.Errorf("expected a map element with merge keys %v", keys)
+		}
+		if !slices.ContainsFunc(keys, func(k string) bool { return fieldValue(e, k) != nil }) {
+			return nil, fmt.Errorf("list element is missing merge keys %v", keys)
+		}
+
+		i := slices.IndexFunc(dest.Content, func(n *yaml.Node) bool { return matchesMergeKeys(n, e, keys) })
+		if i < 0 {
+			if v := m.clean(e); v != nil {
+				added = append(added, v)
+			}
+			continue
+		}
+
+		merged, err := m.mergeMap(dest.Content[i], e, schemaElements(rs))
+		if err != nil {
+			return nil, err
+		}
+		if merged == nil {
+			dest.Content = append(dest.Content[:i], dest.Content[i+1:]...)
+		} else {
+			dest.Content[i] = merged
+		}
+	}
+
+	m.addElements(dest, added)
+	return dest, nil
+}
+
+// addElements adds new elements to the destination list, preserving their relative order.
+func (m *strategicMerge) addElements(dest *yaml.Node, elements []*yaml.Node) {
+	if m.prepend {
+		dest.Content = append(elements, dest.Content...)
+	} else {
+		dest.Content = append(dest.Content, elements...)
+	}
+}
+
+// replace returns a copy of the patch (without directives) to replace the destination.
+func (m *strategicMerge) replace(dest, patch *yaml.Node) *yaml.Node {
+	v := m.clean(patch)
+	if v != nil && dest != nil {
+		if v.HeadComment == "" {
+			v.HeadComment = dest.HeadComment
+		}
+		if v.LineComment == "" {
+			v.LineComment = dest.LineComment
+		}
+		if v.FootComment == "" {
+			v.FootComment = dest.FootComment
+		}
+	}
+	return v
+}
+
+// clean returns a copy of the patch with the directives removed, nil indicates the value was deleted.
+func (m *strategicMerge) clean(patch *yaml.Node) *yaml.Node {
+	if !m.strategic {
+		return yaml.CopyYNode(patch)
+	}
+
+	switch patch.Kind {
+	case yaml.MappingNode:
+		if v := fieldValue(patch, smpPatchDirective); v != nil && v.Value == "delete" {
+			return nil
+		}
+		result := yaml.CopyYNode(patch)
+		result.Content = nil
+		for i := 0; i < len(patch.Content)-1; i += 2 {
+			if m.isDirective(patch.Content[i].Value) {
+				continue
+			}
+			if v := m.clean(patch.Content[i+1]); v != nil {
+				result.Content = append(result.Content, yaml.CopyYNode(patch.Content[i]), v)
+			}
+		}
+		return result
+
+	case yaml.SequenceNode:
+		result := yaml.CopyYNode(patch)
+		result.Content = nil
+		for _, e := range patch.Content {
+			if _, ok := listDirective(e); ok {
+				continue
+			}
+			if v := m.clean(e); v != nil {
+				result.Content = append(result.Content, v)
+			}
+		}
+		return result
+
+	default:
+		return yaml.CopyYNode(patch)
+	}
+}
+
+// isDirective checks if the field name is a strategic merge patch directive.
+func (m *strategicMerge) isDirective(key string) bool {
+	return m.strategic && (key == smpPatchDirective || key == smpRetainKeysDirective ||
+		strings.HasPrefix(key, smpSetElementOrderPrefix) || strings.HasPrefix(key, smpDeleteFromListPrefix))
+}
+
+// listStrategy returns the merge strategy and keys for a list.
+func listStrategy(rs *openapi.ResourceSchema) (bool, []string) {
+	if rs == nil || rs.Schema == nil {
+		return false, nil
+	}
+
+	// Built-in types use the patch strategy extensions
+	if strategy, _ := rs.Schema.Extensions.GetString("x-kubernetes-patch-strategy"); strings.Contains(strategy, "merge") {
+		if key, _ := rs.Schema.Extensions.GetString("x-kubernetes-patch-merge-key"); key != "" {
+			return true, []string{key}
+		}
+		return true, nil
+	}
+
+	// Custom resources use the list type extensions
+	switch listType, _ := rs.Schema.Extensions.GetString("x-kubernetes-list-type"); listType {
+	case "map":
+		keys, _ := rs.Schema.Extensions.GetStringSlice("x-kubernetes-list-map-keys")
+		return len(keys) > 0, keys
+	case "set":
+		return true, nil
+	}
+
+	return false, nil
+}
+
+// listDirective returns the value of a directive element in a list (e.g. `- $patch: replace`).
+func listDirective(n *yaml.Node) (string, bool) {
+	if n.Kind == yaml.MappingNode && len(n.Content) == 2 && n.Content[0].Value == smpPatchDirective {
+		return n.Content[1].Value, true
+	}
+	return "", false
+}
+
+// matchesMergeKeys checks if two elements have the same merge key values.
+func matchesMergeKeys(n, e *yaml.Node, keys []string) bool {
+	if n.Kind != yaml.MappingNode {
+		return false
+	}
+	for _, k := range keys {
+		nv, ev := fieldValue(n, k), fieldValue(e, k)
+		if (nv == nil) != (ev == nil) || (nv != nil && nv.Value != ev.Value) {
+			return false
+		}
+	}
+	return true
+}
+
+// setElementOrder reorders the list according to the supplied order. Elements
+// not present in the order retain their positions.
+func setElementOrder(list, order *yaml.Node, keys []string) {
+	position := func(n *yaml.Node) int {
+		return slices.IndexFunc(order.Content, func(o *yaml.Node) bool {
+			if len(keys) == 0 {
+				return o.Value == n.Value
+			}
+			return matchesMergeKeys(n, o, keys)
+		})
+	}
+
+	var ordered []*yaml.Node
+	for _, n := range list.Content {
+		if position(n) >= 0 {
+			ordered = append(ordered, n)
+		}
+	}
+	slices.SortStableFunc(ordered, func(a, b *yaml.Node) int { return position(a) - position(b) })
+
+	for i, n := range list.Content {
+		if position(n) >= 0 {
+			list.Content[i], ordered = ordered[0], ordered[1:]
+		}
+	}
+}
+
+// copyComments copies the non-empty comments from the source to the destination.
+func copyComments(dest, src *yaml.Node) {
+	if src.HeadComment != "" {
+		dest.HeadComment = src.HeadComment
+	}
+	if src.LineComment != "" {
+		dest.LineComment = src.LineComment
+	}
+	if src.FootComment != "" {
+		dest.FootComment = src.FootComment
+	}
+}
+
+// schemaField returns the schema of a field or nil.
+func schemaField(rs *openapi.ResourceSchema, field string) *openapi.ResourceSchema {
+	if rs == nil || rs.Schema == nil {
+		return nil
+	}
+	return rs.Field(field)
+}
+
+// schemaElements returns the schema of list elements or nil.
+func schemaElements(rs *openapi.ResourceSchema) *openapi.ResourceSchema {
+	if rs == nil || rs.Schema == nil {
+		return nil
+	}
+	return rs.Elements()
+}
+
+// fieldValue returns the value of a mapping node field or nil.
+func fieldValue(n *yaml.Node, key string) *yaml.Node {
+	for i := 0; i < len(n.Content)-1; i += 2 {
+		if n.Content[i].Value == key {
+			return n.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// setFieldValue replaces the value of an existing mapping node field.
+func setFieldValue(n *yaml.Node, key string, value *yaml.Node) {
+	for i := 0; i < len(n.Content)-1; i += 2 {
+		if n.Content[i].Value == key {
+			n.Content[i+1] = value
+			return
+		}
+	}
+}
+
+// removeField removes a field from a mapping node.
+func removeField(n *yaml.Node, key string) {
+	for i := 0; i < len(n.Content)-1; i += 2 {
+		if n.Content[i].Value == key {
+			n.Content = append(n.Content[:i], n.Content[i+2:]...)
+			return
+		}
+	}
+}
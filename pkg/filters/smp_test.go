@@ -0,0 +1,366 @@
+/*
+Copyright 2022 GramLabs, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package filters
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"sigs.k8s.io/kustomize/kyaml/kio"
+	"sigs.k8s.io/kustomize/kyaml/yaml"
+)
+
+func TestPatchFilter_StrategicMergePatch(t *testing.T) {
+	input := `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: test
+  finalizers:
+  - a
+  - b
+spec:
+  replicas: 1 # the replicas
+  strategy:
+    type: RollingUpdate
+    rollingUpdate:
+      maxSurge: 1
+  template:
+    spec:
+      containers:
+      - name: app
+        image: nginx
+        ports:
+        - containerPort: 80
+      - name: sidecar
+        image: busybox
+`
+
+	cases := []struct {
+		desc      string
+		patchType string
+		patch     string
+		expected  string
+		err       string
+	}{
+		{
+			desc:  "merge by key prepends new elements",
+			patch: `{"spec": {"replicas": 3, "template": {"spec": {"containers": [{"name": "sidecar", "image": "alpine"}, {"name": "init", "image": "busybox"}]}}}}`,
+			expected: `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: test
+  finalizers:
+  - a
+  - b
+spec:
+  replicas: 3 # the replicas
+  strategy:
+    type: RollingUpdate
+    rollingUpdate:
+      maxSurge: 1
+  template:
+    spec:
+      containers:
+      - name: init
+        image: busybox
+      - name: app
+        image: nginx
+        ports:
+        - containerPort: 80
+      - name: sidecar
+        image: alpine
+`,
+		},
+		{
+			desc:  "delete and replace directives",
+			patch: `{"metadata": {"finalizers": [{"$patch": "replace"}, "c"]}, "spec": {"template": {"spec": {"containers": [{"name": "sidecar", "$patch": "delete"}, {"name": "app", "ports": [{"containerPort": 8080}]}]}}}}`,
+			expected: `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: test
+  finalizers:
+  - c
+spec:
+  replicas: 1 # the replicas
+  strategy:
+    type: RollingUpdate
+    rollingUpdate:
+      maxSurge: 1
+  template:
+    spec:
+      containers:
+      - name: app
+        image: nginx
+        ports:
+        - containerPort: 8080
+        - containerPort: 80
+`,
+		},
+		{
+			desc:  "retain keys",
+			patch: `{"spec": {"strategy": {"$retainKeys": ["type"], "type": "Recreate"}}}`,
+			expected: `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: test
+  finalizers:
+  - a
+  - b
+spec:
+  replicas: 1 # the replicas
+  strategy:
+    type: Recreate
+  template:
+    spec:
+      containers:
+      - name: app
+        image: nginx
+        ports:
+        - containerPort: 80
+      - name: sidecar
+        image: busybox
+`,
+		},
+		{
+			desc:  "primitive lists",
+			patch: `{"metadata": {"finalizers": ["c", "a"], "$deleteFromPrimitiveList/finalizers": ["b"]}}`,
+			expected: `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: test
+  finalizers:
+  - c
+  - a
+spec:
+  replicas: 1 # the replicas
+  strategy:
+    type: RollingUpdate
+    rollingUpdate:
+      maxSurge: 1
+  template:
+    spec:
+      containers:
+      - name: app
+        image: nginx
+        ports:
+        - containerPort: 80
+      - name: sidecar
+        image: busybox
+`,
+		},
+		{
+			desc:  "set element order",
+			patch: `{"spec": {"template": {"spec": {"$setElementOrder/containers": [{"name": "sidecar"}, {"name": "app"}]}}}}`,
+			expected: `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: test
+  finalizers:
+  - a
+  - b
+spec:
+  replicas: 1 # the replicas
+  strategy:
+    type: RollingUpdate
+    rollingUpdate:
+      maxSurge: 1
+  template:
+    spec:
+      containers:
+      - name: sidecar
+        image: busybox
+      - name: app
+        image: nginx
+        ports:
+        - containerPort: 80
+`,
+		},
+		{
+			desc:      "merge patch replaces lists",
+			patchType: "merge",
+			patch:     `{"metadata": {"finalizers": ["c"]}, "spec": {"replicas": null, "strategy": null, "template": {"spec": {"containers": [{"name": "app", "image": "nginx"}]}}}}`,
+			expected: `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: test
+  finalizers:
+  - c
+spec:
+  template:
+    spec:
+      containers:
+      - name: app
+        image: nginx
+`,
+		},
+		{
+			desc:  "missing merge key",
+			patch: `{"spec": {"template": {"spec": {"containers": [{"image": "alpine"}]}}}}`,
+			err:   "missing merge keys",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.desc, func(t *testing.T) {
+			node := yaml.MustParse(input)
+			patchType := c.patchType
+			if patchType == "" {
+				patchType = "strategic"
+			}
+			f := &PatchFilter{PatchType: patchType, PatchData: []byte(c.patch)}
+
+			_, err := f.Filter(node)
+			if c.err != "" {
+				assert.ErrorContains(t, err, c.err)
+				return
+			}
+			require.NoError(t, err)
+			actual, err := node.String()
+			require.NoError(t, err)
+			assert.Equal(t, c.expected, actual)
+		})
+	}
+}
+
+func TestPatchFilter_CustomResource(t *testing.T) {
+	nodes, err := kio.FromBytes([]byte(`apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: widgets.example.com
+spec:
+  group: example.com
+  names:
+    kind: Widget
+  versions:
+  - name: v1
+    schema:
+      openAPIV3Schema:
+        type: object
+        properties:
+          spec:
+            type: object
+            properties:
+              parts:
+                type: array
+                x-kubernetes-list-type: map
+                x-kubernetes-list-map-keys: [name]
+                items:
+                  type: object
+---
+apiVersion: example.com/v1
+kind: Widget
+metadata:
+  name: test
+spec:
+  parts:
+  - name: a
+    size: 1
+  - name: b
+    size: 2
+`))
+	require.NoError(t, err)
+
+	schemas, err := NewResourceSchemas(nodes)
+	require.NoError(t, err)
+
+	f := &PatchFilter{PatchType: "strategic", PatchData: []byte(`{"spec": {"parts": [{"name": "b", "size": 3}]}}`), Schemas: schemas}
+	_, err = f.Filter(nodes[1])
+	require.NoError(t, err)
+	actual, err := nodes[1].String()
+	require.NoError(t, err)
+	assert.Equal(t, `apiVersion: example.com/v1
+kind: Widget
+metadata:
+  name: test
+spec:
+  parts:
+  - name: a
+    size: 1
+  - name: b
+    size: 3
+`, actual)
+
+	// Without the schema, the list is replaced
+	f.Schemas = nil
+	_, err = f.Filter(nodes[1])
+	require.NoError(t, err)
+	parts, err := nodes[1].Pipe(yaml.Lookup("spec", "parts"))
+	require.NoError(t, err)
+	elements, err := parts.Elements()
+	require.NoError(t, err)
+	assert.Len(t, elements, 1)
+}
+
+func TestParsePatch(t *testing.T) {
+	nodes, err := kio.FromBytes([]byte(`apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+spec:
+  replicas: 1
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: other
+spec:
+  replicas: 1
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: app
+`))
+	require.NoError(t, err)
+
+	f, err := ParsePatch([]byte(`apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+spec:
+  replicas: 3
+`))
+	require.NoError(t, err)
+	nodes, err = f.Filter(nodes)
+	require.NoError(t, err)
+
+	replicas, err := nodes[0].GetFieldValue("spec.replicas")
+	require.NoError(t, err)
+	assert.Equal(t, 3, replicas)
+	replicas, err = nodes[1].GetFieldValue("spec.replicas")
+	require.NoError(t, err)
+	assert.Equal(t, 1, replicas)
+	assert.Equal(t, "Deployment", nodes[0].GetKind())
+	assert.Equal(t, "Service", nodes[2].GetKind())
+
+	// Deleting patches remove the resource from the stream
+	f, err = ParsePatch([]byte(`{"apiVersion": "v1", "kind": "Service", "metadata": {"name": "app"}, "$patch": "delete"}`))
+	require.NoError(t, err)
+	nodes, err = f.Filter(nodes)
+	require.NoError(t, err)
+	assert.Len(t, nodes, 2)
+
+	// JSON patches apply to everything
+	f, err = ParsePatch([]byte(`[{"op": "add", "path": "/metadata/labels", "value": {"app": "test"}}]`))
+	require.NoError(t, err)
+	assert.Equal(t, "json", f.Patch.PatchType)
+	nodes, err = f.Filter(nodes)
+	require.NoError(t, err)
+	for _, node := range nodes {
+		assert.Equal(t, map[string]string{"app": "test"}, node.GetLabels())
+	}
+}
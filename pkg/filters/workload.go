@@ -17,126 +17,515 @@ limitations under the License.
 package filters
 
 import (
+	"fmt"
+	"slices"
+	"strings"
+
 	"sigs.k8s.io/kustomize/kyaml/yaml"
 )
 
+const (
+	// The annotations used by the Operator SDK to track ownership of resources
+	// that cannot use owner references (e.g. cross-namespace ownership).
+	primaryResourceAnnotation     = "operator-sdk/primary-resource"
+	primaryResourceTypeAnnotation = "operator-sdk/primary-resource-type"
+)
+
+// podSpecPaths are the paths to pod specs in conventional workload resources.
+var podSpecPaths = func() [][]string {
+	var result [][]string
+	for _, p := range yaml.ConventionalContainerPaths {
+		result = append(result, p[:len(p)-1])
+	}
+	return result
+}()
+
+// DefaultPodSpecPaths returns the paths to pod specs used to recognize workloads.
+func DefaultPodSpecPaths() [][]string {
+	return append(slices.Clone(podSpecPaths),
+		[]string{"spec", "jobTargetRef", "template", "spec"}, // KEDA ScaledJob
+	)
+}
+
+// workloadReferences are paths to references from a workload to another
+// resource which it effectively owns (e.g. an Argo Rollout referencing a
+// Deployment which it manages the pods of).
+var workloadReferences = map[string][]string{
+	"Rollout.argoproj.io": {"spec", "workloadRef"},
+}
+
 // WorkloadFilter keeps only workload resources, i.e. those that directly or
-// indirectly own pods. For this filter to work, all intermediate resources must
-// be present and must have owner metadata specified.
+// indirectly own pods. Resources are considered to own pods if they are pods or
+// they have a pod template (e.g. Deployments, Argo Rollouts, Knative Services
+// or KEDA ScaledJobs); ownership is determined using controller owner
+// references or the Operator SDK primary resource annotations. Only the top
+// most owner which is present is retained, if intermediate resources are
+// missing (e.g. ReplicaSets) the owner is found by name.
 type WorkloadFilter struct {
-	// Flag indicating if this filter should act as a pass-through.
+	// Flag indicating if this filter should act as a pass-through (ignored when `IncludeRelated` is set).
 	Enabled bool
 	// Secondary filter which can be optionally used to accept non-workload resources.
 	NonWorkloadFilter *ResourceMetaFilter
+	// Additional paths to pod specs used to recognize custom workload types, in addition to `DefaultPodSpecPaths`.
+	PodSpecPaths [][]string
+	// Selects the workloads to keep, all workloads are kept by default.
+	Selector *MatchFilter
+	// Flag indicating that the resources related to the workloads should also
+	// be kept: Services and PodDisruptionBudgets selecting the workload pods,
+	// autoscalers targeting the workloads, the ConfigMaps, Secrets,
+	// ServiceAccounts and PersistentVolumeClaims they reference and the RBAC
+	// resources bound to the service accounts. Implies `Enabled`.
+	IncludeRelated bool
 }
 
 // Filter keeps all the workload resources.
 func (f *WorkloadFilter) Filter(nodes []*yaml.RNode) ([]*yaml.RNode, error) {
-	if !f.Enabled {
+	if !f.Enabled && !f.IncludeRelated {
 		return nodes, nil
 	}
 
-	// TODO https://sdk.operatorframework.io/docs/building-operators/ansible/reference/retroactively-owned-resources/#for-objects-which-are-not-in-the-same-namespace-as-the-owner-cr
+	graph, err := newOwnerGraph(nodes, append(DefaultPodSpecPaths(), f.PodSpecPaths...))
+	if err != nil {
+		return nil, err
+	}
 
-	owners := make(map[yaml.ResourceIdentifier]*yaml.ResourceIdentifier, len(nodes))
-	pods := make([]yaml.ResourceIdentifier, 0, len(nodes)/3)
-	unscoped := make(map[yaml.ResourceIdentifier]struct{})
-	for _, n := range nodes {
-		md, err := n.GetMeta()
+	// Find all the distinct workloads by traversing up from everything with pods
+	workloads := make(map[resourceKey]struct{}, len(nodes))
+	for i := range nodes {
+		if graph.hasPods[i] {
+			workloads[graph.workload(graph.keys[i])] = struct{}{}
+		}
+	}
+
+	keep := make([]bool, len(nodes))
+	for i := range nodes {
+		_, keep[i] = workloads[graph.keys[i]]
+	}
+
+	if f.Selector != nil {
+		selected, err := f.Selector.Filter(nodes)
 		if err != nil {
 			return nil, err
 		}
+		for i, n := range nodes {
+			keep[i] = keep[i] && slices.Contains(selected, n)
+		}
+	}
 
-		id := md.GetIdentifier()
+	if f.IncludeRelated {
+		if err := f.includeRelated(nodes, keep); err != nil {
+			return nil, err
+		}
+	}
 
-		// Keep track of pods
-		if md.APIVersion == "v1" && md.Kind == "Pod" {
-			pods = append(pods, id)
+	// Filter out the workloads
+	result := make([]*yaml.RNode, 0, len(workloads))
+	for i, n := range nodes {
+		if keep[i] {
+			result = append(result, n)
 		}
+	}
 
-		// Keep track of unscoped nodes
-		if md.Namespace == "" {
-			unscoped[id] = struct{}{}
+	// If we have been asked to keep additional workloads, append to the end
+	if f.NonWorkloadFilter != nil {
+		extra, err := f.NonWorkloadFilter.Filter(nodes)
+		if err != nil {
+			return nil, err
 		}
+		for _, n := range extra {
+			if !slices.Contains(result, n) {
+				result = append(result, n)
+			}
+		}
+	}
 
-		// Index the owner with `controller=true`
-		if err := n.PipeE(
-			yaml.Lookup(yaml.MetadataField, "ownerReferences"),
-			yaml.FilterFunc(func(object *yaml.RNode) (*yaml.RNode, error) {
-				return nil, object.VisitElements(func(node *yaml.RNode) error {
-					controller, _ := node.GetFieldValue("controller")
-					if isController, ok := controller.(bool); !ok || !isController {
-						return nil
-					}
+	return result, nil
+}
 
-					owners[id] = &yaml.ResourceIdentifier{}
-					return node.YNode().Decode(owners[id])
-				})
-			})); err != nil {
-			return nil, err
+// resourceKey is a version independent identifier for a resource.
+type resourceKey struct {
+	groupKind string
+	namespace string
+	name      string
+}
+
+// newResourceKey returns the key for the supplied resource.
+func newResourceKey(apiVersion, kind, namespace, name string) resourceKey {
+	return resourceKey{groupKind: groupKind(apiVersion, kind), namespace: namespace, name: name}
+}
+
+// attachedKinds are the kinds of resources which are related to the resources they reference.
+var attachedKinds = []string{
+	"HorizontalPodAutoscaler",
+	"VerticalPodAutoscaler",
+	"ScaledObject",
+	"RoleBinding",
+	"ClusterRoleBinding",
+}
+
+// includeRelated marks the resources related to the kept workloads until there is nothing left to add.
+func (f *WorkloadFilter) includeRelated(nodes []*yaml.RNode, keep []bool) error {
+	refs := DefaultNameReferences()
+	paths := append(DefaultPodSpecPaths(), f.PodSpecPaths...)
+
+	// Attached resources only depend on their roles, following their other
+	// references (e.g. the subjects of a shared binding) would include siblings
+	var roleRefs []NameReference
+	for _, ref := range refs {
+		if len(ref.Path) > 0 && ref.Path[0] == "roleRef" {
+			roleRefs = append(roleRefs, ref)
 		}
 	}
 
-	// Find all the distinct workloads by traversing up from the pods
-	workloads := make(map[yaml.ResourceIdentifier]struct{}, len(nodes))
-	for _, pod := range pods {
-		workload := pod
-		for {
-			owner := owners[workload]
-			if owner == nil {
-				break
-			}
+	scopes := customResourceScopes(nodes)
+	index := make(map[resourceName]int, len(nodes))
+	for i, n := range nodes {
+		md, err := n.GetMeta()
+		if err != nil {
+			return err
+		}
+		index[newResourceName(md, scopes)] = i
+	}
+	lookup := func(kind, namespace, name string) (int, bool) {
+		if i, ok := index[resourceName{kind: kind, namespace: namespace, name: name}]; ok {
+			return i, true
+		}
+		i, ok := index[resourceName{kind: kind, name: name, clusterScoped: true}]
+		return i, ok
+	}
 
-			workload = *owner
-			if _, ok := unscoped[workload]; !ok {
-				workload.Namespace = pod.Namespace
-			}
+	// Collect the pod labels of the workloads for matching selectors
+	podLabels := make([]map[string]string, len(nodes))
+	for i, n := range nodes {
+		if !keep[i] {
+			continue
 		}
-		workloads[workload] = struct{}{}
+		labels, err := templateLabels(n, paths)
+		if err != nil {
+			return err
+		}
+		podLabels[i] = labels
 	}
 
-	// There were no pods found, assume everything we find with a pod template is a workload
-	if len(pods) == 0 {
-		for _, n := range nodes {
-			err := n.PipeE(
-				Has(yaml.LookupFirstMatch(yaml.ConventionalContainerPaths)),
-				yaml.FilterFunc(func(object *yaml.RNode) (*yaml.RNode, error) {
-					md, err := n.GetMeta()
-					if err != nil {
-						return nil, err
+	for changed := true; changed; {
+		changed = false
+		for i, n := range nodes {
+			// References from kept resources are kept
+			if keep[i] {
+				keptRefs := refs
+				if slices.Contains(attachedKinds, n.GetKind()) {
+					keptRefs = roleRefs
+				}
+				if err := visitNameReferences(n, keptRefs, func(kind, namespace string, name *yaml.Node) error {
+					if j, ok := lookup(kind, namespace, name.Value); ok && !keep[j] {
+						keep[j], changed = true, true
 					}
-					if owners[md.GetIdentifier()] == nil {
-						workloads[md.GetIdentifier()] = struct{}{}
+					return nil
+				}); err != nil {
+					return err
+				}
+				continue
+			}
+
+			// Attached resources referencing kept resources are kept
+			if slices.Contains(attachedKinds, n.GetKind()) {
+				if err := visitNameReferences(n, refs, func(kind, namespace string, name *yaml.Node) error {
+					if j, ok := lookup(kind, namespace, name.Value); ok && keep[j] && !keep[i] {
+						keep[i], changed = true, true
 					}
-					return nil, nil
-				}))
+					return nil
+				}); err != nil {
+					return err
+				}
+				if keep[i] {
+					continue
+				}
+			}
+
+			// Resources selecting the pods of kept workloads are kept
+			selects, err := selectsPods(n, nodes, podLabels)
 			if err != nil {
+				return err
+			}
+			if selects {
+				keep[i], changed = true, true
+			}
+		}
+	}
+
+	return nil
+}
+
+// templateLabels returns the pod labels of a workload.
+func templateLabels(n *yaml.RNode, paths [][]string) (map[string]string, error) {
+	if n.GetApiVersion() == "v1" && n.GetKind() == "Pod" {
+		return n.GetLabels(), nil
+	}
+	for _, p := range paths {
+		if len(p) < 2 {
+			continue
+		}
+		labels, err := n.Pipe(yaml.Lookup(append(slices.Clone(p[:len(p)-1]), yaml.MetadataField, yaml.LabelsField)...))
+		if err != nil {
+			return nil, err
+		}
+		if labels != nil {
+			result := make(map[string]string)
+			if err := labels.VisitFields(func(node *yaml.MapNode) error {
+				result[yaml.GetValue(node.Key)] = yaml.GetValue(node.Value)
+				return nil
+			}); err != nil {
 				return nil, err
 			}
+			return result, nil
 		}
 	}
+	return nil, nil
+}
 
-	// Filter out the workloads
-	result := make([]*yaml.RNode, 0, len(workloads))
-	for _, n := range nodes {
+// selectsPods checks if the node is a Service or PodDisruptionBudget selecting the pods of a workload in the same namespace.
+func selectsPods(n *yaml.RNode, nodes []*yaml.RNode, podLabels []map[string]string) (bool, error) {
+	var selector *yaml.RNode
+	var err error
+	switch groupKind(n.GetApiVersion(), n.GetKind()) {
+	case "Service":
+		// Services only support equality based selectors
+		var matchLabels *yaml.RNode
+		if matchLabels, err = n.Pipe(yaml.Lookup("spec", "selector")); err == nil && matchLabels != nil {
+			selector = yaml.NewMapRNode(nil)
+			err = selector.PipeE(yaml.SetField("matchLabels", matchLabels))
+		}
+	case "PodDisruptionBudget.policy":
+		selector, err = n.Pipe(yaml.Lookup("spec", "selector"))
+	}
+	if err != nil || selector == nil {
+		return false, err
+	}
+
+	for i, labels := range podLabels {
+		if labels == nil || nodes[i].GetNamespace() != n.GetNamespace() {
+			continue
+		}
+		if ok, err := matchesLabelSelector(selector, labels); err != nil || ok {
+			return ok, err
+		}
+	}
+	return false, nil
+}
+
+// matchesLabelSelector evaluates a Kubernetes `LabelSelector` against a set of labels. An empty selector matches nothing.
+func matchesLabelSelector(selector *yaml.RNode, labels map[string]string) (bool, error) {
+	s := struct {
+		MatchLabels      map[string]string `yaml:"matchLabels"`
+		MatchExpressions []struct {
+			Key      string   `yaml:"key"`
+			Operator string   `yaml:"operator"`
+			Values   []string `yaml:"values"`
+		} `yaml:"matchExpressions"`
+	}{}
+	if err := selector.YNode().Decode(&s); err != nil {
+		return false, err
+	}
+	if len(s.MatchLabels) == 0 && len(s.MatchExpressions) == 0 {
+		return false, nil
+	}
+
+	for k, v := range s.MatchLabels {
+		if lv, ok := labels[k]; !ok || lv != v {
+			return false, nil
+		}
+	}
+	for _, e := range s.MatchExpressions {
+		v, ok := labels[e.Key]
+		switch e.Operator {
+		case "In":
+			if !ok || !slices.Contains(e.Values, v) {
+				return false, nil
+			}
+		case "NotIn":
+			if ok && slices.Contains(e.Values, v) {
+				return false, nil
+			}
+		case "Exists":
+			if !ok {
+				return false, nil
+			}
+		case "DoesNotExist":
+			if ok {
+				return false, nil
+			}
+		default:
+			return false, fmt.Errorf("unknown label selector operator %q", e.Operator)
+		}
+	}
+	return true, nil
+}
+
+// ownerGraph tracks the ownership relationships between resources.
+type ownerGraph struct {
+	// The keys of the nodes.
+	keys []resourceKey
+	// Flags indicating which nodes own pods.
+	hasPods []bool
+	// The index of each present key.
+	present map[resourceKey]int
+	// The key of the owner of each key.
+	owners map[resourceKey]resourceKey
+}
+
+// newOwnerGraph indexes the ownership of the supplied nodes.
+func newOwnerGraph(nodes []*yaml.RNode, paths [][]string) (*ownerGraph, error) {
+	scopes := customResourceScopes(nodes)
+
+	containerPaths := make([][]string, 0, len(paths))
+	for _, p := range paths {
+		containerPaths = append(containerPaths, append(slices.Clone(p), "containers"))
+	}
+
+	g := &ownerGraph{
+		keys:    make([]resourceKey, len(nodes)),
+		hasPods: make([]bool, len(nodes)),
+		present: make(map[resourceKey]int, len(nodes)),
+		owners:  make(map[resourceKey]resourceKey, len(nodes)),
+	}
+
+	for i, n := range nodes {
 		md, err := n.GetMeta()
 		if err != nil {
 			return nil, err
 		}
 
-		if _, isWorkload := workloads[md.GetIdentifier()]; isWorkload {
-			result = append(result, n)
+		g.keys[i] = newResourceKey(md.APIVersion, md.Kind, md.Namespace, md.Name)
+		g.present[g.keys[i]] = i
+
+		if md.APIVersion == "v1" && md.Kind == "Pod" {
+			g.hasPods[i] = true
+		} else if containers, err := n.Pipe(yaml.LookupFirstMatch(containerPaths)); err != nil {
+			return nil, err
+		} else if containers != nil {
+			g.hasPods[i] = true
 		}
 	}
 
-	// If we have been asked to keep additional workloads, append to the end
-	if f.NonWorkloadFilter != nil {
-		extra, err := f.NonWorkloadFilter.Filter(nodes)
-		if err != nil {
+	for i, n := range nodes {
+		if owner, ok, err := controllerOwner(n, scopes); err != nil {
 			return nil, err
+		} else if ok {
+			g.owners[g.keys[i]] = owner
+		}
+
+		// Referenced resources are owned by the referrer, unless they already have an owner
+		if p, ok := workloadReferences[groupKind(n.GetApiVersion(), n.GetKind())]; ok {
+			ref, err := n.Pipe(yaml.Lookup(p...))
+			if err != nil {
+				return nil, err
+			}
+			if ref != nil {
+				apiVersion, _ := ref.GetString("apiVersion")
+				kind, _ := ref.GetString("kind")
+				name, _ := ref.GetString("name")
+				key := newResourceKey(apiVersion, kind, n.GetNamespace(), name)
+				if _, owned := g.owners[key]; !owned {
+					g.owners[key] = g.keys[i]
+				}
+			}
 		}
-		result = append(result, extra...)
 	}
 
-	return result, nil
+	return g, nil
+}
+
+// workload returns the key of the top most owner of the supplied key.
+func (g *ownerGraph) workload(key resourceKey) resourceKey {
+	seen := make(map[resourceKey]bool)
+	for !seen[key] {
+		seen[key] = true
+
+		owner, ok := g.owners[key]
+		if !ok {
+			break
+		}
+
+		// Try to skip over missing intermediate owners using generated names
+		// (e.g. a ReplicaSet named "app-5d8f7c" created by a Deployment named "app")
+		if _, ok := g.present[owner]; !ok {
+			if generator, ok := g.generatedBy(owner); ok {
+				owner = generator
+			}
+		}
+
+		key = owner
+	}
+	return key
+}
+
+// generatorKinds are the kinds of resources which generate names for the resources they create, indexed by "Kind.group".
+var generatorKinds = map[string][]string{
+	"ReplicaSet.apps": {"Deployment.apps", "Rollout.argoproj.io"},
+	"Job.batch":       {"CronJob.batch"},
+}
+
+// generatedBy returns the key of a present resource with pods that likely generated the missing resource.
+func (g *ownerGraph) generatedBy(key resourceKey) (resourceKey, bool) {
+	i := strings.LastIndexByte(key.name, '-')
+	if i <= 0 {
+		return resourceKey{}, false
+	}
+
+	for _, gk := range generatorKinds[key.groupKind] {
+		generator := resourceKey{groupKind: gk, namespace: key.namespace, name: key.name[:i]}
+		if j, ok := g.present[generator]; ok && g.hasPods[j] {
+			return generator, true
+		}
+	}
+	return resourceKey{}, false
+}
+
+// controllerOwner returns the key of the controller of the supplied node.
+func controllerOwner(n *yaml.RNode, scopes map[string]bool) (resourceKey, bool, error) {
+	var owner resourceKey
+	var found bool
+
+	refs, err := n.Pipe(yaml.Lookup(yaml.MetadataField, "ownerReferences"))
+	if err != nil {
+		return owner, false, err
+	}
+	if refs != nil {
+		if err := refs.VisitElements(func(ref *yaml.RNode) error {
+			controller, _ := ref.GetFieldValue("controller")
+			if isController, ok := controller.(bool); !ok || !isController {
+				return nil
+			}
+
+			apiVersion, _ := ref.GetString("apiVersion")
+			kind, _ := ref.GetString("kind")
+			name, _ := ref.GetString("name")
+
+			// Namespaced resources can only be owned by resources in the same namespace or cluster scoped resources
+			namespace := n.GetNamespace()
+			if !isNamespaceScoped(yaml.TypeMeta{APIVersion: apiVersion, Kind: kind}, scopes) {
+				namespace = ""
+			}
+
+			owner, found = newResourceKey(apiVersion, kind, namespace, name), true
+			return nil
+		}); err != nil {
+			return owner, false, err
+		}
+	}
+	if found {
+		return owner, true, nil
+	}
+
+	// Fallback to the annotations used for cross-namespace ownership
+	annotations := n.GetAnnotations()
+	primary, primaryType := annotations[primaryResourceAnnotation], annotations[primaryResourceTypeAnnotation]
+	if primary == "" || primaryType == "" {
+		return owner, false, nil
+	}
+	namespace, name, ok := strings.Cut(primary, "/")
+	if !ok {
+		namespace, name = "", namespace
+	}
+	return resourceKey{groupKind: primaryType, namespace: namespace, name: name}, true, nil
 }
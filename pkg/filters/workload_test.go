@@ -0,0 +1,560 @@
+/*
+Copyright 2022 GramLabs, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package filters
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"sigs.k8s.io/kustomize/kyaml/kio"
+)
+
+func TestWorkloadFilter(t *testing.T) {
+	cases := []struct {
+		desc     string
+		filter   WorkloadFilter
+		input    string
+		expected []string
+	}{
+		{
+			desc:   "live deployment",
+			filter: WorkloadFilter{Enabled: true},
+			input: `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+  namespace: default
+spec:
+  template:
+    spec:
+      containers:
+      - name: app
+---
+apiVersion: apps/v1
+kind: ReplicaSet
+metadata:
+  name: app-5d8f7c
+  namespace: default
+  ownerReferences:
+  - apiVersion: apps/v1
+    kind: Deployment
+    name: app
+    controller: true
+spec:
+  template:
+    spec:
+      containers:
+      - name: app
+---
+apiVersion: v1
+kind: Pod
+metadata:
+  name: app-5d8f7c-abcde
+  namespace: default
+  ownerReferences:
+  - apiVersion: apps/v1
+    kind: ReplicaSet
+    name: app-5d8f7c
+    controller: true
+spec:
+  containers:
+  - name: app
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: idle
+  namespace: default
+spec:
+  replicas: 0
+  template:
+    spec:
+      containers:
+      - name: idle
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: config
+  namespace: default
+`,
+			expected: []string{"Deployment.apps/app", "Deployment.apps/idle"},
+		},
+		{
+			desc:   "missing replica set",
+			filter: WorkloadFilter{Enabled: true},
+			input: `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+  namespace: default
+spec:
+  template:
+    spec:
+      containers:
+      - name: app
+---
+apiVersion: v1
+kind: Pod
+metadata:
+  name: app-5d8f7c-abcde
+  namespace: default
+  ownerReferences:
+  - apiVersion: apps/v1
+    kind: ReplicaSet
+    name: app-5d8f7c
+    controller: true
+spec:
+  containers:
+  - name: app
+---
+apiVersion: v1
+kind: Pod
+metadata:
+  name: orphan
+  namespace: default
+spec:
+  containers:
+  - name: orphan
+`,
+			expected: []string{"Deployment.apps/app", "Pod/orphan"},
+		},
+		{
+			desc:   "custom controllers",
+			filter: WorkloadFilter{Enabled: true, PodSpecPaths: [][]string{{"spec", "workers", "podSpec"}}},
+			input: `apiVersion: argoproj.io/v1alpha1
+kind: Rollout
+metadata:
+  name: canary
+  namespace: default
+spec:
+  workloadRef:
+    apiVersion: apps/v1
+    kind: Deployment
+    name: canary
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: canary
+  namespace: default
+spec:
+  replicas: 0
+  template:
+    spec:
+      containers:
+      - name: canary
+---
+apiVersion: keda.sh/v1alpha1
+kind: ScaledJob
+metadata:
+  name: queue
+  namespace: default
+spec:
+  jobTargetRef:
+    template:
+      spec:
+        containers:
+        - name: worker
+---
+apiVersion: serving.knative.dev/v1
+kind: Service
+metadata:
+  name: hello
+  namespace: default
+spec:
+  template:
+    spec:
+      containers:
+      - image: hello
+---
+apiVersion: example.com/v1
+kind: Pool
+metadata:
+  name: pool
+  namespace: default
+spec:
+  workers:
+    podSpec:
+      containers:
+      - name: worker
+`,
+			expected: []string{"Rollout.argoproj.io/canary", "ScaledJob.keda.sh/queue", "Service.serving.knative.dev/hello", "Pool.example.com/pool"},
+		},
+		{
+			desc:   "cross-namespace owner",
+			filter: WorkloadFilter{Enabled: true},
+			input: `apiVersion: cache.example.com/v1
+kind: Memcached
+metadata:
+  name: cache
+  namespace: operators
+spec:
+  size: 3
+---
+apiVersion: apps/v1
+kind: StatefulSet
+metadata:
+  name: cache
+  namespace: cache
+  annotations:
+    operator-sdk/primary-resource: operators/cache
+    operator-sdk/primary-resource-type: Memcached.cache.example.com
+spec:
+  template:
+    spec:
+      containers:
+      - name: memcached
+`,
+			expected: []string{"Memcached.cache.example.com/cache"},
+		},
+		{
+			desc: "related resources",
+			filter: WorkloadFilter{
+				Enabled:        true,
+				Selector:       &MatchFilter{ResourceMetaFilter: ResourceMetaFilter{Name: "app"}},
+				IncludeRelated: true,
+			},
+			input: `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+  namespace: default
+spec:
+  template:
+    metadata:
+      labels:
+        app: app
+        tier: web
+    spec:
+      serviceAccountName: app
+      containers:
+      - name: app
+        envFrom:
+        - configMapRef:
+            name: app-config
+      volumes:
+      - name: data
+        persistentVolumeClaim:
+          claimName: data
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: other
+  namespace: default
+spec:
+  template:
+    metadata:
+      labels:
+        app: other
+    spec:
+      containers:
+      - name: other
+        envFrom:
+        - configMapRef:
+            name: app-config
+        - secretRef:
+            name: other-secret
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: app
+  namespace: default
+spec:
+  selector:
+    app: app
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: other
+  namespace: default
+spec:
+  selector:
+    app: other
+---
+apiVersion: policy/v1
+kind: PodDisruptionBudget
+metadata:
+  name: web
+  namespace: default
+spec:
+  selector:
+    matchExpressions:
+    - key: tier
+      operator: In
+      values: [web]
+---
+apiVersion: autoscaling/v2
+kind: HorizontalPodAutoscaler
+metadata:
+  name: app
+  namespace: default
+spec:
+  scaleTargetRef:
+    apiVersion: apps/v1
+    kind: Deployment
+    name: app
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: app-config
+  namespace: default
+---
+apiVersion: v1
+kind: Secret
+metadata:
+  name: other-secret
+  namespace: default
+---
+apiVersion: v1
+kind: PersistentVolumeClaim
+metadata:
+  name: data
+  namespace: default
+---
+apiVersion: v1
+kind: ServiceAccount
+metadata:
+  name: app
+  namespace: default
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: RoleBinding
+metadata:
+  name: app
+  namespace: default
+roleRef:
+  apiGroup: rbac.authorization.k8s.io
+  kind: Role
+  name: app
+subjects:
+- kind: ServiceAccount
+  name: app
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: Role
+metadata:
+  name: app
+  namespace: default
+`,
+			expected: []string{"Deployment.apps/app", "Service/app", "PodDisruptionBudget.policy/web", "HorizontalPodAutoscaler.autoscaling/app",
+				"ConfigMap/app-config", "PersistentVolumeClaim/data", "ServiceAccount/app", "RoleBinding.rbac.authorization.k8s.io/app", "Role.rbac.authorization.k8s.io/app"},
+		},
+		{
+			desc: "shared binding",
+			filter: WorkloadFilter{
+				Selector:       &MatchFilter{ResourceMetaFilter: ResourceMetaFilter{Namespace: "a", Name: "app"}},
+				IncludeRelated: true,
+			},
+			input: `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+  namespace: a
+spec:
+  template:
+    spec:
+      serviceAccountName: app
+      containers:
+      - name: app
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: other
+  namespace: b
+spec:
+  template:
+    spec:
+      serviceAccountName: other
+      containers:
+      - name: other
+---
+apiVersion: v1
+kind: ServiceAccount
+metadata:
+  name: app
+  namespace: a
+---
+apiVersion: v1
+kind: ServiceAccount
+metadata:
+  name: other
+  namespace: b
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRoleBinding
+metadata:
+  name: view
+roleRef:
+  apiGroup: rbac.authorization.k8s.io
+  kind: ClusterRole
+  name: view
+subjects:
+- kind: ServiceAccount
+  name: app
+  namespace: a
+- kind: ServiceAccount
+  name: other
+  namespace: b
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRole
+metadata:
+  name: view
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: RoleBinding
+metadata:
+  name: other-admin
+  namespace: b
+roleRef:
+  apiGroup: rbac.authorization.k8s.io
+  kind: Role
+  name: other-admin
+subjects:
+- kind: ServiceAccount
+  name: other
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: Role
+metadata:
+  name: other-admin
+  namespace: b
+`,
+			expected: []string{"Deployment.apps/app", "ServiceAccount/app",
+				"ClusterRoleBinding.rbac.authorization.k8s.io/view", "ClusterRole.rbac.authorization.k8s.io/view"},
+		},
+		{
+			desc: "related non-workloads",
+			filter: WorkloadFilter{
+				NonWorkloadFilter: &ResourceMetaFilter{Kind: "ConfigMap"},
+				IncludeRelated:    true,
+			},
+			input: `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+spec:
+  template:
+    spec:
+      containers:
+      - name: app
+        envFrom:
+        - configMapRef:
+            name: app-config
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: app-config
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: other
+`,
+			expected: []string{"Deployment.apps/app", "ConfigMap/app-config", "ConfigMap/other"},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.desc, func(t *testing.T) {
+			nodes, err := kio.FromBytes([]byte(c.input))
+			require.NoError(t, err)
+
+			actual, err := c.filter.Filter(nodes)
+			require.NoError(t, err)
+
+			var names []string
+			for _, n := range actual {
+				names = append(names, groupKind(n.GetApiVersion(), n.GetKind())+"/"+n.GetName())
+			}
+			assert.Equal(t, c.expected, names)
+		})
+	}
+}
+
+func TestOwnerGraph_Workload(t *testing.T) {
+	const input = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+spec:
+  template:
+    spec:
+      containers:
+      - name: app
+---
+apiVersion: batch/v1
+kind: CronJob
+metadata:
+  name: report
+spec:
+  jobTemplate:
+    spec:
+      template:
+        spec:
+          containers:
+          - name: report
+`
+
+	cases := []struct {
+		desc     string
+		owner    resourceKey
+		expected resourceKey
+	}{
+		{
+			desc:     "replica set",
+			owner:    newResourceKey("apps/v1", "ReplicaSet", "", "app-5d8f7c"),
+			expected: newResourceKey("apps/v1", "Deployment", "", "app"),
+		},
+		{
+			desc:     "job",
+			owner:    newResourceKey("batch/v1", "Job", "", "report-28190"),
+			expected: newResourceKey("batch/v1", "CronJob", "", "report"),
+		},
+		{
+			desc:     "job named like a deployment",
+			owner:    newResourceKey("batch/v1", "Job", "", "app-28190"),
+			expected: newResourceKey("batch/v1", "Job", "", "app-28190"),
+		},
+		{
+			desc:     "unknown generator",
+			owner:    newResourceKey("example.com/v1", "Widget", "", "app-5d8f7c"),
+			expected: newResourceKey("example.com/v1", "Widget", "", "app-5d8f7c"),
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.desc, func(t *testing.T) {
+			nodes, err := kio.FromBytes([]byte(input))
+			require.NoError(t, err)
+
+			g, err := newOwnerGraph(nodes, DefaultPodSpecPaths())
+			require.NoError(t, err)
+
+			// Simulate a pod owned by the missing resource
+			pod := newResourceKey("v1", "Pod", "", "pod")
+			g.owners[pod] = c.owner
+			assert.Equal(t, c.expected, g.workload(pod))
+		})
+	}
+}
@@ -33,12 +33,26 @@ type Filter struct {
 	Depth int
 	// The default reader to use, defaults to stdin.
 	DefaultReader io.Reader
+	// Filter used to append content hashes to the names of generated Secrets and ConfigMaps.
+	HashSuffixFilter filters.HashSuffixFilter
 	// Filter used to reduce the output to application definitions.
 	ApplicationFilter filters.ApplicationFilter
 	// Filter used to reduce the output to workloads.
 	WorkloadFilter filters.WorkloadFilter
 	// Filter to determine which resources are retained.
 	filters.ResourceMetaFilter
+	// Filter to determine which resources are retained using boolean combinations of metadata terms.
+	MatchFilter filters.MatchFilter
+	// Patches to apply to the retained resources.
+	Patches []filters.TargetedPatchFilter
+	// Filter used to set the namespace of the retained resources.
+	NamespaceFilter filters.NamespaceFilter
+	// Filter used to add common labels and annotations to the retained resources.
+	CommonMetadataFilter filters.CommonMetadataFilter
+	// Filter used to override container images of the retained resources.
+	ImageFilter filters.ImageFilter
+	// Filter used to add a prefix or suffix to the names of the retained resources.
+	NameFilter filters.NameFilter
 	// Flag indicating that status fields should not be stripped.
 	KeepStatus bool
 	// Flag indicating that comments should not be stripped.
@@ -75,9 +89,15 @@ func (f *Filter) Filter(nodes []*yaml.RNode) ([]*yaml.RNode, error) {
 		defaultTypes = append(defaultTypes, "deployments", "statefulsets", "configmaps")
 	}
 
-	if f.WorkloadFilter.Enabled {
+	if f.WorkloadFilter.Enabled || f.WorkloadFilter.IncludeRelated {
 		// Include the built-in workload types (and intermediaries necessary for detection to work)
 		defaultTypes = appendDistinct(defaultTypes, "daemonsets", "deployments", "statefulsets", "replicasets", "cronjobs", "pods")
+
+		if f.WorkloadFilter.IncludeRelated {
+			// Include the types which can be related to workloads
+			defaultTypes = appendDistinct(defaultTypes, "services", "configmaps", "secrets", "serviceaccounts", "persistentvolumeclaims",
+				"horizontalpodautoscalers", "poddisruptionbudgets", "roles", "rolebindings", "clusterroles", "clusterrolebindings")
+		}
 	}
 
 	p := &filters.Pipeline{
@@ -97,12 +117,25 @@ func (f *Filter) Filter(nodes []*yaml.RNode) ([]*yaml.RNode, error) {
 				},
 			},
 
+			&f.HashSuffixFilter,
 			&f.ApplicationFilter,
 			&f.WorkloadFilter,
 			&f.ResourceMetaFilter,
+			&f.MatchFilter,
 		},
 	}
 
+	for i := range f.Patches {
+		p.Filters = append(p.Filters, &f.Patches[i])
+	}
+
+	p.Filters = append(p.Filters,
+		&f.NamespaceFilter,
+		&f.CommonMetadataFilter,
+		&f.ImageFilter,
+		&f.NameFilter,
+	)
+
 	if !f.KeepStatus {
 		p.Filters = append(p.Filters, kio.FilterAll(yaml.Clear("status")))
 	}
@@ -119,10 +152,8 @@ func (f *Filter) Filter(nodes []*yaml.RNode) ([]*yaml.RNode, error) {
 		p.Filters = append(p.Filters, &kiofilters.FormatFilter{})
 	}
 
-	if f.Reverse {
-		p.Filters = append(p.Filters, filters.UninstallOrder())
-	} else if f.Sort {
-		p.Filters = append(p.Filters, filters.InstallOrder())
+	if f.Sort || f.Reverse {
+		p.Filters = append(p.Filters, &filters.DependencyOrderFilter{Reverse: f.Reverse})
 	}
 
 	return p.Read()
@@ -33,15 +33,18 @@ import (
 // structured form of a resource. Only one of the pointers may be non-nil at
 // a time.
 type Resource struct {
-	Resource   *konjurev1beta2.Resource   `json:"resource,omitempty" yaml:"resource,omitempty"`
-	Helm       *konjurev1beta2.Helm       `json:"helm,omitempty" yaml:"helm,omitempty"`
-	Jsonnet    *konjurev1beta2.Jsonnet    `json:"jsonnet,omitempty" yaml:"jsonnet,omitempty"`
-	Kubernetes *konjurev1beta2.Kubernetes `json:"kubernetes,omitempty" yaml:"kubernetes,omitempty"`
-	Kustomize  *konjurev1beta2.Kustomize  `json:"kustomize,omitempty" yaml:"kustomize,omitempty"`
-	Secret     *konjurev1beta2.Secret     `json:"secret,omitempty" yaml:"secret,omitempty"`
-	Git        *konjurev1beta2.Git        `json:"git,omitempty" yaml:"git,omitempty"`
-	HTTP       *konjurev1beta2.HTTP       `json:"http,omitempty" yaml:"http,omitempty"`
-	File       *konjurev1beta2.File       `json:"file,omitempty" yaml:"file,omitempty"`
+	Resource    *konjurev1beta2.Resource    `json:"resource,omitempty" yaml:"resource,omitempty"`
+	Helm        *konjurev1beta2.Helm        `json:"helm,omitempty" yaml:"helm,omitempty"`
+	Jsonnet     *konjurev1beta2.Jsonnet     `json:"jsonnet,omitempty" yaml:"jsonnet,omitempty"`
+	Kubernetes  *konjurev1beta2.Kubernetes  `json:"kubernetes,omitempty" yaml:"kubernetes,omitempty"`
+	Kustomize   *konjurev1beta2.Kustomize   `json:"kustomize,omitempty" yaml:"kustomize,omitempty"`
+	Secret      *konjurev1beta2.Secret      `json:"secret,omitempty" yaml:"secret,omitempty"`
+	ConfigMap   *konjurev1beta2.ConfigMap   `json:"configMap,omitempty" yaml:"configMap,omitempty"`
+	Git         *konjurev1beta2.Git         `json:"git,omitempty" yaml:"git,omitempty"`
+	HTTP        *konjurev1beta2.HTTP        `json:"http,omitempty" yaml:"http,omitempty"`
+	File        *konjurev1beta2.File        `json:"file,omitempty" yaml:"file,omitempty"`
+	Overlay     *konjurev1beta2.Overlay     `json:"overlay,omitempty" yaml:"overlay,omitempty"`
+	Replacement *konjurev1beta2.Replacement `json:"replacement,omitempty" yaml:"replacement,omitempty"`
 
 	// Some specs (default reader, `data:` URLs, inline resources) resolve to a stream.
 	raw kio.Reader `json:"-"` // NOTE: when this is non-nil there MUST be a value for `str`!
@@ -106,8 +109,8 @@ func (r *Resource) UnmarshalJSON(bytes []byte) error {
 		return fmt.Errorf("unknown resource type: %T", rr)
 	}
 
-	type rt *Resource
-	return json.Unmarshal(bytes, rt(r))
+	type rt Resource
+	return json.Unmarshal(bytes, (*rt)(r))
 }
 
 // MarshalJSON produces JSON for this Konjure resource. If it was initially read
@@ -20,6 +20,7 @@ import (
 	"bytes"
 	"encoding/base64"
 	"encoding/json"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -48,6 +49,13 @@ func TestResource_Read(t *testing.T) {
 			},
 			expected: []*yaml.RNode{mustRNode(&konjurev1beta2.Git{Repository: "http://example.com/repo"})},
 		},
+		{
+			desc: "overlay",
+			resource: Resource{
+				Overlay: &konjurev1beta2.Overlay{Resources: []string{"test.yaml"}, NamePrefix: "test-"},
+			},
+			expected: []*yaml.RNode{mustRNode(&konjurev1beta2.Overlay{Resources: []string{"test.yaml"}, NamePrefix: "test-"})},
+		},
 	}
 	for _, c := range cases {
 		t.Run(c.desc, func(t *testing.T) {
@@ -90,6 +98,41 @@ func TestResource_UnmarshalJSON(t *testing.T) {
 				},
 			},
 		},
+		{
+			desc:    "git object",
+			rawJSON: `{"git":{"repo":"https://github.com/thestormforge/konjure.git","refspec":"main"}}`,
+			expected: Resource{
+				Git: &konjurev1beta2.Git{
+					Repository: "https://github.com/thestormforge/konjure.git",
+					Refspec:    "main",
+				},
+			},
+		},
+		{
+			desc:    "overlay object",
+			rawJSON: `{"overlay":{"resources":["test.yaml"],"patches":[{"target":{"kind":"Deployment"},"patch":"{}"}]}}`,
+			expected: Resource{
+				Overlay: &konjurev1beta2.Overlay{
+					Resources: []string{"test.yaml"},
+					Patches: []konjurev1beta2.Patch{
+						{Target: &konjurev1beta2.Selector{Kind: "Deployment"}, Patch: "{}"},
+					},
+				},
+			},
+		},
+		{
+			desc:    "replacement object",
+			rawJSON: `{"replacement":{"resources":["test.yaml"],"source":{"kind":"ConfigMap"},"targets":[{"kind":"Deployment","fieldPaths":["metadata/name"]}]}}`,
+			expected: Resource{
+				Replacement: &konjurev1beta2.Replacement{
+					Resources: []string{"test.yaml"},
+					Source:    konjurev1beta2.ReplacementSource{Selector: konjurev1beta2.Selector{Kind: "ConfigMap"}},
+					Targets: []konjurev1beta2.ReplacementTarget{
+						{Selector: konjurev1beta2.Selector{Kind: "Deployment"}, FieldPaths: []string{"metadata/name"}},
+					},
+				},
+			},
+		},
 		{
 			desc:    "data",
 			rawJSON: `"data:;base64,` + base64.URLEncoding.EncodeToString([]byte(testResource)) + `"`,
@@ -126,6 +169,11 @@ func TestResource_MarshalJSON(t *testing.T) {
 			resource: Resource{File: &konjurev1beta2.File{Path: "/this/is/a/test"}},
 			expected: `"/this/is/a/test"`,
 		},
+		{
+			desc:     "overlay object",
+			resource: Resource{Overlay: &konjurev1beta2.Overlay{Resources: []string{"test.yaml"}, Namespace: "test"}},
+			expected: `{"overlay":{"resources":["test.yaml"],"namespace":"test"}}`,
+		},
 	}
 	for _, c := range cases {
 		t.Run(c.desc, func(t *testing.T) {
@@ -147,6 +195,31 @@ func TestResource_DeepCopyInto(t *testing.T) {
 	assert.NotSame(t, in.File, out.File)
 }
 
+func TestRegisterScheme(t *testing.T) {
+	RegisterScheme("test", func(spec string) (any, error) {
+		return &kio.ByteReader{Reader: strings.NewReader(testResource)}, nil
+	})
+	RegisterSchemeOverride("catalog", func(spec string) (any, error) {
+		return &konjurev1beta2.HTTP{URL: "https://catalog.example.com/" + spec}, nil
+	})
+	t.Cleanup(func() {
+		RegisterScheme("test", nil)
+		RegisterSchemeOverride("catalog", nil)
+	})
+
+	var rs Resources
+	err := json.Unmarshal([]byte(`["test://this-is-a-test", "catalog::foo/bar"]`), &rs)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	actual, err := rs.Read()
+	if assert.NoError(t, err) && assert.Len(t, actual, 2) {
+		assert.Equal(t, "this-is-a-test", actual[0].GetName())
+		assert.Equal(t, mustRNode(&konjurev1beta2.HTTP{URL: "https://catalog.example.com/foo/bar"}), actual[1])
+	}
+}
+
 func mustRNode(obj any) *yaml.RNode {
 	rn, err := konjurev1beta2.GetRNode(obj)
 	if err != nil {
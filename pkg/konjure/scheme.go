@@ -0,0 +1,43 @@
+/*
+Copyright 2022 GramLabs, Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package konjure
+
+import (
+	"github.com/thestormforge/konjure/internal/spec"
+)
+
+// SchemeFunc decodes a resource specification into either a Konjure object
+// (e.g. `*konjurev1beta2.HTTP`) or a `kio.Reader` producing the resources.
+type SchemeFunc = spec.SchemeFunc
+
+// RegisterScheme registers a function for decoding resource specifications
+// using the supplied URL scheme, for example "s3" would handle specifications
+// like `s3://bucket/key`. Registered schemes take precedence over the built-in
+// schemes; registering a nil function removes a previous registration.
+func RegisterScheme(scheme string, fn SchemeFunc) {
+	spec.DefaultSchemes.RegisterScheme(scheme, fn)
+}
+
+// RegisterSchemeOverride registers a function for decoding resource
+// specifications prefixed with the supplied scheme override, for example
+// "catalog" would handle specifications like `catalog::my-app`. The function
+// is invoked with the portion of the specification following the "::".
+// Registered overrides take precedence over the built-in overrides (i.e.
+// "git" and "helm"); registering a nil function removes a previous registration.
+func RegisterSchemeOverride(name string, fn SchemeFunc) {
+	spec.DefaultSchemes.RegisterSchemeOverride(name, fn)
+}
@@ -32,6 +32,7 @@ import (
 	"text/tabwriter"
 	"text/template"
 
+	"github.com/thestormforge/konjure/internal/sops"
 	"github.com/thestormforge/konjure/pkg/filters"
 	"sigs.k8s.io/kustomize/kyaml/kio"
 	"sigs.k8s.io/kustomize/kyaml/kio/kioutil"
@@ -58,6 +59,13 @@ type Writer struct {
 	// The root template used to parse user supplied templates. Can be used to
 	// inject new functions or templates.
 	RootTemplate *template.Template
+	// List of age recipients used to encrypt the data of Secret resources (using the SOPS format).
+	EncryptSecrets []string
+	// Path to the sealed secrets controller certificate used to convert Secret resources into SealedSecrets.
+	SealSecretsCertificate string
+	// The scope of generated SealedSecrets (strict, namespace-wide or cluster-wide), defaults to the scope annotated
+	// on each Secret.
+	SealSecretsScope string
 	// Generic configuration options for specific writer implementations.
 	Options []WriterOption
 }
@@ -84,6 +92,18 @@ func (w *Writer) Write(nodes []*yaml.RNode) error {
 		f, t = "template", w.Format
 	}
 
+	// Seal or encrypt secrets before they are written
+	if w.SealSecretsCertificate != "" {
+		if err := w.sealSecrets(nodes); err != nil {
+			return err
+		}
+	}
+	if len(w.EncryptSecrets) > 0 {
+		if err := w.encryptSecrets(nodes); err != nil {
+			return err
+		}
+	}
+
 	switch f {
 
 	case "yaml", "":
@@ -194,6 +214,45 @@ func (w *Writer) Write(nodes []*yaml.RNode) error {
 	return ww.Write(nodes)
 }
 
+// sealSecrets converts any secrets into sealed secrets.
+func (w *Writer) sealSecrets(nodes []*yaml.RNode) error {
+	data, err := os.ReadFile(w.SealSecretsCertificate)
+	if err != nil {
+		return err
+	}
+
+	f := &filters.SealedSecretFilter{Scope: w.SealSecretsScope}
+	if f.PublicKey, err = filters.ParseSealedSecretsPublicKey(data); err != nil {
+		return err
+	}
+
+	_, err = f.Filter(nodes)
+	return err
+}
+
+// encryptSecrets encrypts the data of any secrets for the configured recipients.
+func (w *Writer) encryptSecrets(nodes []*yaml.RNode) error {
+	encrypt := sops.Encrypt(w.EncryptSecrets, sops.KubernetesSecretRegex)
+	for _, n := range nodes {
+		if n.GetApiVersion() != "v1" || n.GetKind() != "Secret" {
+			continue
+		}
+
+		// Annotations must be removed prior to encryption or the MAC will not match
+		if err := clearAnnotations(n, w.KeepReaderAnnotations, w.ClearAnnotations); err != nil {
+			return err
+		}
+		if err := yaml.ClearEmptyAnnotations(n); err != nil {
+			return err
+		}
+
+		if err := n.PipeE(encrypt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // JSONWriter is a writer which emits JSON instead of YAML. This is useful if you like `jq`.
 type JSONWriter struct {
 	Writer                io.Writer
@@ -231,21 +290,8 @@ func (w *JSONWriter) Write(nodes []*yaml.RNode) error {
 	}
 
 	for _, n := range nodes {
-		// This is to be consistent with ByteWriter
-		if !w.KeepReaderAnnotations {
-			if err := n.PipeE(
-				yaml.ClearAnnotation(kioutil.IndexAnnotation),
-				yaml.ClearAnnotation(kioutil.LegacyIndexAnnotation),
-				yaml.ClearAnnotation(kioutil.SeqIndentAnnotation),
-			); err != nil {
-				return err
-			}
-		}
-		for _, a := range w.ClearAnnotations {
-			_, err := n.Pipe(yaml.ClearAnnotation(a))
-			if err != nil {
-				return err
-			}
+		if err := clearAnnotations(n, w.KeepReaderAnnotations, w.ClearAnnotations); err != nil {
+			return err
 		}
 	}
 
@@ -665,6 +711,25 @@ func (w *GroupWriter) indexNodes(nodes []*yaml.RNode) (map[string][]*yaml.RNode,
 	return result, nil
 }
 
+// clearAnnotations removes the same annotations from the node that the ByteWriter would.
+func clearAnnotations(n *yaml.RNode, keepReaderAnnotations bool, annotations []string) error {
+	if !keepReaderAnnotations {
+		if err := n.PipeE(
+			yaml.ClearAnnotation(kioutil.IndexAnnotation),
+			yaml.ClearAnnotation(kioutil.LegacyIndexAnnotation),
+			yaml.ClearAnnotation(kioutil.SeqIndentAnnotation),
+		); err != nil {
+			return err
+		}
+	}
+	for _, a := range annotations {
+		if _, err := n.Pipe(yaml.ClearAnnotation(a)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // wrap is a helper that wraps a list of resource nodes into a single node.
 func wrap(apiVersion, kind string, nodes []*yaml.RNode) *yaml.RNode {
 	items := &yaml.Node{Kind: yaml.SequenceNode}
@@ -1,11 +1,15 @@
 package konjure
 
 import (
+	"bytes"
 	"testing"
 
+	"filippo.io/age"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"github.com/thestormforge/konjure/internal/sops"
 	"sigs.k8s.io/kustomize/kyaml/kio"
+	"sigs.k8s.io/kustomize/kyaml/kio/kioutil"
 	"sigs.k8s.io/kustomize/kyaml/yaml"
 )
 
@@ -145,3 +149,44 @@ func TestSplitColumns(t *testing.T) {
 		})
 	}
 }
+
+func TestWriter_EncryptSecrets(t *testing.T) {
+	identity, err := age.GenerateX25519Identity()
+	require.NoError(t, err)
+
+	nodes, err := kio.FromBytes([]byte(`apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: test
+data:
+  foo: bar
+---
+apiVersion: v1
+kind: Secret
+metadata:
+  name: test
+  annotations:
+    config.kubernetes.io/path: secret.yaml
+data:
+  foo: YmFy
+`))
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	w := &Writer{
+		Writer:           &buf,
+		ClearAnnotations: []string{kioutil.PathAnnotation, kioutil.LegacyPathAnnotation},
+		EncryptSecrets:   []string{identity.Recipient().String()},
+	}
+	require.NoError(t, w.Write(nodes))
+
+	actual, err := kio.FromBytes(buf.Bytes())
+	require.NoError(t, err)
+	require.Len(t, actual, 2)
+	assert.Equal(t, map[string]string{"foo": "bar"}, actual[0].GetDataMap())
+	assert.Contains(t, actual[1].GetDataMap()["foo"], "ENC[AES256_GCM,")
+
+	require.NoError(t, sops.DecryptNodes(actual, identity))
+	assert.Equal(t, map[string]string{"foo": "YmFy"}, actual[1].GetDataMap())
+	assert.Empty(t, actual[1].GetAnnotations())
+}